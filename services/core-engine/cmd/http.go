@@ -12,6 +12,7 @@ import (
 	"github.com/labstack/echo/v4/middleware"
 
 	"core-engine/config"
+	httpMiddleware "core-engine/internal/app/middleware/http"
 )
 
 type HTTPServer struct {
@@ -21,9 +22,17 @@ type HTTPServer struct {
 
 // NewHTTPServer returns new HttpServer.
 func NewHTTPServer(cfg *config.Config) *HTTPServer {
+	server := echo.New()
+
+	ipExtractor, err := httpMiddleware.ClientIPExtractor(cfg.App.HTTP.TrustedProxies)
+	if err != nil {
+		log.Fatalf("%v server, %v", cfg.App.Name, err)
+	}
+	server.IPExtractor = ipExtractor
+
 	return &HTTPServer{
 		cfg:    cfg,
-		Server: echo.New(),
+		Server: server,
 	}
 }
 
@@ -15,6 +15,8 @@ const (
 type Config struct {
 	App          App
 	Security     Security
+	Fee          Fee
+	Chain        Chain
 	Dependencies Dependencies
 }
 
@@ -27,9 +29,10 @@ type App struct {
 }
 
 type HTTP struct {
-	Host       string
-	Port       string
-	CtxTimeout time.Duration
+	Host           string
+	Port           string
+	CtxTimeout     time.Duration
+	TrustedProxies []string // CIDR of the reverse proxies allowed to set X-Forwarded-For
 }
 
 type GRPC struct {
@@ -49,9 +52,30 @@ type Logging struct {
 
 type Security struct {
 	Jwt struct {
-		Key      string
-		Duration time.Duration
+		Key             string
+		Duration        time.Duration // Lifetime of the access token
+		RefreshDuration time.Duration // Lifetime of the refresh token, renewed on every refresh
 	}
+	ApiKey struct {
+		EncryptionKey string        // Passphrase of the stored API secret, the secret is needed to verify the signature
+		RecvWindow    time.Duration // Largest difference between the request timestamp and the server time
+	}
+	Totp struct {
+		Issuer        string        // Account issuer shown by the authenticator app
+		EncryptionKey string        // Passphrase of the stored TOTP secret
+		MaxAttempts   int           // Invalid code allowed before the two factor check is locked
+		Lockout       time.Duration // Window of the invalid code count, the lock is lifted once it expired
+	}
+}
+
+type Fee struct {
+	AccountUserID int // Exchange user receiving every trading fee, and paying the maker rebate
+}
+
+type Chain struct {
+	Adapter               string        // Chain adapter, only "fake" for now
+	RequiredConfirmations int           // Confirmations before a deposit is credited or a withdrawal completed
+	PollInterval          time.Duration // Interval of syncing deposits and withdrawals with the chain
 }
 
 type Dependencies struct {
@@ -72,11 +96,30 @@ type Cache struct {
 type MessageBroker struct {
 	Brokers  string
 	Group    string
+	Retry    Retry
 	Consumer struct {
 		Topic struct {
-			MatchOrder string
+			MatchOrder  string
+			OrderUpdate string
 		}
+		Batch Batch
 	}
+	Producer struct {
+		Topic struct {
+			PairRegistry string // Pair created or updated, read by every matching engine
+		}
+	}
+}
+
+type Batch struct {
+	Size int           // Trades settled in one transaction, 1 or less settle every trade on its own
+	Wait time.Duration // Longest wait for a batch to fill up
+}
+
+type Retry struct {
+	MaxAttempts int           // Attempt before the message is moved to the dead-letter topic
+	Backoff     time.Duration // Wait before the first retry, doubled on every next retry
+	MaxBackoff  time.Duration
 }
 
 type Database struct {
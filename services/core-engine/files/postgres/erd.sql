@@ -17,6 +17,11 @@ CREATE TABLE users (
     phone_number                    VARCHAR(128) NOT NULL DEFAULT '',
     password                        VARCHAR(512) NOT NULL,
     status                          BOOLEAN NOT NULL DEFAULT true,
+    fee_tier                        VARCHAR(32) NOT NULL DEFAULT 'DEFAULT',
+    role                            VARCHAR(32) NOT NULL DEFAULT 'USER' CHECK (role IN ('USER', 'SUPPORT', 'ADMIN')),
+    totp_secret                     VARCHAR(255) NOT NULL DEFAULT '', -- Encrypted TOTP secret, set on enrollment
+    totp_enabled                    BOOLEAN NOT NULL DEFAULT false,
+    totp_last_step                  BIGINT NOT NULL DEFAULT 0, -- Step of the last accepted code, a code is never accepted twice
     created_at                      TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
     updated_at                      TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
     deleted_at                      TIMESTAMP WITH TIME ZONE 
@@ -35,17 +40,70 @@ INSERT INTO users (
     "deleted_at"
 ) VALUES (0, '', '', '', '', false, now());
 
+-- Refresh token is rotated on every use, the tokens of one login share a family.
+-- Presenting a revoked token means it was stolen, the whole family is revoked.
+CREATE TABLE refresh_tokens (
+    id                              SERIAL PRIMARY KEY,
+    user_id                         INTEGER NOT NULL REFERENCES users(id),
+    family_id                       VARCHAR(64) NOT NULL,
+    token_hash                      VARCHAR(64) NOT NULL, -- SHA-256 of the token, the token itself is never stored
+    access_token_id                 VARCHAR(64) NOT NULL, -- jti of the access token issued with it
+    expires_at                      TIMESTAMP WITH TIME ZONE NOT NULL,
+    revoked_at                      TIMESTAMP WITH TIME ZONE,
+    created_at                      TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS refresh_tokens_hash_idx ON refresh_tokens (token_hash);
+CREATE INDEX IF NOT EXISTS refresh_tokens_family_idx ON refresh_tokens (family_id);
+CREATE INDEX IF NOT EXISTS refresh_tokens_access_idx ON refresh_tokens (access_token_id);
+
+---------------------------------------------------------------------------------------------------------------------
+
+-- Single use replacement of a TOTP code, stored as SHA-256 of the code
+CREATE TABLE recovery_codes (
+    id                              SERIAL PRIMARY KEY,
+    user_id                         INTEGER NOT NULL REFERENCES users(id),
+    code_hash                       VARCHAR(64) NOT NULL,
+    used_at                         TIMESTAMP WITH TIME ZONE,
+    created_at                      TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS recovery_codes_user_idx ON recovery_codes (user_id);
+
+---------------------------------------------------------------------------------------------------------------------
+
+-- API key of a trading bot, requests are signed with HMAC-SHA256 of the secret.
+-- The secret is needed to verify the signature so it is stored encrypted instead of hashed.
+CREATE TABLE api_keys (
+    id                              SERIAL PRIMARY KEY,
+    user_id                         INTEGER NOT NULL REFERENCES users(id),
+    name                            VARCHAR(128) NOT NULL DEFAULT '',
+    key_id                          VARCHAR(64) NOT NULL, -- Public, sent with every request
+    secret_encrypted                VARCHAR(255) NOT NULL,
+    scopes                          VARCHAR(64) NOT NULL, -- Comma separated READ, TRADE, WITHDRAW
+    ip_allowlist                    VARCHAR(1024) NOT NULL DEFAULT '', -- Comma separated IP or CIDR, empty allows any
+    last_used_at                    TIMESTAMP WITH TIME ZONE,
+    revoked_at                      TIMESTAMP WITH TIME ZONE,
+    created_at                      TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS api_keys_key_id_idx ON api_keys (key_id);
+CREATE INDEX IF NOT EXISTS api_keys_user_idx ON api_keys (user_id);
+
 ---------------------------------------------------------------------------------------------------------------------
 
 CREATE TABLE crypto (
     id                              SERIAL PRIMARY KEY,
     symbol                          VARCHAR(128) NOT NULL DEFAULT '', -- BTC, ETH
+    precision                       INTEGER NOT NULL DEFAULT 8, -- Decimal places of the amount
     status                          BOOLEAN NOT NULL DEFAULT true,
+    transfer_daily_limit            DOUBLE PRECISION NOT NULL DEFAULT 0, -- Amount a user can transfer in 24 hours, 0 disables transfer
     created_at                      TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
     updated_at                      TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
     deleted_at                      TIMESTAMP WITH TIME ZONE 
 );
 
+CREATE UNIQUE INDEX IF NOT EXISTS crypto_symbol_idx ON crypto (symbol);
 CREATE TRIGGER crypto BEFORE UPDATE ON crypto FOR EACH ROW EXECUTE PROCEDURE update_modified_column();
 
 INSERT INTO crypto (
@@ -61,10 +119,13 @@ CREATE TABLE wallet (
     id                              SERIAL PRIMARY KEY,
     user_id                         INTEGER NOT NULL REFERENCES users(id),
     crypto_id                       INTEGER NOT NULL REFERENCES crypto(id),
-    quantity                        DOUBLE PRECISION NOT NULL DEFAULT 0,
+    available                       DOUBLE PRECISION NOT NULL DEFAULT 0, -- Free to use
+    locked                          DOUBLE PRECISION NOT NULL DEFAULT 0, -- Reserved by open orders
     created_at                      TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
     updated_at                      TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
-    deleted_at                      TIMESTAMP WITH TIME ZONE 
+    deleted_at                      TIMESTAMP WITH TIME ZONE,
+    CONSTRAINT wallet_available_non_negative CHECK (available >= 0),
+    CONSTRAINT wallet_locked_non_negative CHECK (locked >= 0)
 );
 
 CREATE TRIGGER wallet BEFORE UPDATE ON wallet FOR EACH ROW EXECUTE PROCEDURE update_modified_column();
@@ -73,7 +134,7 @@ INSERT INTO wallet (
     "id",
     "user_id",
     "crypto_id",
-    "quantity",
+    "available",
     "deleted_at"
 ) VALUES (0, 0, 0, 0, now());
 
@@ -84,10 +145,17 @@ CREATE TABLE pairs (
     code                            VARCHAR(256) NOT NULL DEFAULT '',
     primary_crypto_id               INTEGER NOT NULL REFERENCES crypto(id),
     secondary_crypto_id             INTEGER NOT NULL REFERENCES crypto(id),
+    tick_size                       DOUBLE PRECISION NOT NULL DEFAULT 0, -- Price increment, 0 accepts any price
+    lot_size                        DOUBLE PRECISION NOT NULL DEFAULT 0, -- Quantity increment, also the pro-rata rounding
+    min_quantity                    DOUBLE PRECISION NOT NULL DEFAULT 0,
+    matching_algorithm              VARCHAR(32) NOT NULL DEFAULT 'FIFO',
+    min_allocation                  DOUBLE PRECISION NOT NULL DEFAULT 0, -- Pro-rata share below this is given to the oldest order
     status                          BOOLEAN NOT NULL DEFAULT true,
     created_at                      TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
     updated_at                      TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
-    deleted_at                      TIMESTAMP WITH TIME ZONE 
+    deleted_at                      TIMESTAMP WITH TIME ZONE,
+    CONSTRAINT pairs_trading_rule_non_negative CHECK (tick_size >= 0 AND lot_size >= 0 AND min_quantity >= 0 AND min_allocation >= 0),
+    CONSTRAINT pairs_matching_algorithm_check CHECK (matching_algorithm IN ('FIFO', 'PRO_RATA', 'FIFO_TOP_PRO_RATA'))
 );
 
 CREATE UNIQUE INDEX IF NOT EXISTS pairs_code_idx ON pairs (code);
@@ -104,9 +172,29 @@ INSERT INTO pairs (
 
 ---------------------------------------------------------------------------------------------------------------------
 
+-- Pair ID 0 applies to every pair, tier DEFAULT applies to every user without a specific schedule
+CREATE TABLE fee_schedules (
+    id                              SERIAL PRIMARY KEY,
+    pair_id                         INTEGER NOT NULL REFERENCES pairs(id),
+    tier                            VARCHAR(32) NOT NULL DEFAULT 'DEFAULT',
+    maker_rate                      DOUBLE PRECISION NOT NULL DEFAULT 0, -- Negative rate is a maker rebate, paid out of the taker fee
+    taker_rate                      DOUBLE PRECISION NOT NULL DEFAULT 0,
+    status                          BOOLEAN NOT NULL DEFAULT true,
+    created_at                      TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at                      TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    deleted_at                      TIMESTAMP WITH TIME ZONE,
+    CHECK (taker_rate >= 0 AND taker_rate < 1),
+    CHECK (maker_rate > -1 AND maker_rate < 1)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS fee_schedules_pair_tier_idx ON fee_schedules (pair_id, tier) WHERE deleted_at IS NULL;
+CREATE TRIGGER fee_schedules BEFORE UPDATE ON fee_schedules FOR EACH ROW EXECUTE PROCEDURE update_modified_column();
+
+---------------------------------------------------------------------------------------------------------------------
+
 CREATE TYPE order_side AS ENUM ('BUY', 'SELL');
 CREATE TYPE order_type AS ENUM ('MARKET', 'LIMIT', 'STOP_LOSS', 'TAKE_PROFIT');
-CREATE TYPE order_status AS ENUM ('COMPLETE', 'FAILED', 'PROGRESS', 'PARTIAL');
+CREATE TYPE order_status AS ENUM ('COMPLETE', 'FAILED', 'PROGRESS', 'PARTIAL', 'CANCELLED');
 
 CREATE TABLE orders (
     id                              SERIAL PRIMARY KEY,
@@ -115,6 +203,8 @@ CREATE TABLE orders (
     quantity                        DOUBLE PRECISION NOT NULL DEFAULT 0,
     filled_quantity                 DOUBLE PRECISION NOT NULL DEFAULT 0,
     price                           DOUBLE PRECISION NOT NULL DEFAULT 0,
+    quote_quantity                  DOUBLE PRECISION NOT NULL DEFAULT 0,
+    filled_quote_quantity           DOUBLE PRECISION NOT NULL DEFAULT 0,
     type                            order_type,
     side                            order_side,
     status                          order_status,
@@ -124,6 +214,7 @@ CREATE TABLE orders (
     deleted_at                      TIMESTAMP WITH TIME ZONE 
 );
 
+CREATE INDEX IF NOT EXISTS orders_user_idx ON orders (user_id, transaction_time);
 CREATE TRIGGER orders BEFORE UPDATE ON orders FOR EACH ROW EXECUTE PROCEDURE update_modified_column();
 
 INSERT INTO orders (
@@ -149,12 +240,22 @@ CREATE TABLE match_orders (
     maker_order_id                  INTEGER NOT NULL REFERENCES orders(id),
     quantity                        DOUBLE PRECISION NOT NULL DEFAULT 0,
     price                           DOUBLE PRECISION NOT NULL DEFAULT 0,
+    taker_fee                       DOUBLE PRECISION NOT NULL DEFAULT 0,
+    taker_fee_crypto_id             INTEGER NOT NULL DEFAULT 0 REFERENCES crypto(id),
+    maker_fee                       DOUBLE PRECISION NOT NULL DEFAULT 0, -- Negative fee is a maker rebate
+    maker_fee_crypto_id             INTEGER NOT NULL DEFAULT 0 REFERENCES crypto(id),
+    trade_sequence                  BIGINT NOT NULL DEFAULT 0, -- Assigned by the matching engine, unique per pair
     transaction_time                BIGINT NOT NULL DEFAULT 0,
     created_at                      TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
     updated_at                      TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
     deleted_at                      TIMESTAMP WITH TIME ZONE 
 );
 
+CREATE INDEX IF NOT EXISTS match_orders_pair_idx ON match_orders (pair_id, id);
+CREATE INDEX IF NOT EXISTS match_orders_taker_order_idx ON match_orders (taker_order_id);
+CREATE INDEX IF NOT EXISTS match_orders_maker_order_idx ON match_orders (maker_order_id);
+-- Settling the same trade twice is rejected, 0 is a trade from matching engine without sequence
+CREATE UNIQUE INDEX IF NOT EXISTS match_orders_pair_sequence_idx ON match_orders (pair_id, trade_sequence) WHERE trade_sequence <> 0;
 CREATE TRIGGER match_orders BEFORE UPDATE ON match_orders FOR EACH ROW EXECUTE PROCEDURE update_modified_column();
 
 INSERT INTO match_orders (
@@ -167,3 +268,116 @@ INSERT INTO match_orders (
     "transaction_time",
     "deleted_at"
 ) VALUES (0, 0, 0, 0, 0, 0, 0, now());
+
+---------------------------------------------------------------------------------------------------------------------
+
+-- Append-only double-entry ledger, entries of one journal sum to zero per crypto.
+-- Wallet available and locked balance is the sum of the AVAILABLE and LOCKED entries of the user,
+-- EXTERNAL entries of user 0 are the counterpart of money entering or leaving the exchange,
+-- PENDING entries are deposits seen on the chain and not credited yet.
+CREATE TYPE ledger_account AS ENUM ('AVAILABLE', 'LOCKED', 'EXTERNAL', 'PENDING');
+CREATE TYPE ledger_entry_type AS ENUM ('DEPOSIT', 'LOCK', 'UNLOCK', 'FILL', 'FEE', 'REFUND', 'ADJUSTMENT', 'WITHDRAWAL', 'TRANSFER');
+CREATE TYPE ledger_reference_type AS ENUM ('USER', 'ORDER', 'TRADE', 'WALLET', 'DEPOSIT', 'WITHDRAWAL', 'TRANSFER', 'ADJUSTMENT');
+
+CREATE SEQUENCE ledger_journal_seq;
+
+CREATE TABLE ledger_entries (
+    id                              BIGSERIAL PRIMARY KEY,
+    journal_id                      BIGINT NOT NULL,
+    user_id                         INTEGER NOT NULL REFERENCES users(id),
+    crypto_id                       INTEGER NOT NULL REFERENCES crypto(id),
+    account                         ledger_account NOT NULL,
+    amount                          DOUBLE PRECISION NOT NULL, -- Positive increase the account balance
+    entry_type                      ledger_entry_type NOT NULL,
+    reference_type                  ledger_reference_type NOT NULL,
+    reference_id                    INTEGER NOT NULL DEFAULT 0, -- Users, orders, match_orders, wallet, deposits, withdrawals, transfers or balance_adjustments ID
+    created_at                      TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS ledger_entries_user_crypto_idx ON ledger_entries (user_id, crypto_id);
+CREATE INDEX IF NOT EXISTS ledger_entries_journal_idx ON ledger_entries (journal_id);
+CREATE INDEX IF NOT EXISTS ledger_entries_reference_idx ON ledger_entries (reference_type, reference_id);
+
+CREATE OR REPLACE FUNCTION reject_ledger_modification()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'ledger_entries is append-only';
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER ledger_entries BEFORE UPDATE OR DELETE ON ledger_entries FOR EACH ROW EXECUTE PROCEDURE reject_ledger_modification();
+
+---------------------------------------------------------------------------------------------------------------------
+
+CREATE TYPE deposit_status AS ENUM ('PENDING', 'CONFIRMING', 'CREDITED');
+
+CREATE TABLE deposits (
+    id                              SERIAL PRIMARY KEY,
+    user_id                         INTEGER NOT NULL REFERENCES users(id),
+    crypto_id                       INTEGER NOT NULL REFERENCES crypto(id),
+    amount                          DOUBLE PRECISION NOT NULL CHECK (amount > 0),
+    address                         VARCHAR(256) NOT NULL, -- Exchange address the transaction was sent to
+    tx_hash                         VARCHAR(256) NOT NULL,
+    confirmations                   INTEGER NOT NULL DEFAULT 0,
+    status                          deposit_status NOT NULL DEFAULT 'PENDING',
+    created_at                      TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at                      TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS deposits_tx_hash_idx ON deposits (crypto_id, tx_hash); -- A chain transaction is credited once
+CREATE INDEX IF NOT EXISTS deposits_user_idx ON deposits (user_id, id);
+CREATE INDEX IF NOT EXISTS deposits_status_idx ON deposits (status, id);
+CREATE TRIGGER deposits BEFORE UPDATE ON deposits FOR EACH ROW EXECUTE PROCEDURE update_modified_column();
+
+---------------------------------------------------------------------------------------------------------------------
+
+CREATE TYPE withdrawal_status AS ENUM ('REQUESTED', 'APPROVED', 'BROADCAST', 'COMPLETED', 'FAILED');
+
+CREATE TABLE withdrawals (
+    id                              SERIAL PRIMARY KEY,
+    user_id                         INTEGER NOT NULL REFERENCES users(id),
+    crypto_id                       INTEGER NOT NULL REFERENCES crypto(id),
+    amount                          DOUBLE PRECISION NOT NULL CHECK (amount > 0),
+    address                         VARCHAR(256) NOT NULL,
+    tx_hash                         VARCHAR(256) NOT NULL DEFAULT '', -- Set when broadcast
+    status                          withdrawal_status NOT NULL DEFAULT 'REQUESTED',
+    failure_reason                  VARCHAR(512) NOT NULL DEFAULT '',
+    approved_by                     INTEGER NOT NULL DEFAULT 0, -- Admin user ID
+    created_at                      TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at                      TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS withdrawals_user_idx ON withdrawals (user_id, id);
+CREATE INDEX IF NOT EXISTS withdrawals_status_idx ON withdrawals (status, id);
+CREATE TRIGGER withdrawals BEFORE UPDATE ON withdrawals FOR EACH ROW EXECUTE PROCEDURE update_modified_column();
+
+---------------------------------------------------------------------------------------------------------------------
+
+CREATE TABLE transfers (
+    id                              SERIAL PRIMARY KEY,
+    sender_id                       INTEGER NOT NULL REFERENCES users(id),
+    receiver_id                     INTEGER NOT NULL REFERENCES users(id),
+    crypto_id                       INTEGER NOT NULL REFERENCES crypto(id),
+    amount                          DOUBLE PRECISION NOT NULL CHECK (amount > 0),
+    note                            VARCHAR(256) NOT NULL DEFAULT '',
+    created_at                      TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT transfers_not_self CHECK (sender_id <> receiver_id)
+);
+
+CREATE INDEX IF NOT EXISTS transfers_sender_idx ON transfers (sender_id, crypto_id, created_at);
+CREATE INDEX IF NOT EXISTS transfers_receiver_idx ON transfers (receiver_id, id);
+
+---------------------------------------------------------------------------------------------------------------------
+
+-- Manual balance correction by an admin, the reason is mandatory for audit
+CREATE TABLE balance_adjustments (
+    id                              SERIAL PRIMARY KEY,
+    user_id                         INTEGER NOT NULL REFERENCES users(id),
+    crypto_id                       INTEGER NOT NULL REFERENCES crypto(id),
+    amount                          DOUBLE PRECISION NOT NULL CHECK (amount <> 0), -- Negative is a debit
+    reason                          VARCHAR(512) NOT NULL CHECK (reason <> ''),
+    admin_id                        INTEGER NOT NULL REFERENCES users(id),
+    created_at                      TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS balance_adjustments_user_idx ON balance_adjustments (user_id, id);
@@ -7,6 +7,13 @@ INSERT INTO public.users (full_name,email,phone_number,"password",status) VALUES
 	 ('Arvid Hudson','Oliver_Heller81@gmail.com','796-641-9993','$2a$10$0no/8pCvBzP2mR4UM3HdWOaJGrNcObDJghaibt7YgTHpqdMzTTIma',true),
 	 ('Erna Ortiz','Drew.Lueilwitz@gmail.com','744-322-0964','$2a$10$mUwSleaEimRTE2Idfuj1l.v3uI14cXHq7jcRNiUBlawPC3/hurRT6',true);
 
+-- First user is the exchange administrator
+UPDATE public.users SET role = 'ADMIN' WHERE id = 1;
+
+-- Exchange fee account, blocked from login and trading
+INSERT INTO public.users (full_name,email,phone_number,"password",status) VALUES
+	 ('Exchange Fee Account','fee@exchange.local','','',false);
+
 -- Init Crypto symbol
 INSERT INTO public.crypto (symbol,status) VALUES
 	 ('IDRT',true),
@@ -21,6 +28,9 @@ INSERT INTO public.crypto (symbol,status) VALUES
 	 ('LINK',true),
 	 ('BCH',true);
 
+-- Daily transfer limit between users, in the crypto unit
+UPDATE public.crypto SET transfer_daily_limit = CASE WHEN symbol = 'IDRT' THEN 100000000 ELSE 1000 END WHERE id <> 0;
+
 -- Init crypto pairs
 INSERT INTO public.pairs (code,primary_crypto_id,secondary_crypto_id,status) VALUES
 	 ('BTCIDRT',2,1,true),
@@ -29,8 +39,12 @@ INSERT INTO public.pairs (code,primary_crypto_id,secondary_crypto_id,status) VAL
 	 ('BNBIDRT',4,1,true),
 	 ('XRPIDRT',7,1,true);
 
+-- Trading rules, DOGEIDRT matches the lot size of the matching engine config
+UPDATE public.crypto SET precision = 2 WHERE symbol = 'IDRT';
+UPDATE public.pairs SET tick_size = 1, lot_size = 1, min_quantity = 1, min_allocation = 1 WHERE code = 'DOGEIDRT';
+
 -- Init user wallet
-INSERT INTO public.wallet (user_id,crypto_id,quantity) VALUES
+INSERT INTO public.wallet (user_id,crypto_id,available) VALUES
 	 (1,1,3000000.0),
 	 (1,2,20.0),
 	 (1,5,20000.0),
@@ -44,7 +58,7 @@ INSERT INTO public.wallet (user_id,crypto_id,quantity) VALUES
 	 (3,1,6000000.0),
 	 (3,2,30.0),
 	 (3,5,20000.0);
-INSERT INTO public.wallet (user_id,crypto_id,quantity) VALUES
+INSERT INTO public.wallet (user_id,crypto_id,available) VALUES
 	 (3,6,30000.0),
 	 (3,2,200.0),
 	 (4,1,7000000.0),
@@ -57,9 +71,33 @@ INSERT INTO public.wallet (user_id,crypto_id,quantity) VALUES
 	 (5,5,20000.0),
 	 (5,6,30000.0),
 	 (5,2,200.0);
-INSERT INTO public.wallet (user_id,crypto_id,quantity) VALUES
+INSERT INTO public.wallet (user_id,crypto_id,available) VALUES
 	 (6,1,9000000.0),
 	 (6,2,25.0),
 	 (6,5,20000.0),
 	 (6,6,30000.0),
 	 (6,2,200.0);
+INSERT INTO public.wallet (user_id,crypto_id,available) VALUES
+	 (7,1,0.0),
+	 (7,2,0.0),
+	 (7,3,0.0),
+	 (7,4,0.0),
+	 (7,5,0.0),
+	 (7,6,0.0),
+	 (7,7,0.0),
+	 (7,8,0.0),
+	 (7,9,0.0),
+	 (7,10,0.0),
+	 (7,11,0.0);
+
+-- Init fee schedule, pair 0 applies to every pair
+INSERT INTO public.fee_schedules (pair_id,tier,maker_rate,taker_rate) VALUES
+	 (0,'DEFAULT',0.001,0.002),
+	 (0,'VIP',-0.0001,0.0015),
+	 (2,'DEFAULT',0.0005,0.001);
+
+-- Opening balance of every seeded wallet, one balanced deposit journal per wallet
+INSERT INTO public.ledger_entries (journal_id,user_id,crypto_id,account,amount,entry_type,reference_type,reference_id)
+SELECT nextval('ledger_journal_seq'),user_id,crypto_id,'AVAILABLE',available,'DEPOSIT','WALLET',id FROM public.wallet WHERE id <> 0 AND available <> 0;
+INSERT INTO public.ledger_entries (journal_id,user_id,crypto_id,account,amount,entry_type,reference_type,reference_id)
+SELECT journal_id,0,crypto_id,'EXTERNAL',-amount,'DEPOSIT','WALLET',reference_id FROM public.ledger_entries WHERE reference_type = 'WALLET';
@@ -0,0 +1,8 @@
+package dto
+
+type ApiKeyRequest struct {
+	Name        string   `json:"name"`
+	Scopes      []string `json:"scopes"`       // READ, TRADE or WITHDRAW
+	IPAllowlist []string `json:"ip_allowlist"` // IP or CIDR, empty allows any
+	TotpCode    string   `json:"totp_code"`    // Required once 2FA is enabled
+}
@@ -0,0 +1,26 @@
+package dto
+
+// DepositRequest records a deposit seen on the chain, sent by admin or the chain watcher
+type DepositRequest struct {
+	UserID   int     `json:"user_id"`
+	CryptoID int     `json:"crypto_id"`
+	Amount   float64 `json:"amount"`
+	Address  string  `json:"address"` // Exchange address the transaction was sent to
+	TxHash   string  `json:"tx_hash"`
+}
+
+type WithdrawalRequest struct {
+	CryptoID int     `json:"crypto_id"`
+	Amount   float64 `json:"amount"`
+	Address  string  `json:"address"`   // Destination address on the chain
+	TotpCode string  `json:"totp_code"` // Required once 2FA is enabled
+}
+
+type RejectWithdrawalRequest struct {
+	Reason string `json:"reason"`
+}
+
+type FundingListRequest struct {
+	Page  int `query:"page"`
+	Limit int `query:"limit"`
+}
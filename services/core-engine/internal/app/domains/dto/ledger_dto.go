@@ -0,0 +1,8 @@
+package dto
+
+type LedgerRequest struct {
+	CryptoID  int    `query:"crypto_id"`  // Optional, every crypto when empty
+	EntryType string `query:"entry_type"` // Optional, DEPOSIT / LOCK / UNLOCK / FILL / FEE / REFUND / ADJUSTMENT / WITHDRAWAL / TRANSFER
+	Page      int    `query:"page"`
+	Limit     int    `query:"limit"`
+}
@@ -0,0 +1,40 @@
+package dto
+
+// CryptoRequest lists a new crypto, status is active when empty
+type CryptoRequest struct {
+	Symbol             string  `json:"symbol"`
+	Precision          int     `json:"precision"`
+	Status             *bool   `json:"status"`
+	TransferDailyLimit float64 `json:"transfer_daily_limit"`
+}
+
+// CryptoUpdateRequest only changes the field sent
+type CryptoUpdateRequest struct {
+	Symbol             *string  `json:"symbol"`
+	Precision          *int     `json:"precision"`
+	Status             *bool    `json:"status"`
+	TransferDailyLimit *float64 `json:"transfer_daily_limit"`
+}
+
+// PairRequest lists a new pair, the code is the base symbol followed by the quote symbol when empty
+type PairRequest struct {
+	Code              string  `json:"code"`
+	PrimaryCryptoID   int     `json:"primary_crypto_id"`   // Base
+	SecondaryCryptoID int     `json:"secondary_crypto_id"` // Quote
+	TickSize          float64 `json:"tick_size"`
+	LotSize           float64 `json:"lot_size"`
+	MinQuantity       float64 `json:"min_quantity"`
+	MatchingAlgorithm string  `json:"matching_algorithm"` // FIFO when empty
+	MinAllocation     float64 `json:"min_allocation"`
+	Status            *bool   `json:"status"`
+}
+
+// PairUpdateRequest only changes the field sent, the code and crypto of a pair are fixed
+type PairUpdateRequest struct {
+	TickSize          *float64 `json:"tick_size"`
+	LotSize           *float64 `json:"lot_size"`
+	MinQuantity       *float64 `json:"min_quantity"`
+	MatchingAlgorithm *string  `json:"matching_algorithm"`
+	MinAllocation     *float64 `json:"min_allocation"`
+	Status            *bool    `json:"status"`
+}
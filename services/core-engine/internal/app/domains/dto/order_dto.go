@@ -3,11 +3,12 @@ package dto
 import "encoding/json"
 
 type OrderRequest struct {
-	PairCode string  `json:"pair_code"`
-	Quantity float64 `json:"quantity"`
-	Price    float64 `json:"price"`
-	Side     string  `json:"side"` // BUY / SELL
-	Type     string  `json:"type"` // MARKET / LIMIT
+	PairCode      string  `json:"pair_code"`
+	Quantity      float64 `json:"quantity"`
+	Price         float64 `json:"price"`
+	QuoteQuantity float64 `json:"quote_quantity"` // Amount of secondary crypto to spend, only for MARKET BUY without quantity
+	Side          string  `json:"side"`           // BUY / SELL
+	Type          string  `json:"type"`           // MARKET / LIMIT
 }
 
 type TradeRequest struct {
@@ -20,14 +21,57 @@ type TradeRequest struct {
 	Price        float64 `json:"price"`
 	Side         string  `json:"side"`
 	TradeTime    int64   `json:"trade_time"`
+	Sequence     int64   `json:"trade_sequence"`      // Unique per pair, a redelivered trade has the same sequence
+	FencingToken int64   `json:"fencing_token"`       // Leader term of the matching engine that produced the trade
+	TermStart    int64   `json:"term_start_sequence"` // First trade sequence of the leader term
 }
 
 func (trade *TradeRequest) FromJSON(msg []byte) error {
 	return json.Unmarshal(msg, trade)
 }
 
+// OrderUpdateRequest is published by the matching engine when an order finished without resting in the book,
+// when an order is rejected before reaching the book, or when a resting order is cancelled
+type OrderUpdateRequest struct {
+	OrderID             int     `json:"order_id"`
+	UserID              int     `json:"user_id"`
+	PairID              int     `json:"pair_id"`
+	Status              string  `json:"status"`
+	FilledQuantity      float64 `json:"filled_quantity"`
+	FilledQuoteQuantity float64 `json:"filled_quote_quantity"`
+	RemainingQuantity   float64 `json:"remaining_quantity"` // Quantity removed from the book by a cancel, or rejected by it
+	UpdateTime          int64   `json:"update_time"`
+	TradeSequence       int64   `json:"trade_sequence"` // Last trade sequence of the pair when the update was made
+	FencingToken        int64   `json:"fencing_token"`
+	TermStart           int64   `json:"term_start_sequence"`
+}
+
+func (update *OrderUpdateRequest) FromJSON(msg []byte) error {
+	return json.Unmarshal(msg, update)
+}
+
 type BulkTradeRequest []TradeRequest
 
 func (trade *BulkTradeRequest) FromJSON(msg []byte) error {
 	return json.Unmarshal(msg, trade)
 }
+
+type OrderListRequest struct {
+	PairCode      string `query:"pair_code"`
+	Status        string `query:"status"`         // COMPLETE / FAILED / PROGRESS / PARTIAL / CANCELLED
+	Side          string `query:"side"`           // BUY / SELL
+	StartTime     int64  `query:"start_time"`     // Unix time, inclusive
+	EndTime       int64  `query:"end_time"`       // Unix time, exclusive
+	SortBy        string `query:"sort_by"`        // id / transaction_time / price / quantity
+	SortDirection string `query:"sort_direction"` // ASC / DESC
+	Page          int    `query:"page"`
+	Limit         int    `query:"limit"`
+}
+
+type TradeListRequest struct {
+	PairCode  string `query:"pair_code"`
+	StartTime int64  `query:"start_time"` // Unix time, inclusive
+	EndTime   int64  `query:"end_time"`   // Unix time, exclusive
+	Cursor    string `query:"cursor"`     // Next cursor of the previous page, empty for the first page
+	Limit     int    `query:"limit"`
+}
@@ -0,0 +1,17 @@
+package dto
+
+// TransferRequest sends an asset to another user, the receiver is found by ID or by email
+type TransferRequest struct {
+	ReceiverID    int     `json:"receiver_id"`
+	ReceiverEmail string  `json:"receiver_email"`
+	CryptoID      int     `json:"crypto_id"`
+	Amount        float64 `json:"amount"`
+	Note          string  `json:"note"`
+	TotpCode      string  `json:"totp_code"` // Required once 2FA is enabled
+}
+
+type TransferListRequest struct {
+	CryptoID int `query:"crypto_id"` // Optional, every crypto when empty
+	Page     int `query:"page"`
+	Limit    int `query:"limit"`
+}
@@ -3,11 +3,18 @@ package dto
 type LoginRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	TotpCode string `json:"totp_code"` // Required once 2FA is enabled, a recovery code is also accepted
 }
 
 type LoginResponse struct {
-	Email string `json:"email"`
-	Token string `json:"token"`
+	Email        string `json:"email"`
+	Token        string `json:"token"`         // Access token
+	ExpiresAt    int64  `json:"expires_at"`    // Access token expiry, unix second
+	RefreshToken string `json:"refresh_token"` // Single use, exchanged for a new pair of token
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
 }
 
 type RegisterRequest struct {
@@ -16,3 +23,33 @@ type RegisterRequest struct {
 	PhoneNumber string `json:"phone_number"`
 	Password    string `json:"password"`
 }
+
+type FreezeRequest struct {
+	Reason string `json:"reason"`
+}
+
+// AdjustmentRequest corrects the available balance, the reason is mandatory
+type AdjustmentRequest struct {
+	CryptoID int     `json:"crypto_id"`
+	Amount   float64 `json:"amount"` // Negative is a debit
+	Reason   string  `json:"reason"`
+}
+
+type ChangePasswordRequest struct {
+	OldPassword string `json:"old_password"`
+	NewPassword string `json:"new_password"`
+	TotpCode    string `json:"totp_code"`
+}
+
+type TwoFactorEnrollResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"` // otpauth URI, rendered as QR code by the client
+}
+
+type TwoFactorRequest struct {
+	TotpCode string `json:"totp_code"`
+}
+
+type TwoFactorEnableResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"` // Single use, shown only once
+}
@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"context"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/spf13/cast"
+
+	"core-engine/config"
+	"core-engine/internal/app/domains/dto"
+	"core-engine/internal/app/domains/model"
+	httpMiddleware "core-engine/internal/app/middleware/http"
+	serverError "core-engine/pkg/error"
+	"core-engine/pkg/jwt"
+	"core-engine/pkg/response"
+)
+
+type adminHandler struct {
+	timeout        time.Duration
+	adminUsecase   model.AdminUsecase
+	securityConfig config.Security
+	denyList       jwt.DenyList
+}
+
+func NewAdminHTTPHandler(adminUsecase model.AdminUsecase, timeout time.Duration, securityConfig config.Security, denyList jwt.DenyList) interface {
+	InitRoutes(e *echo.Echo)
+} {
+	return &adminHandler{
+		timeout:        timeout,
+		adminUsecase:   adminUsecase,
+		securityConfig: securityConfig,
+		denyList:       denyList,
+	}
+}
+
+func (h *adminHandler) InitRoutes(e *echo.Echo) {
+	// Support can look up user, every change requires admin
+	adminOnly := httpMiddleware.ValidateRole(model.UserRoleAdmin)
+
+	v1 := e.Group("/api/v1/admin/user")
+	v1.Use(httpMiddleware.ValidateJwtToken([]byte(h.securityConfig.Jwt.Key), h.denyList))
+	v1.Use(httpMiddleware.ValidateRole(model.UserRoleAdmin, model.UserRoleSupport))
+	{
+		v1.GET("", h.FindUserHandler)
+		v1.GET("/:id", h.UserDetailHandler)
+		v1.POST("/:id/freeze", h.FreezeUserHandler, adminOnly)
+		v1.POST("/:id/unfreeze", h.UnfreezeUserHandler, adminOnly)
+		v1.POST("/:id/adjustment", h.AdjustBalanceHandler, adminOnly)
+	}
+}
+
+func (h *adminHandler) FindUserHandler(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Get("ctx").(context.Context), h.timeout)
+	defer cancel()
+
+	email := c.QueryParam("email")
+	if email == "" {
+		return response.Failed(c, serverError.ErrInvalidRequest(nil))
+	}
+
+	user, err := h.adminUsecase.FindUser(ctx, email)
+	if err != nil {
+		return response.Failed(c, err)
+	}
+
+	return response.Success(c, user)
+}
+
+func (h *adminHandler) UserDetailHandler(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Get("ctx").(context.Context), h.timeout)
+	defer cancel()
+
+	userID, err := cast.ToIntE(c.Param("id"))
+	if err != nil {
+		return response.Failed(c, serverError.ErrInvalidRequest(err))
+	}
+
+	user, err := h.adminUsecase.GetUser(ctx, userID)
+	if err != nil {
+		return response.Failed(c, err)
+	}
+
+	return response.Success(c, user)
+}
+
+func (h *adminHandler) FreezeUserHandler(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Get("ctx").(context.Context), h.timeout)
+	defer cancel()
+
+	userID, err := cast.ToIntE(c.Param("id"))
+	if err != nil {
+		return response.Failed(c, serverError.ErrInvalidRequest(err))
+	}
+
+	var requestPayload dto.FreezeRequest
+	if err := c.Bind(&requestPayload); err != nil {
+		return response.Failed(c, err)
+	}
+
+	user, err := h.adminUsecase.FreezeUser(ctx, userID, requestPayload.Reason)
+	if err != nil {
+		return response.Failed(c, err)
+	}
+
+	return response.Success(c, user)
+}
+
+func (h *adminHandler) UnfreezeUserHandler(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Get("ctx").(context.Context), h.timeout)
+	defer cancel()
+
+	userID, err := cast.ToIntE(c.Param("id"))
+	if err != nil {
+		return response.Failed(c, serverError.ErrInvalidRequest(err))
+	}
+
+	var requestPayload dto.FreezeRequest
+	if err := c.Bind(&requestPayload); err != nil {
+		return response.Failed(c, err)
+	}
+
+	user, err := h.adminUsecase.UnfreezeUser(ctx, userID, requestPayload.Reason)
+	if err != nil {
+		return response.Failed(c, err)
+	}
+
+	return response.Success(c, user)
+}
+
+func (h *adminHandler) AdjustBalanceHandler(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Get("ctx").(context.Context), h.timeout)
+	defer cancel()
+
+	userID, err := cast.ToIntE(c.Param("id"))
+	if err != nil {
+		return response.Failed(c, serverError.ErrInvalidRequest(err))
+	}
+
+	var requestPayload dto.AdjustmentRequest
+	if err := c.Bind(&requestPayload); err != nil {
+		return response.Failed(c, err)
+	}
+
+	adjustment, err := h.adminUsecase.AdjustBalance(ctx, userID, requestPayload)
+	if err != nil {
+		return response.Failed(c, err)
+	}
+
+	return response.Success(c, adjustment)
+}
@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"context"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/spf13/cast"
+
+	"core-engine/config"
+	"core-engine/internal/app/domains/dto"
+	"core-engine/internal/app/domains/model"
+	httpMiddleware "core-engine/internal/app/middleware/http"
+	serverError "core-engine/pkg/error"
+	"core-engine/pkg/jwt"
+	"core-engine/pkg/response"
+)
+
+type apiKeyHandler struct {
+	timeout        time.Duration
+	apiKeyUsecase  model.ApiKeyUsecase
+	securityConfig config.Security
+	denyList       jwt.DenyList
+}
+
+func NewApiKeyHTTPHandler(apiKeyUsecase model.ApiKeyUsecase, timeout time.Duration, securityConfig config.Security, denyList jwt.DenyList) interface {
+	InitRoutes(e *echo.Echo)
+} {
+	return &apiKeyHandler{
+		timeout:        timeout,
+		apiKeyUsecase:  apiKeyUsecase,
+		securityConfig: securityConfig,
+		denyList:       denyList,
+	}
+}
+
+func (h *apiKeyHandler) InitRoutes(e *echo.Echo) {
+	// Login session only, an API key can not manage the keys
+	v1 := e.Group("/api/v1/apikey")
+	v1.Use(httpMiddleware.ValidateJwtToken([]byte(h.securityConfig.Jwt.Key), h.denyList))
+	{
+		v1.POST("", h.CreateApiKeyHandler)
+		v1.GET("", h.ApiKeyListHandler)
+		v1.POST("/:id/revoke", h.RevokeApiKeyHandler)
+	}
+}
+
+func (h *apiKeyHandler) CreateApiKeyHandler(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Get("ctx").(context.Context), h.timeout)
+	defer cancel()
+
+	var requestPayload dto.ApiKeyRequest
+	if err := c.Bind(&requestPayload); err != nil {
+		return response.Failed(c, err)
+	}
+
+	apiKey, err := h.apiKeyUsecase.CreateApiKey(ctx, requestPayload)
+	if err != nil {
+		return response.Failed(c, err)
+	}
+
+	return response.Success(c, apiKey)
+}
+
+func (h *apiKeyHandler) ApiKeyListHandler(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Get("ctx").(context.Context), h.timeout)
+	defer cancel()
+
+	apiKeys, err := h.apiKeyUsecase.GetApiKeys(ctx)
+	if err != nil {
+		return response.Failed(c, err)
+	}
+
+	return response.Success(c, apiKeys)
+}
+
+func (h *apiKeyHandler) RevokeApiKeyHandler(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Get("ctx").(context.Context), h.timeout)
+	defer cancel()
+
+	apiKeyID, err := cast.ToIntE(c.Param("id"))
+	if err != nil {
+		return response.Failed(c, serverError.ErrInvalidRequest(err))
+	}
+
+	apiKey, err := h.apiKeyUsecase.RevokeApiKey(ctx, apiKeyID)
+	if err != nil {
+		return response.Failed(c, err)
+	}
+
+	return response.Success(c, apiKey)
+}
@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"context"
+	"time"
+
+	"github.com/gerins/log"
+
+	"core-engine/internal/app/domains/model"
+)
+
+type chainSyncHandler struct {
+	timeout        time.Duration
+	interval       time.Duration
+	fundingUsecase model.FundingUsecase
+}
+
+func NewChainSyncHandler(fundingUsecase model.FundingUsecase, interval, timeout time.Duration) interface {
+	StartSync(ctx context.Context)
+} {
+	return &chainSyncHandler{
+		timeout:        timeout,
+		interval:       interval,
+		fundingUsecase: fundingUsecase,
+	}
+}
+
+// StartSync moves deposits and withdrawals forward with the chain every interval, until the context is done
+func (h *chainSyncHandler) StartSync(ctx context.Context) {
+	if h.interval <= 0 {
+		log.Warn("chain sync disabled, poll interval is not set")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(h.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				h.sync(ctx)
+			}
+		}
+	}()
+}
+
+func (h *chainSyncHandler) sync(parent context.Context) {
+	logging := log.NewRequest()
+	logging.Method = "chain-sync"
+
+	ctx, cancel := context.WithTimeout(parent, h.timeout)
+	defer func() { logging.Save(); cancel() }()
+
+	if err := h.fundingUsecase.SyncChain(logging.SaveToContext(ctx)); err != nil {
+		log.Context(ctx).Error(err)
+	}
+}
@@ -0,0 +1,171 @@
+package handler
+
+import (
+	"context"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/spf13/cast"
+
+	"core-engine/config"
+	"core-engine/internal/app/domains/dto"
+	"core-engine/internal/app/domains/model"
+	httpMiddleware "core-engine/internal/app/middleware/http"
+	serverError "core-engine/pkg/error"
+	"core-engine/pkg/jwt"
+	"core-engine/pkg/response"
+)
+
+type fundingHandler struct {
+	timeout             time.Duration
+	fundingUsecase      model.FundingUsecase
+	securityConfig      config.Security
+	denyList            jwt.DenyList
+	apiKeyAuthenticator httpMiddleware.ApiKeyAuthenticator
+}
+
+func NewFundingHTTPHandler(fundingUsecase model.FundingUsecase, timeout time.Duration, securityConfig config.Security, denyList jwt.DenyList, apiKeyAuthenticator httpMiddleware.ApiKeyAuthenticator) interface {
+	InitRoutes(e *echo.Echo)
+} {
+	return &fundingHandler{
+		timeout:             timeout,
+		fundingUsecase:      fundingUsecase,
+		securityConfig:      securityConfig,
+		denyList:            denyList,
+		apiKeyAuthenticator: apiKeyAuthenticator,
+	}
+}
+
+func (h *fundingHandler) InitRoutes(e *echo.Echo) {
+	readScope := httpMiddleware.ValidateScope(model.ApiKeyScopeRead)
+	withdrawScope := httpMiddleware.ValidateScope(model.ApiKeyScopeWithdraw)
+
+	deposit := e.Group("/api/v1/deposit")
+	deposit.Use(httpMiddleware.ValidateApiKey(h.apiKeyAuthenticator, httpMiddleware.ValidateJwtToken([]byte(h.securityConfig.Jwt.Key), h.denyList)))
+	{
+		deposit.GET("", h.DepositListHandler, readScope)
+	}
+
+	withdrawal := e.Group("/api/v1/withdrawal")
+	withdrawal.Use(httpMiddleware.ValidateApiKey(h.apiKeyAuthenticator, httpMiddleware.ValidateJwtToken([]byte(h.securityConfig.Jwt.Key), h.denyList)))
+	{
+		withdrawal.POST("", h.WithdrawalHandler, withdrawScope)
+		withdrawal.GET("", h.WithdrawalListHandler, readScope)
+	}
+
+	admin := e.Group("/api/v1/admin")
+	admin.Use(httpMiddleware.ValidateJwtToken([]byte(h.securityConfig.Jwt.Key), h.denyList))
+	admin.Use(httpMiddleware.ValidateRole(model.UserRoleAdmin))
+	{
+		admin.POST("/deposit", h.RecordDepositHandler)
+		admin.POST("/withdrawal/:id/approve", h.ApproveWithdrawalHandler)
+		admin.POST("/withdrawal/:id/reject", h.RejectWithdrawalHandler)
+	}
+}
+
+func (h *fundingHandler) DepositListHandler(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Get("ctx").(context.Context), h.timeout)
+	defer cancel()
+
+	requestPayload := dto.FundingListRequest{Page: 1, Limit: 10}
+	if err := c.Bind(&requestPayload); err != nil {
+		return response.Failed(c, err)
+	}
+
+	deposits, total, err := h.fundingUsecase.GetDeposits(ctx, requestPayload)
+	if err != nil {
+		return response.Failed(c, err)
+	}
+
+	return response.SuccessList(c, deposits, requestPayload.Page, requestPayload.Limit, total)
+}
+
+func (h *fundingHandler) WithdrawalHandler(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Get("ctx").(context.Context), h.timeout)
+	defer cancel()
+
+	var requestPayload dto.WithdrawalRequest
+	if err := c.Bind(&requestPayload); err != nil {
+		return response.Failed(c, err)
+	}
+
+	withdrawal, err := h.fundingUsecase.RequestWithdrawal(ctx, requestPayload)
+	if err != nil {
+		return response.Failed(c, err)
+	}
+
+	return response.Success(c, withdrawal)
+}
+
+func (h *fundingHandler) WithdrawalListHandler(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Get("ctx").(context.Context), h.timeout)
+	defer cancel()
+
+	requestPayload := dto.FundingListRequest{Page: 1, Limit: 10}
+	if err := c.Bind(&requestPayload); err != nil {
+		return response.Failed(c, err)
+	}
+
+	withdrawals, total, err := h.fundingUsecase.GetWithdrawals(ctx, requestPayload)
+	if err != nil {
+		return response.Failed(c, err)
+	}
+
+	return response.SuccessList(c, withdrawals, requestPayload.Page, requestPayload.Limit, total)
+}
+
+func (h *fundingHandler) RecordDepositHandler(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Get("ctx").(context.Context), h.timeout)
+	defer cancel()
+
+	var requestPayload dto.DepositRequest
+	if err := c.Bind(&requestPayload); err != nil {
+		return response.Failed(c, err)
+	}
+
+	deposit, err := h.fundingUsecase.RecordDeposit(ctx, requestPayload)
+	if err != nil {
+		return response.Failed(c, err)
+	}
+
+	return response.Success(c, deposit)
+}
+
+func (h *fundingHandler) ApproveWithdrawalHandler(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Get("ctx").(context.Context), h.timeout)
+	defer cancel()
+
+	withdrawalID, err := cast.ToIntE(c.Param("id"))
+	if err != nil {
+		return response.Failed(c, serverError.ErrInvalidRequest(err))
+	}
+
+	withdrawal, err := h.fundingUsecase.ApproveWithdrawal(ctx, withdrawalID)
+	if err != nil {
+		return response.Failed(c, err)
+	}
+
+	return response.Success(c, withdrawal)
+}
+
+func (h *fundingHandler) RejectWithdrawalHandler(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Get("ctx").(context.Context), h.timeout)
+	defer cancel()
+
+	withdrawalID, err := cast.ToIntE(c.Param("id"))
+	if err != nil {
+		return response.Failed(c, serverError.ErrInvalidRequest(err))
+	}
+
+	var requestPayload dto.RejectWithdrawalRequest
+	if err := c.Bind(&requestPayload); err != nil {
+		return response.Failed(c, err)
+	}
+
+	withdrawal, err := h.fundingUsecase.RejectWithdrawal(ctx, withdrawalID, requestPayload.Reason)
+	if err != nil {
+		return response.Failed(c, err)
+	}
+
+	return response.Success(c, withdrawal)
+}
@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"context"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"core-engine/config"
+	"core-engine/internal/app/domains/dto"
+	"core-engine/internal/app/domains/model"
+	httpMiddleware "core-engine/internal/app/middleware/http"
+	serverError "core-engine/pkg/error"
+	"core-engine/pkg/jwt"
+	"core-engine/pkg/response"
+)
+
+type ledgerHandler struct {
+	timeout             time.Duration
+	ledgerUsecase       model.LedgerUsecase
+	securityConfig      config.Security
+	denyList            jwt.DenyList
+	apiKeyAuthenticator httpMiddleware.ApiKeyAuthenticator
+}
+
+func NewLedgerHTTPHandler(ledgerUsecase model.LedgerUsecase, timeout time.Duration, securityConfig config.Security, denyList jwt.DenyList, apiKeyAuthenticator httpMiddleware.ApiKeyAuthenticator) interface {
+	InitRoutes(e *echo.Echo)
+} {
+	return &ledgerHandler{
+		timeout:             timeout,
+		ledgerUsecase:       ledgerUsecase,
+		securityConfig:      securityConfig,
+		denyList:            denyList,
+		apiKeyAuthenticator: apiKeyAuthenticator,
+	}
+}
+
+func (h *ledgerHandler) InitRoutes(e *echo.Echo) {
+	readScope := httpMiddleware.ValidateScope(model.ApiKeyScopeRead)
+
+	v1 := e.Group("/api/v1/ledger")
+	v1.Use(httpMiddleware.ValidateApiKey(h.apiKeyAuthenticator, httpMiddleware.ValidateJwtToken([]byte(h.securityConfig.Jwt.Key), h.denyList)))
+	{
+		v1.GET("", h.LedgerHandler, readScope)
+		v1.GET("/balance", h.BalanceProjectionHandler, readScope)
+	}
+}
+
+func (h *ledgerHandler) LedgerHandler(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Get("ctx").(context.Context), h.timeout)
+	defer cancel()
+
+	requestPayload := dto.LedgerRequest{Page: 1, Limit: 10}
+	if err := c.Bind(&requestPayload); err != nil {
+		return response.Failed(c, err)
+	}
+
+	if requestPayload.Page < 1 || requestPayload.Limit < 1 || requestPayload.Limit > 1000 {
+		return response.Failed(c, serverError.ErrInvalidRequest(nil))
+	}
+
+	entries, total, err := h.ledgerUsecase.GetLedger(ctx, requestPayload)
+	if err != nil {
+		return response.Failed(c, err)
+	}
+
+	return response.SuccessList(c, entries, requestPayload.Page, requestPayload.Limit, total)
+}
+
+func (h *ledgerHandler) BalanceProjectionHandler(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Get("ctx").(context.Context), h.timeout)
+	defer cancel()
+
+	balances, err := h.ledgerUsecase.GetBalanceProjection(ctx)
+	if err != nil {
+		return response.Failed(c, err)
+	}
+
+	return response.Success(c, balances)
+}
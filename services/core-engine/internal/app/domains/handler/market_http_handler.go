@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"context"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/spf13/cast"
+
+	"core-engine/config"
+	"core-engine/internal/app/domains/dto"
+	"core-engine/internal/app/domains/model"
+	httpMiddleware "core-engine/internal/app/middleware/http"
+	serverError "core-engine/pkg/error"
+	"core-engine/pkg/jwt"
+	"core-engine/pkg/response"
+)
+
+type marketHandler struct {
+	timeout        time.Duration
+	marketUsecase  model.MarketUsecase
+	securityConfig config.Security
+	denyList       jwt.DenyList
+}
+
+func NewMarketHTTPHandler(marketUsecase model.MarketUsecase, timeout time.Duration, securityConfig config.Security, denyList jwt.DenyList) interface {
+	InitRoutes(e *echo.Echo)
+} {
+	return &marketHandler{
+		timeout:        timeout,
+		marketUsecase:  marketUsecase,
+		securityConfig: securityConfig,
+		denyList:       denyList,
+	}
+}
+
+func (h *marketHandler) InitRoutes(e *echo.Echo) {
+	crypto := e.Group("/api/v1/admin/crypto")
+	crypto.Use(httpMiddleware.ValidateJwtToken([]byte(h.securityConfig.Jwt.Key), h.denyList))
+	crypto.Use(httpMiddleware.ValidateRole(model.UserRoleAdmin))
+	{
+		crypto.POST("", h.CreateCryptoHandler)
+		crypto.PUT("/:id", h.UpdateCryptoHandler)
+	}
+
+	pair := e.Group("/api/v1/admin/pair")
+	pair.Use(httpMiddleware.ValidateJwtToken([]byte(h.securityConfig.Jwt.Key), h.denyList))
+	pair.Use(httpMiddleware.ValidateRole(model.UserRoleAdmin))
+	{
+		pair.POST("", h.CreatePairHandler)
+		pair.PUT("/:id", h.UpdatePairHandler)
+	}
+}
+
+func (h *marketHandler) CreateCryptoHandler(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Get("ctx").(context.Context), h.timeout)
+	defer cancel()
+
+	var requestPayload dto.CryptoRequest
+	if err := c.Bind(&requestPayload); err != nil {
+		return response.Failed(c, err)
+	}
+
+	crypto, err := h.marketUsecase.CreateCrypto(ctx, requestPayload)
+	if err != nil {
+		return response.Failed(c, err)
+	}
+
+	return response.Success(c, crypto)
+}
+
+func (h *marketHandler) UpdateCryptoHandler(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Get("ctx").(context.Context), h.timeout)
+	defer cancel()
+
+	cryptoID, err := cast.ToIntE(c.Param("id"))
+	if err != nil {
+		return response.Failed(c, serverError.ErrInvalidRequest(err))
+	}
+
+	var requestPayload dto.CryptoUpdateRequest
+	if err := c.Bind(&requestPayload); err != nil {
+		return response.Failed(c, err)
+	}
+
+	crypto, err := h.marketUsecase.UpdateCrypto(ctx, cryptoID, requestPayload)
+	if err != nil {
+		return response.Failed(c, err)
+	}
+
+	return response.Success(c, crypto)
+}
+
+func (h *marketHandler) CreatePairHandler(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Get("ctx").(context.Context), h.timeout)
+	defer cancel()
+
+	var requestPayload dto.PairRequest
+	if err := c.Bind(&requestPayload); err != nil {
+		return response.Failed(c, err)
+	}
+
+	pair, err := h.marketUsecase.CreatePair(ctx, requestPayload)
+	if err != nil {
+		return response.Failed(c, err)
+	}
+
+	return response.Success(c, pair)
+}
+
+func (h *marketHandler) UpdatePairHandler(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Get("ctx").(context.Context), h.timeout)
+	defer cancel()
+
+	pairID, err := cast.ToIntE(c.Param("id"))
+	if err != nil {
+		return response.Failed(c, serverError.ErrInvalidRequest(err))
+	}
+
+	var requestPayload dto.PairUpdateRequest
+	if err := c.Bind(&requestPayload); err != nil {
+		return response.Failed(c, err)
+	}
+
+	pair, err := h.marketUsecase.UpdatePair(ctx, pairID, requestPayload)
+	if err != nil {
+		return response.Failed(c, err)
+	}
+
+	return response.Success(c, pair)
+}
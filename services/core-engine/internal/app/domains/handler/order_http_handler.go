@@ -5,35 +5,49 @@ import (
 	"time"
 
 	"github.com/labstack/echo/v4"
+	"github.com/spf13/cast"
 
 	"core-engine/config"
 	"core-engine/internal/app/domains/dto"
 	"core-engine/internal/app/domains/model"
 	httpMiddleware "core-engine/internal/app/middleware/http"
+	serverError "core-engine/pkg/error"
+	"core-engine/pkg/jwt"
 	"core-engine/pkg/response"
 )
 
 type orderHandler struct {
-	timeout        time.Duration
-	orderUsecase   model.OrderUsecase
-	securityConfig config.Security
+	timeout             time.Duration
+	orderUsecase        model.OrderUsecase
+	securityConfig      config.Security
+	denyList            jwt.DenyList
+	apiKeyAuthenticator httpMiddleware.ApiKeyAuthenticator
 }
 
-func NewOrderHTTPHandler(orderUsecase model.OrderUsecase, timeout time.Duration, securityConfig config.Security) interface {
+func NewOrderHTTPHandler(orderUsecase model.OrderUsecase, timeout time.Duration, securityConfig config.Security, denyList jwt.DenyList, apiKeyAuthenticator httpMiddleware.ApiKeyAuthenticator) interface {
 	InitRoutes(e *echo.Echo)
 } {
 	return &orderHandler{
-		timeout:        timeout,
-		orderUsecase:   orderUsecase,
-		securityConfig: securityConfig,
+		timeout:             timeout,
+		orderUsecase:        orderUsecase,
+		securityConfig:      securityConfig,
+		denyList:            denyList,
+		apiKeyAuthenticator: apiKeyAuthenticator,
 	}
 }
 
 func (h *orderHandler) InitRoutes(e *echo.Echo) {
+	// A bot calls with an API key instead of the login token, each route requires a scope of the key
+	readScope := httpMiddleware.ValidateScope(model.ApiKeyScopeRead)
+	tradeScope := httpMiddleware.ValidateScope(model.ApiKeyScopeTrade)
+
 	v1 := e.Group("/api/v1/order")
-	v1.Use(httpMiddleware.ValidateJwtToken([]byte(h.securityConfig.Jwt.Key)))
+	v1.Use(httpMiddleware.ValidateApiKey(h.apiKeyAuthenticator, httpMiddleware.ValidateJwtToken([]byte(h.securityConfig.Jwt.Key), h.denyList)))
 	{
-		v1.POST("", h.OrderHandler)
+		v1.POST("", h.OrderHandler, tradeScope)
+		v1.GET("", h.OrderListHandler, readScope)
+		v1.GET("/open", h.OpenOrderListHandler, readScope)
+		v1.GET("/:id", h.OrderDetailHandler, readScope)
 	}
 }
 
@@ -53,3 +67,54 @@ func (h *orderHandler) OrderHandler(c echo.Context) error {
 
 	return response.Success(c, orderResult)
 }
+
+func (h *orderHandler) OrderListHandler(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Get("ctx").(context.Context), h.timeout)
+	defer cancel()
+
+	requestPayload := dto.OrderListRequest{Page: 1, Limit: 10}
+	if err := c.Bind(&requestPayload); err != nil {
+		return response.Failed(c, err)
+	}
+
+	orders, total, err := h.orderUsecase.GetOrders(ctx, requestPayload)
+	if err != nil {
+		return response.Failed(c, err)
+	}
+
+	return response.SuccessList(c, orders, requestPayload.Page, requestPayload.Limit, total)
+}
+
+func (h *orderHandler) OpenOrderListHandler(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Get("ctx").(context.Context), h.timeout)
+	defer cancel()
+
+	requestPayload := dto.OrderListRequest{Page: 1, Limit: 10}
+	if err := c.Bind(&requestPayload); err != nil {
+		return response.Failed(c, err)
+	}
+
+	orders, total, err := h.orderUsecase.GetOpenOrders(ctx, requestPayload)
+	if err != nil {
+		return response.Failed(c, err)
+	}
+
+	return response.SuccessList(c, orders, requestPayload.Page, requestPayload.Limit, total)
+}
+
+func (h *orderHandler) OrderDetailHandler(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Get("ctx").(context.Context), h.timeout)
+	defer cancel()
+
+	orderID, err := cast.ToIntE(c.Param("id"))
+	if err != nil {
+		return response.Failed(c, serverError.ErrInvalidRequest(err))
+	}
+
+	order, err := h.orderUsecase.GetOrder(ctx, orderID)
+	if err != nil {
+		return response.Failed(c, err)
+	}
+
+	return response.Success(c, order)
+}
@@ -2,75 +2,308 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"sync"
 	"time"
 
 	"github.com/gerins/log"
+	"github.com/redis/go-redis/v9"
 	"github.com/segmentio/kafka-go"
 
+	"core-engine/config"
 	"core-engine/internal/app/domains/dto"
 	"core-engine/internal/app/domains/model"
+	kafkapkg "core-engine/pkg/kafka"
+	redispkg "core-engine/pkg/redis"
 )
 
 type queueHandler struct {
-	kafkaConsumer *kafka.Reader
-	orderUsecase  model.OrderUsecase
-	timeout       time.Duration
+	matchOrderConsumer  *kafka.Reader
+	orderUpdateConsumer *kafka.Reader
+	retry               *kafkapkg.RetryProcessor
+	cache               *redis.Client
+	orderUsecase        model.OrderUsecase
+	batch               config.Batch
+	timeout             time.Duration
 }
 
-func NewOrderQueueHandler(kafkaConsumer *kafka.Reader, orderUsecase model.OrderUsecase, timeout time.Duration) *queueHandler {
+func NewOrderQueueHandler(
+	matchOrderConsumer *kafka.Reader,
+	orderUpdateConsumer *kafka.Reader,
+	retry *kafkapkg.RetryProcessor,
+	cache *redis.Client,
+	orderUsecase model.OrderUsecase,
+	batch config.Batch,
+	timeout time.Duration,
+) *queueHandler {
 	return &queueHandler{
-		kafkaConsumer: kafkaConsumer,
-		orderUsecase:  orderUsecase,
-		timeout:       timeout,
+		matchOrderConsumer:  matchOrderConsumer,
+		orderUpdateConsumer: orderUpdateConsumer,
+		retry:               retry,
+		cache:               cache,
+		orderUsecase:        orderUsecase,
+		batch:               batch,
+		timeout:             timeout,
 	}
 }
 
 func (h *queueHandler) StartConsumer() {
+	if h.batch.Size > 1 {
+		h.consumeBatch(h.matchOrderConsumer)
+	} else {
+		h.consume(h.matchOrderConsumer, h.MatchOrderHandler)
+	}
+
+	h.consume(h.orderUpdateConsumer, h.OrderUpdateHandler)
+}
+
+// consume handles every partition concurrently and the messages of a partition one by one, in order
+func (h *queueHandler) consume(kafkaConsumer *kafka.Reader, handle func(ctx context.Context, msg []byte) error) {
 	go func() {
+		partitions := make(map[int]chan kafka.Message)
+		defer func() {
+			for _, messages := range partitions {
+				close(messages)
+			}
+		}()
+
 		for {
-			kafkaMessage, err := h.kafkaConsumer.FetchMessage(context.Background())
+			kafkaMessage, err := kafkaConsumer.FetchMessage(context.Background())
 			if err != nil {
+				if errors.Is(err, io.EOF) {
+					return // Consumer closed
+				}
 				continue
 			}
 
-			go func() {
-				logging := log.NewRequest()
-				logging.Method = kafkaMessage.Topic
-				logging.IP = string(kafkaMessage.Key)
-				logging.URL = fmt.Sprintf("partition %v offset %v", kafkaMessage.Partition, kafkaMessage.Offset)
+			messages, ok := partitions[kafkaMessage.Partition]
+			if !ok {
+				messages = make(chan kafka.Message, partitionQueueSize)
+				partitions[kafkaMessage.Partition] = messages
+				go h.consumePartition(kafkaConsumer, messages, handle)
+			}
+
+			messages <- kafkaMessage // Blocks the fetch while the partition is behind
+		}
+	}()
+}
+
+// consumePartition commits a message only after every message before it is done, a message that can be
+// neither handled nor dead-lettered blocks the partition until it is
+func (h *queueHandler) consumePartition(kafkaConsumer *kafka.Reader, messages <-chan kafka.Message, handle func(ctx context.Context, msg []byte) error) {
+	for kafkaMessage := range messages {
+		h.processUntilDone(kafkaMessage, handle)
+
+		// Failed commit is covered by the commit of a later offset
+		if err := kafkaConsumer.CommitMessages(context.Background(), kafkaMessage); err != nil {
+			log.Error(err)
+		}
+	}
+}
 
-				// Parent context
-				ctx, cancel := context.WithTimeout(context.Background(), h.timeout)
-				defer func() { logging.Save(); cancel() }()
+// consumeBatch collects up to batch size trades, or what arrive within the batch wait, and settles
+// the trades of each partition in one transaction. The offsets are committed after the database commit.
+func (h *queueHandler) consumeBatch(kafkaConsumer *kafka.Reader) {
+	messages := make(chan kafka.Message, h.batch.Size)
 
-				// Proceed match order
-				if err := h.MatchOrderHandler(logging.SaveToContext(ctx), kafkaMessage.Value); err != nil {
-					return // Dont commit message if error occur
+	go func() {
+		defer close(messages)
+		for {
+			kafkaMessage, err := kafkaConsumer.FetchMessage(context.Background())
+			if err != nil {
+				if errors.Is(err, io.EOF) {
+					return // Consumer closed
 				}
+				continue
+			}
 
-				// Commit message
-				if err := h.kafkaConsumer.CommitMessages(ctx, kafkaMessage); err != nil {
-					log.Context(ctx).Error(err)
-					return
+			messages <- kafkaMessage
+		}
+	}()
+
+	go func() {
+		for first := range messages {
+			batch := []kafka.Message{first}
+			timer := time.NewTimer(h.batch.Wait)
+
+		collect:
+			for len(batch) < h.batch.Size {
+				select {
+				case kafkaMessage, ok := <-messages:
+					if !ok {
+						break collect
+					}
+					batch = append(batch, kafkaMessage)
+				case <-timer.C:
+					break collect
 				}
-			}()
+			}
+			timer.Stop()
+
+			// Partitions are independent, the order within a partition is kept
+			partitions := make(map[int][]kafka.Message)
+			for _, kafkaMessage := range batch {
+				partitions[kafkaMessage.Partition] = append(partitions[kafkaMessage.Partition], kafkaMessage)
+			}
+
+			var wg sync.WaitGroup
+			for _, partitionMessages := range partitions {
+				wg.Add(1)
+				go func(partitionMessages []kafka.Message) {
+					defer wg.Done()
+					h.settleBatch(kafkaConsumer, partitionMessages)
+				}(partitionMessages)
+			}
+			wg.Wait()
 		}
 	}()
 }
 
+const (
+	blockedMessageBackoff = time.Second // Wait before processing again a message that could not be handled nor dead-lettered
+	partitionQueueSize    = 64          // Message fetched ahead of the one being handled, per partition
+)
+
+// settleBatch settles the trades of a single partition, when the batch fails every message is
+// handled on its own so only the failing one is retried and moved to the dead-letter topic. The
+// partition is blocked until each message is done, so a later batch never commits past it.
+func (h *queueHandler) settleBatch(kafkaConsumer *kafka.Reader, kafkaMessages []kafka.Message) {
+	last := kafkaMessages[len(kafkaMessages)-1]
+
+	logging := log.NewRequest()
+	logging.Method = last.Topic
+	logging.URL = fmt.Sprintf("partition %v offset %v-%v", last.Partition, kafkaMessages[0].Offset, last.Offset)
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.timeout)
+	ctx = logging.SaveToContext(ctx)
+
+	err := h.MatchOrdersHandler(ctx, kafkaMessages)
+	logging.Save()
+	cancel()
+
+	if err != nil {
+		log.Errorf("failed settling batch of %v trades, settling one by one, %v", len(kafkaMessages), err)
+
+		for _, kafkaMessage := range kafkaMessages {
+			h.processUntilDone(kafkaMessage, h.MatchOrderHandler)
+
+			// Failed commit is covered by the commit of a later offset
+			if err := kafkaConsumer.CommitMessages(context.Background(), kafkaMessage); err != nil {
+				log.Error(err)
+			}
+		}
+		return
+	}
+
+	// Commit message
+	if err := kafkaConsumer.CommitMessages(context.Background(), last); err != nil {
+		log.Error(err)
+	}
+}
+
+// processUntilDone returns once the message is handled or moved to the dead-letter topic
+func (h *queueHandler) processUntilDone(kafkaMessage kafka.Message, handle func(ctx context.Context, msg []byte) error) {
+	for err := h.process(kafkaMessage, handle); err != nil; err = h.process(kafkaMessage, handle) {
+		log.Errorf("partition %v blocked at offset %v, %v", kafkaMessage.Partition, kafkaMessage.Offset, err)
+		time.Sleep(blockedMessageBackoff)
+	}
+}
+
+// process handles a single message, failed message is retried then moved to the dead-letter topic
+func (h *queueHandler) process(kafkaMessage kafka.Message, handle func(ctx context.Context, msg []byte) error) error {
+	return h.retry.Process(context.Background(), kafkaMessage, func() error {
+		logging := log.NewRequest()
+		logging.Method = kafkaMessage.Topic
+		logging.IP = string(kafkaMessage.Key)
+		logging.URL = fmt.Sprintf("partition %v offset %v", kafkaMessage.Partition, kafkaMessage.Offset)
+
+		// Parent context
+		ctx, cancel := context.WithTimeout(context.Background(), h.timeout)
+		defer func() { logging.Save(); cancel() }()
+
+		return handle(logging.SaveToContext(ctx), kafkaMessage.Value)
+	})
+}
+
+// MatchOrdersHandler settles every valid trade of the messages in one transaction
+func (h *queueHandler) MatchOrdersHandler(ctx context.Context, kafkaMessages []kafka.Message) error {
+	payloads := make(dto.BulkTradeRequest, 0, len(kafkaMessages))
+	for _, kafkaMessage := range kafkaMessages {
+		var payload dto.TradeRequest
+		if err := payload.FromJSON(kafkaMessage.Value); err != nil {
+			return err // Handled one by one and moved to the dead-letter topic
+		}
+
+		if valid, err := h.validFencingToken(ctx, payload.PairID, payload.FencingToken, payload.TermStart, payload.Sequence); err != nil {
+			return err
+		} else if !valid {
+			continue // Trade from stale leader, it must never be applied
+		}
+
+		payloads = append(payloads, payload)
+	}
+
+	log.Context(ctx).ReqBody = payloads
+
+	return h.orderUsecase.MatchOrders(ctx, payloads)
+}
+
 func (h *queueHandler) MatchOrderHandler(ctx context.Context, msg []byte) error {
 	var payload dto.TradeRequest
 
 	if err := payload.FromJSON(msg); err != nil {
 		log.Context(ctx).Error(err)
-		return err
+		return kafkapkg.Permanent(err)
 	}
 
 	log.Context(ctx).ReqBody = payload
+
+	if valid, err := h.validFencingToken(ctx, payload.PairID, payload.FencingToken, payload.TermStart, payload.Sequence); !valid || err != nil {
+		return err // Commit the message from stale leader, it must never be applied
+	}
+
 	if err := h.orderUsecase.MatchOrder(ctx, payload); err != nil {
 		return err
 	}
 
 	return nil
 }
+
+func (h *queueHandler) OrderUpdateHandler(ctx context.Context, msg []byte) error {
+	var payload dto.OrderUpdateRequest
+
+	if err := payload.FromJSON(msg); err != nil {
+		log.Context(ctx).Error(err)
+		return kafkapkg.Permanent(err)
+	}
+
+	log.Context(ctx).ReqBody = payload
+
+	if valid, err := h.validFencingToken(ctx, payload.PairID, payload.FencingToken, payload.TermStart, payload.TradeSequence); !valid || err != nil {
+		return err // Commit the message from stale leader, it must never be applied
+	}
+
+	return h.orderUsecase.CompleteOrder(ctx, payload)
+}
+
+// Message without token comes from a standalone matching engine
+func (h *queueHandler) validFencingToken(ctx context.Context, pairID int, token, termStart, sequence int64) (bool, error) {
+	if token == 0 {
+		return true, nil
+	}
+
+	key := fmt.Sprintf("fencing#term#%v", pairID)
+	valid, err := redispkg.CheckFencingToken(ctx, h.cache, key, token, termStart, sequence)
+	if err != nil {
+		log.Context(ctx).Error(err)
+		return false, err
+	}
+
+	if !valid {
+		log.Context(ctx).Errorf("rejected message from stale matching engine leader, fencing token %v sequence %v", token, sequence)
+	}
+
+	return valid, nil
+}
@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"context"
+	"encoding/csv"
+	"net/http"
+	"time"
+
+	"github.com/gerins/log"
+	"github.com/labstack/echo/v4"
+	"github.com/spf13/cast"
+
+	"core-engine/config"
+	"core-engine/internal/app/domains/dto"
+	"core-engine/internal/app/domains/model"
+	httpMiddleware "core-engine/internal/app/middleware/http"
+	"core-engine/pkg/jwt"
+	"core-engine/pkg/response"
+)
+
+// Trades fetched per query while exporting
+const tradeExportPageSize = 1000
+
+type tradeHandler struct {
+	timeout             time.Duration
+	tradeUsecase        model.TradeUsecase
+	securityConfig      config.Security
+	denyList            jwt.DenyList
+	apiKeyAuthenticator httpMiddleware.ApiKeyAuthenticator
+}
+
+func NewTradeHTTPHandler(tradeUsecase model.TradeUsecase, timeout time.Duration, securityConfig config.Security, denyList jwt.DenyList, apiKeyAuthenticator httpMiddleware.ApiKeyAuthenticator) interface {
+	InitRoutes(e *echo.Echo)
+} {
+	return &tradeHandler{
+		timeout:             timeout,
+		tradeUsecase:        tradeUsecase,
+		securityConfig:      securityConfig,
+		denyList:            denyList,
+		apiKeyAuthenticator: apiKeyAuthenticator,
+	}
+}
+
+func (h *tradeHandler) InitRoutes(e *echo.Echo) {
+	readScope := httpMiddleware.ValidateScope(model.ApiKeyScopeRead)
+
+	v1 := e.Group("/api/v1/trades")
+	v1.Use(httpMiddleware.ValidateApiKey(h.apiKeyAuthenticator, httpMiddleware.ValidateJwtToken([]byte(h.securityConfig.Jwt.Key), h.denyList)))
+	{
+		v1.GET("", h.TradeListHandler, readScope)
+		v1.GET("/export", h.TradeExportHandler, readScope)
+	}
+}
+
+func (h *tradeHandler) TradeListHandler(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Get("ctx").(context.Context), h.timeout)
+	defer cancel()
+
+	requestPayload := dto.TradeListRequest{Limit: 50}
+	if err := c.Bind(&requestPayload); err != nil {
+		return response.Failed(c, err)
+	}
+
+	trades, nextCursor, err := h.tradeUsecase.GetTrades(ctx, requestPayload)
+	if err != nil {
+		return response.Failed(c, err)
+	}
+
+	return response.SuccessCursor(c, trades, requestPayload.Limit, nextCursor)
+}
+
+// TradeExportHandler writes every trade matching the filter as CSV, page by page
+func (h *tradeHandler) TradeExportHandler(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Get("ctx").(context.Context), h.timeout)
+	defer cancel()
+
+	var requestPayload dto.TradeListRequest
+	if err := c.Bind(&requestPayload); err != nil {
+		return response.Failed(c, err)
+	}
+	requestPayload.Limit = tradeExportPageSize
+
+	// The first page is fetched before writing so an invalid request still get a JSON error
+	trades, nextCursor, err := h.tradeUsecase.GetTrades(ctx, requestPayload)
+	if err != nil {
+		return response.Failed(c, err)
+	}
+
+	c.Response().Header().Set(echo.HeaderContentType, "text/csv")
+	c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="trades.csv"`)
+	c.Response().WriteHeader(http.StatusOK)
+
+	writer := csv.NewWriter(c.Response())
+	_ = writer.Write([]string{"match_order_id", "order_id", "pair_code", "side", "role", "price", "quantity", "fee", "fee_crypto_id", "transaction_time"})
+
+	for {
+		for _, trade := range trades {
+			_ = writer.Write([]string{
+				cast.ToString(trade.MatchOrderID),
+				cast.ToString(trade.OrderID),
+				trade.PairCode,
+				string(trade.Side),
+				string(trade.Role),
+				cast.ToString(trade.Price),
+				cast.ToString(trade.Quantity),
+				cast.ToString(trade.Fee),
+				cast.ToString(trade.FeeCryptoID),
+				cast.ToString(trade.TransactionTime),
+			})
+		}
+		writer.Flush()
+
+		if nextCursor == "" {
+			break
+		}
+
+		requestPayload.Cursor = nextCursor
+		if trades, nextCursor, err = h.tradeUsecase.GetTrades(ctx, requestPayload); err != nil {
+			log.Context(ctx).Error(err) // Status already sent, the export is cut short
+			break
+		}
+	}
+
+	return writer.Error()
+}
@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"context"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"core-engine/config"
+	"core-engine/internal/app/domains/dto"
+	"core-engine/internal/app/domains/model"
+	httpMiddleware "core-engine/internal/app/middleware/http"
+	"core-engine/pkg/jwt"
+	"core-engine/pkg/response"
+)
+
+type transferHandler struct {
+	timeout             time.Duration
+	transferUsecase     model.TransferUsecase
+	securityConfig      config.Security
+	denyList            jwt.DenyList
+	apiKeyAuthenticator httpMiddleware.ApiKeyAuthenticator
+}
+
+func NewTransferHTTPHandler(transferUsecase model.TransferUsecase, timeout time.Duration, securityConfig config.Security, denyList jwt.DenyList, apiKeyAuthenticator httpMiddleware.ApiKeyAuthenticator) interface {
+	InitRoutes(e *echo.Echo)
+} {
+	return &transferHandler{
+		timeout:             timeout,
+		transferUsecase:     transferUsecase,
+		securityConfig:      securityConfig,
+		denyList:            denyList,
+		apiKeyAuthenticator: apiKeyAuthenticator,
+	}
+}
+
+func (h *transferHandler) InitRoutes(e *echo.Echo) {
+	readScope := httpMiddleware.ValidateScope(model.ApiKeyScopeRead)
+	withdrawScope := httpMiddleware.ValidateScope(model.ApiKeyScopeWithdraw)
+
+	v1 := e.Group("/api/v1/transfer")
+	v1.Use(httpMiddleware.ValidateApiKey(h.apiKeyAuthenticator, httpMiddleware.ValidateJwtToken([]byte(h.securityConfig.Jwt.Key), h.denyList)))
+	{
+		v1.POST("", h.TransferHandler, withdrawScope)
+		v1.GET("", h.TransferListHandler, readScope)
+	}
+}
+
+func (h *transferHandler) TransferHandler(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Get("ctx").(context.Context), h.timeout)
+	defer cancel()
+
+	var requestPayload dto.TransferRequest
+	if err := c.Bind(&requestPayload); err != nil {
+		return response.Failed(c, err)
+	}
+
+	transfer, err := h.transferUsecase.Transfer(ctx, requestPayload)
+	if err != nil {
+		return response.Failed(c, err)
+	}
+
+	return response.Success(c, transfer)
+}
+
+func (h *transferHandler) TransferListHandler(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Get("ctx").(context.Context), h.timeout)
+	defer cancel()
+
+	requestPayload := dto.TransferListRequest{Page: 1, Limit: 10}
+	if err := c.Bind(&requestPayload); err != nil {
+		return response.Failed(c, err)
+	}
+
+	transfers, total, err := h.transferUsecase.GetTransfers(ctx, requestPayload)
+	if err != nil {
+		return response.Failed(c, err)
+	}
+
+	return response.SuccessList(c, transfers, requestPayload.Page, requestPayload.Limit, total)
+}
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"context"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"core-engine/config"
+	"core-engine/internal/app/domains/dto"
+	"core-engine/internal/app/domains/model"
+	httpMiddleware "core-engine/internal/app/middleware/http"
+	"core-engine/pkg/jwt"
+	"core-engine/pkg/response"
+)
+
+type twoFactorHandler struct {
+	timeout          time.Duration
+	twoFactorUsecase model.TwoFactorUsecase
+	securityConfig   config.Security
+	denyList         jwt.DenyList
+}
+
+func NewTwoFactorHTTPHandler(twoFactorUsecase model.TwoFactorUsecase, timeout time.Duration, securityConfig config.Security, denyList jwt.DenyList) interface {
+	InitRoutes(e *echo.Echo)
+} {
+	return &twoFactorHandler{
+		timeout:          timeout,
+		twoFactorUsecase: twoFactorUsecase,
+		securityConfig:   securityConfig,
+		denyList:         denyList,
+	}
+}
+
+func (h *twoFactorHandler) InitRoutes(e *echo.Echo) {
+	v1 := e.Group("/api/v1/user/2fa")
+	v1.Use(httpMiddleware.ValidateJwtToken([]byte(h.securityConfig.Jwt.Key), h.denyList))
+	{
+		v1.POST("/enroll", h.EnrollHandler)
+		v1.POST("/enable", h.EnableHandler)
+		v1.POST("/disable", h.DisableHandler)
+	}
+}
+
+func (h *twoFactorHandler) EnrollHandler(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Get("ctx").(context.Context), h.timeout)
+	defer cancel()
+
+	enrollResult, err := h.twoFactorUsecase.Enroll(ctx)
+	if err != nil {
+		return response.Failed(c, err)
+	}
+
+	return response.Success(c, enrollResult)
+}
+
+func (h *twoFactorHandler) EnableHandler(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Get("ctx").(context.Context), h.timeout)
+	defer cancel()
+
+	var requestPayload dto.TwoFactorRequest
+	if err := c.Bind(&requestPayload); err != nil {
+		return response.Failed(c, err)
+	}
+
+	enableResult, err := h.twoFactorUsecase.Enable(ctx, requestPayload.TotpCode)
+	if err != nil {
+		return response.Failed(c, err)
+	}
+
+	return response.Success(c, enableResult)
+}
+
+func (h *twoFactorHandler) DisableHandler(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Get("ctx").(context.Context), h.timeout)
+	defer cancel()
+
+	var requestPayload dto.TwoFactorRequest
+	if err := c.Bind(&requestPayload); err != nil {
+		return response.Failed(c, err)
+	}
+
+	if err := h.twoFactorUsecase.Disable(ctx, requestPayload.TotpCode); err != nil {
+		return response.Failed(c, err)
+	}
+
+	return response.Success(c, nil)
+}
@@ -6,20 +6,27 @@ import (
 
 	"github.com/labstack/echo/v4"
 
+	"core-engine/config"
 	"core-engine/internal/app/domains/dto"
 	"core-engine/internal/app/domains/model"
+	httpMiddleware "core-engine/internal/app/middleware/http"
+	"core-engine/pkg/jwt"
 	"core-engine/pkg/response"
 )
 
 type userHandler struct {
-	timeout     time.Duration
-	userUsecase model.UserUsecase
+	timeout        time.Duration
+	userUsecase    model.UserUsecase
+	securityConfig config.Security
+	denyList       jwt.DenyList
 }
 
-func NewUserHandler(userUsecase model.UserUsecase, timeout time.Duration) interface{ InitRoutes(e *echo.Echo) } {
+func NewUserHandler(userUsecase model.UserUsecase, timeout time.Duration, securityConfig config.Security, denyList jwt.DenyList) interface{ InitRoutes(e *echo.Echo) } {
 	return &userHandler{
-		timeout:     timeout,
-		userUsecase: userUsecase,
+		timeout:        timeout,
+		userUsecase:    userUsecase,
+		securityConfig: securityConfig,
+		denyList:       denyList,
 	}
 }
 
@@ -28,6 +35,9 @@ func (h *userHandler) InitRoutes(e *echo.Echo) {
 	{
 		v1.POST("/login", h.LoginHandler)
 		v1.POST("/register", h.RegisterHandler)
+		v1.POST("/refresh", h.RefreshHandler)
+		v1.POST("/logout", h.LogoutHandler, httpMiddleware.ValidateJwtToken([]byte(h.securityConfig.Jwt.Key), h.denyList))
+		v1.POST("/password", h.ChangePasswordHandler, httpMiddleware.ValidateJwtToken([]byte(h.securityConfig.Jwt.Key), h.denyList))
 	}
 }
 
@@ -64,3 +74,47 @@ func (h *userHandler) RegisterHandler(c echo.Context) error {
 
 	return response.Success(c, registerResult)
 }
+
+func (h *userHandler) RefreshHandler(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Get("ctx").(context.Context), h.timeout)
+	defer cancel()
+
+	var requestPayload dto.RefreshRequest
+	if err := c.Bind(&requestPayload); err != nil {
+		return response.Failed(c, err)
+	}
+
+	refreshResult, err := h.userUsecase.Refresh(ctx, requestPayload)
+	if err != nil {
+		return response.Failed(c, err)
+	}
+
+	return response.Success(c, refreshResult)
+}
+
+func (h *userHandler) LogoutHandler(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Get("ctx").(context.Context), h.timeout)
+	defer cancel()
+
+	if err := h.userUsecase.Logout(ctx); err != nil {
+		return response.Failed(c, err)
+	}
+
+	return response.Success(c, nil)
+}
+
+func (h *userHandler) ChangePasswordHandler(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Get("ctx").(context.Context), h.timeout)
+	defer cancel()
+
+	var requestPayload dto.ChangePasswordRequest
+	if err := c.Bind(&requestPayload); err != nil {
+		return response.Failed(c, err)
+	}
+
+	if err := h.userUsecase.ChangePassword(ctx, requestPayload); err != nil {
+		return response.Failed(c, err)
+	}
+
+	return response.Success(c, nil)
+}
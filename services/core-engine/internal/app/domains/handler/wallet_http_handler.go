@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"context"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"core-engine/config"
+	"core-engine/internal/app/domains/model"
+	httpMiddleware "core-engine/internal/app/middleware/http"
+	"core-engine/pkg/jwt"
+	"core-engine/pkg/response"
+)
+
+type walletHandler struct {
+	timeout             time.Duration
+	walletUsecase       model.WalletUsecase
+	securityConfig      config.Security
+	denyList            jwt.DenyList
+	apiKeyAuthenticator httpMiddleware.ApiKeyAuthenticator
+}
+
+func NewWalletHTTPHandler(walletUsecase model.WalletUsecase, timeout time.Duration, securityConfig config.Security, denyList jwt.DenyList, apiKeyAuthenticator httpMiddleware.ApiKeyAuthenticator) interface {
+	InitRoutes(e *echo.Echo)
+} {
+	return &walletHandler{
+		timeout:             timeout,
+		walletUsecase:       walletUsecase,
+		securityConfig:      securityConfig,
+		denyList:            denyList,
+		apiKeyAuthenticator: apiKeyAuthenticator,
+	}
+}
+
+func (h *walletHandler) InitRoutes(e *echo.Echo) {
+	readScope := httpMiddleware.ValidateScope(model.ApiKeyScopeRead)
+
+	// Separate group for each path, a group middleware also guards every unknown path under its prefix
+	wallet := e.Group("/api/v1/wallet")
+	wallet.Use(httpMiddleware.ValidateApiKey(h.apiKeyAuthenticator, httpMiddleware.ValidateJwtToken([]byte(h.securityConfig.Jwt.Key), h.denyList)))
+	{
+		wallet.GET("", h.WalletHandler, readScope)
+	}
+
+	portfolio := e.Group("/api/v1/portfolio")
+	portfolio.Use(httpMiddleware.ValidateApiKey(h.apiKeyAuthenticator, httpMiddleware.ValidateJwtToken([]byte(h.securityConfig.Jwt.Key), h.denyList)))
+	{
+		portfolio.GET("", h.PortfolioHandler, readScope)
+	}
+}
+
+func (h *walletHandler) WalletHandler(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Get("ctx").(context.Context), h.timeout)
+	defer cancel()
+
+	balances, err := h.walletUsecase.GetBalances(ctx)
+	if err != nil {
+		return response.Failed(c, err)
+	}
+
+	return response.Success(c, balances)
+}
+
+func (h *walletHandler) PortfolioHandler(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Get("ctx").(context.Context), h.timeout)
+	defer cancel()
+
+	portfolio, err := h.walletUsecase.GetPortfolio(ctx)
+	if err != nil {
+		return response.Failed(c, err)
+	}
+
+	return response.Success(c, portfolio)
+}
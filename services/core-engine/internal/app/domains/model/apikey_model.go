@@ -0,0 +1,94 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"time"
+
+	"core-engine/internal/app/domains/dto"
+	"core-engine/pkg/apikey"
+	"core-engine/pkg/jwt"
+)
+
+const (
+	ApiKeyScopeRead     = "READ"
+	ApiKeyScopeTrade    = "TRADE"
+	ApiKeyScopeWithdraw = "WITHDRAW" // Withdrawal and transfer, only with an IP allowlist
+)
+
+var (
+	ErrInvalidApiKeyScope   = errors.New("scope must be READ, TRADE or WITHDRAW")
+	ErrInvalidIPAllowlist   = errors.New("IP allowlist entry must be an IP or CIDR")
+	ErrWithdrawKeyAllowlist = errors.New("withdraw scope requires an IP allowlist")
+	ErrApiKeyNotFound       = errors.New("api key not found")
+	ErrApiKeyExpiredRequest = errors.New("request timestamp is outside the recv window")
+	ErrApiKeySignature      = errors.New("invalid request signature")
+	ErrApiKeyIPNotAllowed   = errors.New("request IP is not in the api key allowlist")
+)
+
+type ApiKey struct {
+	ID              int        `json:"id" gorm:"column:id;type:int;primaryKey;autoIncrement"`
+	UserID          int        `json:"user_id" gorm:"column:user_id;type:int"`
+	Name            string     `json:"name" gorm:"column:name;type:varchar;size:128"`
+	KeyID           string     `json:"key_id" gorm:"column:key_id;type:varchar;size:64"`
+	SecretEncrypted string     `json:"-" gorm:"column:secret_encrypted;type:varchar;size:255"`
+	Scopes          string     `json:"scopes" gorm:"column:scopes;type:varchar;size:64"`               // Comma separated
+	IPAllowlist     string     `json:"ip_allowlist" gorm:"column:ip_allowlist;type:varchar;size:1024"` // Comma separated, empty allows any
+	LastUsedAt      *time.Time `json:"last_used_at" gorm:"column:last_used_at;type:datetime"`
+	RevokedAt       *time.Time `json:"revoked_at" gorm:"column:revoked_at;type:datetime"`
+	CreatedAt       time.Time  `json:"created_at" gorm:"column:created_at;type:datetime"`
+}
+
+func (ApiKey) TableName() string {
+	return "api_keys"
+}
+
+// AllowsIP returns true when the allowlist is empty or one of its IP or CIDR contains the ip
+func (k ApiKey) AllowsIP(ip string) bool {
+	if k.IPAllowlist == "" {
+		return true
+	}
+
+	clientIP := net.ParseIP(ip)
+	if clientIP == nil {
+		return false
+	}
+
+	for _, entry := range strings.Split(k.IPAllowlist, ",") {
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			if network.Contains(clientIP) {
+				return true
+			}
+			continue
+		}
+
+		if allowed := net.ParseIP(entry); allowed != nil && allowed.Equal(clientIP) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ApiKeyCreated is returned once on creation, the secret can not be read again
+type ApiKeyCreated struct {
+	ApiKey
+	Secret string `json:"secret"`
+}
+
+type ApiKeyUsecase interface {
+	CreateApiKey(ctx context.Context, apiKeyReq dto.ApiKeyRequest) (ApiKeyCreated, error)
+	GetApiKeys(ctx context.Context) ([]ApiKey, error)
+	RevokeApiKey(ctx context.Context, id int) (ApiKey, error)
+	Authenticate(ctx context.Context, request apikey.Request) (jwt.Payload, error) // Payload of the key owner, with the key scopes
+}
+
+type ApiKeyRepository interface {
+	SaveApiKey(ctx context.Context, apiKey ApiKey) (ApiKey, error)
+	GetApiKeyByKeyID(ctx context.Context, keyID string) (ApiKey, error) // Not revoked only
+	GetUserApiKeys(ctx context.Context, userID int) ([]ApiKey, error)
+	RevokeApiKey(ctx context.Context, userID, id int) (ApiKey, error)
+	TouchApiKey(ctx context.Context, id int, usedAt time.Time) error
+}
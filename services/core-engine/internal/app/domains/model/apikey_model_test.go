@@ -0,0 +1,23 @@
+package model
+
+import "testing"
+
+func TestApiKeyAllowsIP(t *testing.T) {
+	tests := []struct {
+		allowlist, ip string
+		want          bool
+	}{
+		{"", "203.0.113.7", true},
+		{"203.0.113.7", "203.0.113.7", true},
+		{"203.0.113.7", "203.0.113.8", false},
+		{"198.51.100.1,10.0.0.0/8", "10.20.30.40", true},
+		{"10.0.0.0/8", "11.0.0.1", false},
+		{"10.0.0.0/8", "not an ip", false},
+	}
+
+	for _, test := range tests {
+		if got := (ApiKey{IPAllowlist: test.allowlist}).AllowsIP(test.ip); got != test.want {
+			t.Errorf("%q allows %q: got %v, want %v", test.allowlist, test.ip, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package model
+
+import (
+	"context"
+	"time"
+)
+
+// FeeTierDefault is the tier of every user without a special tier, schedule with pair ID 0 applies to every pair
+const FeeTierDefault = "DEFAULT"
+
+type FeeSchedule struct {
+	ID        int        `json:"id" gorm:"column:id;type:int;primaryKey;autoIncrement"`
+	PairID    int        `json:"pair_id" gorm:"column:pair_id;type:int"`
+	Tier      string     `json:"tier" gorm:"column:tier;type:varchar;size:32"`
+	MakerRate float64    `json:"maker_rate" gorm:"column:maker_rate;type:double"` // Negative rate is a rebate paid to the maker
+	TakerRate float64    `json:"taker_rate" gorm:"column:taker_rate;type:double"`
+	Status    bool       `json:"status" gorm:"column:status;type:tinyint"`
+	CreatedAt time.Time  `json:"created_at" gorm:"column:created_at;type:datetime"`
+	UpdatedAt time.Time  `json:"updated_at" gorm:"column:updated_at;type:datetime"`
+	DeletedAt *time.Time `json:"deleted_at" gorm:"column:deleted_at;type:datetime"`
+}
+
+func (FeeSchedule) TableName() string {
+	return "fee_schedules"
+}
+
+// MakerFee returns the fee charged from the amount received by the maker
+func (schedule FeeSchedule) MakerFee(amount float64) float64 {
+	return amount * schedule.MakerRate
+}
+
+// TakerFee returns the fee charged from the amount received by the taker
+func (schedule FeeSchedule) TakerFee(amount float64) float64 {
+	return amount * schedule.TakerRate
+}
+
+type FeeRepository interface {
+	// GetUserFeeSchedule returns the most specific schedule for the user tier and pair, zero fee when nothing match
+	GetUserFeeSchedule(ctx context.Context, pairID, userID int) (FeeSchedule, error)
+}
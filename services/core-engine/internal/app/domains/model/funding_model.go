@@ -0,0 +1,122 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"core-engine/internal/app/domains/dto"
+)
+
+type (
+	DepositStatus    string
+	WithdrawalStatus string
+)
+
+const (
+	DepositStatusPending    DepositStatus = "PENDING"    // Seen on the chain, amount held in the PENDING ledger account
+	DepositStatusConfirming DepositStatus = "CONFIRMING" // Mined, waiting for enough confirmations
+	DepositStatusCredited   DepositStatus = "CREDITED"   // Moved to the available balance
+)
+
+const (
+	WithdrawalStatusRequested WithdrawalStatus = "REQUESTED" // Amount locked, waiting for admin approval
+	WithdrawalStatusApproved  WithdrawalStatus = "APPROVED"  // Waiting to be broadcast
+	WithdrawalStatusBroadcast WithdrawalStatus = "BROADCAST" // Sent to the chain, waiting for confirmations
+	WithdrawalStatusCompleted WithdrawalStatus = "COMPLETED" // Locked amount left the exchange
+	WithdrawalStatusFailed    WithdrawalStatus = "FAILED"    // Rejected or failed on the chain, locked amount is refunded
+)
+
+var ErrInvalidTransition = errors.New("invalid status transition")
+
+var depositTransitions = map[DepositStatus][]DepositStatus{
+	DepositStatusPending:    {DepositStatusConfirming, DepositStatusCredited},
+	DepositStatusConfirming: {DepositStatusCredited},
+}
+
+var withdrawalTransitions = map[WithdrawalStatus][]WithdrawalStatus{
+	WithdrawalStatusRequested: {WithdrawalStatusApproved, WithdrawalStatusFailed},
+	WithdrawalStatusApproved:  {WithdrawalStatusBroadcast, WithdrawalStatusFailed},
+	WithdrawalStatusBroadcast: {WithdrawalStatusCompleted, WithdrawalStatusFailed},
+}
+
+func (s DepositStatus) CanTransitionTo(next DepositStatus) bool {
+	for _, status := range depositTransitions[s] {
+		if status == next {
+			return true
+		}
+	}
+	return false
+}
+
+func (s WithdrawalStatus) CanTransitionTo(next WithdrawalStatus) bool {
+	for _, status := range withdrawalTransitions[s] {
+		if status == next {
+			return true
+		}
+	}
+	return false
+}
+
+type Deposit struct {
+	ID            int           `json:"id" gorm:"column:id;type:int;primaryKey;autoIncrement"`
+	UserID        int           `json:"user_id" gorm:"column:user_id;type:int"`
+	CryptoID      int           `json:"crypto_id" gorm:"column:crypto_id;type:int"`
+	Amount        float64       `json:"amount" gorm:"column:amount;type:double"`
+	Address       string        `json:"address" gorm:"column:address;type:varchar;size:256"`
+	TxHash        string        `json:"tx_hash" gorm:"column:tx_hash;type:varchar;size:256"`
+	Confirmations int           `json:"confirmations" gorm:"column:confirmations;type:int"`
+	Status        DepositStatus `json:"status" gorm:"column:status;type:text;default:PENDING"`
+	CreatedAt     time.Time     `json:"created_at" gorm:"column:created_at;type:datetime"`
+	UpdatedAt     time.Time     `json:"updated_at" gorm:"column:updated_at;type:datetime"`
+}
+
+func (Deposit) TableName() string {
+	return "deposits"
+}
+
+type Withdrawal struct {
+	ID            int              `json:"id" gorm:"column:id;type:int;primaryKey;autoIncrement"`
+	UserID        int              `json:"user_id" gorm:"column:user_id;type:int"`
+	CryptoID      int              `json:"crypto_id" gorm:"column:crypto_id;type:int"`
+	Amount        float64          `json:"amount" gorm:"column:amount;type:double"`
+	Address       string           `json:"address" gorm:"column:address;type:varchar;size:256"`
+	TxHash        string           `json:"tx_hash" gorm:"column:tx_hash;type:varchar;size:256"`
+	Status        WithdrawalStatus `json:"status" gorm:"column:status;type:text;default:REQUESTED"`
+	FailureReason string           `json:"failure_reason" gorm:"column:failure_reason;type:varchar;size:512"`
+	ApprovedBy    int              `json:"approved_by" gorm:"column:approved_by;type:int"`
+	CreatedAt     time.Time        `json:"created_at" gorm:"column:created_at;type:datetime"`
+	UpdatedAt     time.Time        `json:"updated_at" gorm:"column:updated_at;type:datetime"`
+}
+
+func (Withdrawal) TableName() string {
+	return "withdrawals"
+}
+
+type FundingUsecase interface {
+	// User
+	GetDeposits(ctx context.Context, listReq dto.FundingListRequest) ([]Deposit, int, error)
+	GetWithdrawals(ctx context.Context, listReq dto.FundingListRequest) ([]Withdrawal, int, error)
+	RequestWithdrawal(ctx context.Context, withdrawalReq dto.WithdrawalRequest) (Withdrawal, error)
+
+	// Admin
+	RecordDeposit(ctx context.Context, depositReq dto.DepositRequest) (Deposit, error) // Idempotent by crypto and transaction hash
+	ApproveWithdrawal(ctx context.Context, id int) (Withdrawal, error)
+	RejectWithdrawal(ctx context.Context, id int, reason string) (Withdrawal, error)
+
+	// SyncChain moves deposits and withdrawals forward with the state of their chain transaction
+	SyncChain(ctx context.Context) error
+}
+
+type FundingRepository interface {
+	SaveDeposit(ctx context.Context, deposit Deposit) (Deposit, error)
+	GetDepositByTxHash(ctx context.Context, cryptoID int, txHash string) (Deposit, error)
+	GetDepositForUpdate(ctx context.Context, id int) (Deposit, error)
+	GetDepositsByStatus(ctx context.Context, statuses []DepositStatus, limit int) ([]Deposit, error)
+	GetUserDeposits(ctx context.Context, userID, page, limit int) ([]Deposit, int, error)
+
+	SaveWithdrawal(ctx context.Context, withdrawal Withdrawal) (Withdrawal, error)
+	GetWithdrawalForUpdate(ctx context.Context, id int) (Withdrawal, error)
+	GetWithdrawalsByStatus(ctx context.Context, statuses []WithdrawalStatus, limit int) ([]Withdrawal, error)
+	GetUserWithdrawals(ctx context.Context, userID, page, limit int) ([]Withdrawal, int, error)
+}
@@ -0,0 +1,45 @@
+package model
+
+import "testing"
+
+func TestDepositTransition(t *testing.T) {
+	tests := []struct {
+		from, to DepositStatus
+		want     bool
+	}{
+		{DepositStatusPending, DepositStatusConfirming, true},
+		{DepositStatusPending, DepositStatusCredited, true},
+		{DepositStatusConfirming, DepositStatusCredited, true},
+		{DepositStatusConfirming, DepositStatusPending, false},
+		{DepositStatusCredited, DepositStatusConfirming, false},
+		{DepositStatusCredited, DepositStatusCredited, false},
+	}
+
+	for _, test := range tests {
+		if got := test.from.CanTransitionTo(test.to); got != test.want {
+			t.Errorf("%v to %v: got %v, want %v", test.from, test.to, got, test.want)
+		}
+	}
+}
+
+func TestWithdrawalTransition(t *testing.T) {
+	tests := []struct {
+		from, to WithdrawalStatus
+		want     bool
+	}{
+		{WithdrawalStatusRequested, WithdrawalStatusApproved, true},
+		{WithdrawalStatusRequested, WithdrawalStatusFailed, true},
+		{WithdrawalStatusRequested, WithdrawalStatusBroadcast, false},
+		{WithdrawalStatusApproved, WithdrawalStatusBroadcast, true},
+		{WithdrawalStatusBroadcast, WithdrawalStatusCompleted, true},
+		{WithdrawalStatusBroadcast, WithdrawalStatusFailed, true},
+		{WithdrawalStatusCompleted, WithdrawalStatusFailed, false},
+		{WithdrawalStatusFailed, WithdrawalStatusApproved, false},
+	}
+
+	for _, test := range tests {
+		if got := test.from.CanTransitionTo(test.to); got != test.want {
+			t.Errorf("%v to %v: got %v, want %v", test.from, test.to, got, test.want)
+		}
+	}
+}
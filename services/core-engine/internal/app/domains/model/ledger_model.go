@@ -0,0 +1,173 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"core-engine/internal/app/domains/dto"
+)
+
+type (
+	LedgerAccount       string
+	LedgerEntryType     string
+	LedgerReferenceType string
+)
+
+const (
+	LedgerAccountAvailable LedgerAccount = "AVAILABLE"
+	LedgerAccountLocked    LedgerAccount = "LOCKED"
+	LedgerAccountExternal  LedgerAccount = "EXTERNAL" // Outside of the exchange, counterpart of deposit and adjustment, held by user 0
+	LedgerAccountPending   LedgerAccount = "PENDING"  // Deposit seen on the chain, not credited to the wallet yet
+)
+
+const (
+	LedgerEntryDeposit    LedgerEntryType = "DEPOSIT"
+	LedgerEntryLock       LedgerEntryType = "LOCK"
+	LedgerEntryUnlock     LedgerEntryType = "UNLOCK"
+	LedgerEntryFill       LedgerEntryType = "FILL"
+	LedgerEntryFee        LedgerEntryType = "FEE"
+	LedgerEntryRefund     LedgerEntryType = "REFUND"
+	LedgerEntryAdjustment LedgerEntryType = "ADJUSTMENT"
+	LedgerEntryWithdrawal LedgerEntryType = "WITHDRAWAL"
+	LedgerEntryTransfer   LedgerEntryType = "TRANSFER"
+)
+
+const (
+	LedgerReferenceUser       LedgerReferenceType = "USER"
+	LedgerReferenceOrder      LedgerReferenceType = "ORDER"
+	LedgerReferenceTrade      LedgerReferenceType = "TRADE"  // Match order
+	LedgerReferenceWallet     LedgerReferenceType = "WALLET" // Opening balance of seeded wallet
+	LedgerReferenceDeposit    LedgerReferenceType = "DEPOSIT"
+	LedgerReferenceWithdrawal LedgerReferenceType = "WITHDRAWAL"
+	LedgerReferenceTransfer   LedgerReferenceType = "TRANSFER"
+	LedgerReferenceAdjustment LedgerReferenceType = "ADJUSTMENT" // Balance adjustment by admin
+)
+
+// Float residue tolerated when checking a journal is balanced
+const ledgerTolerance = 1e-6
+
+var (
+	ErrMissingLedgerJournal = errors.New("balance movement without ledger journal")
+	ErrUnbalancedJournal    = errors.New("ledger journal is not balanced")
+)
+
+// LedgerEntry is a single append-only posting, positive amount increase the account balance and negative amount decrease it
+type LedgerEntry struct {
+	ID            int64               `json:"id" gorm:"column:id;type:bigint;primaryKey;autoIncrement"`
+	JournalID     int64               `json:"journal_id" gorm:"column:journal_id;type:bigint"` // Entries of the same journal sum to zero per crypto
+	UserID        int                 `json:"user_id" gorm:"column:user_id;type:int"`
+	CryptoID      int                 `json:"crypto_id" gorm:"column:crypto_id;type:int"`
+	Account       LedgerAccount       `json:"account" gorm:"column:account;type:text"`
+	Amount        float64             `json:"amount" gorm:"column:amount;type:double"`
+	EntryType     LedgerEntryType     `json:"entry_type" gorm:"column:entry_type;type:text"`
+	ReferenceType LedgerReferenceType `json:"reference_type" gorm:"column:reference_type;type:text"`
+	ReferenceID   int                 `json:"reference_id" gorm:"column:reference_id;type:int"`
+	CreatedAt     time.Time           `json:"created_at" gorm:"column:created_at;type:datetime"`
+}
+
+func (LedgerEntry) TableName() string {
+	return "ledger_entries"
+}
+
+// LedgerJournal collects the entries of every balance movement inside one transaction.
+// Each wallet balance operation posts to the journal found in the context, the journal is
+// saved before the transaction is committed and rejected when it does not balance.
+type LedgerJournal struct {
+	EntryType     LedgerEntryType // Type of the next posted entries
+	ReferenceType LedgerReferenceType
+	ReferenceID   int // Set before saving, the referenced order or trade may not exist yet while posting
+	Entries       []LedgerEntry
+}
+
+func NewLedgerJournal(entryType LedgerEntryType, referenceType LedgerReferenceType) *LedgerJournal {
+	return &LedgerJournal{
+		EntryType:     entryType,
+		ReferenceType: referenceType,
+	}
+}
+
+// Post add an entry to the account of the user
+func (j *LedgerJournal) Post(userID, cryptoID int, account LedgerAccount, amount float64) {
+	j.Entries = append(j.Entries, LedgerEntry{
+		UserID:    userID,
+		CryptoID:  cryptoID,
+		Account:   account,
+		Amount:    amount,
+		EntryType: j.EntryType,
+	})
+}
+
+// PostBalanceChange add the entries of a change to the available and locked balance of the user
+func (j *LedgerJournal) PostBalanceChange(userID, cryptoID int, available, locked float64) {
+	if available != 0 {
+		j.Post(userID, cryptoID, LedgerAccountAvailable, available)
+	}
+	if locked != 0 {
+		j.Post(userID, cryptoID, LedgerAccountLocked, locked)
+	}
+}
+
+// PostExternal add the counterpart of money entering (positive) or leaving (negative) the exchange
+func (j *LedgerJournal) PostExternal(cryptoID int, amount float64) {
+	j.Post(0, cryptoID, LedgerAccountExternal, -amount)
+}
+
+// Balanced returns true when every crypto in the journal sums to zero
+func (j *LedgerJournal) Balanced() bool {
+	total := make(map[int]float64)
+	for _, entry := range j.Entries {
+		total[entry.CryptoID] += entry.Amount
+	}
+
+	for _, amount := range total {
+		if math.Abs(amount) > ledgerTolerance {
+			return false
+		}
+	}
+
+	return true
+}
+
+type ledgerJournalKey struct{}
+
+func SaveLedgerJournalToContext(parent context.Context, journal *LedgerJournal) context.Context {
+	return context.WithValue(parent, ledgerJournalKey{}, journal)
+}
+
+func GetLedgerJournalFromContext(ctx context.Context) *LedgerJournal {
+	if journal, ok := ctx.Value(ledgerJournalKey{}).(*LedgerJournal); ok {
+		return journal
+	}
+
+	return nil
+}
+
+// LedgerBalance compares the wallet balance with the balance projected from the ledger
+type LedgerBalance struct {
+	CryptoID        int     `json:"crypto_id"`
+	Available       float64 `json:"available"`
+	Locked          float64 `json:"locked"`
+	LedgerAvailable float64 `json:"ledger_available"`
+	LedgerLocked    float64 `json:"ledger_locked"`
+	Matched         bool    `json:"matched"`
+}
+
+// Verify set whether the wallet balance equals its ledger projection
+func (balance *LedgerBalance) Verify() {
+	balance.Matched = math.Abs(balance.Available-balance.LedgerAvailable) <= ledgerTolerance &&
+		math.Abs(balance.Locked-balance.LedgerLocked) <= ledgerTolerance
+}
+
+type LedgerUsecase interface {
+	GetLedger(ctx context.Context, ledgerReq dto.LedgerRequest) ([]LedgerEntry, int, error)
+	GetBalanceProjection(ctx context.Context) ([]LedgerBalance, error)
+}
+
+type LedgerRepository interface {
+	SaveJournal(ctx context.Context, journal *LedgerJournal) error // ErrUnbalancedJournal when the entries do not sum to zero
+	SaveJournals(ctx context.Context, journals []*LedgerJournal) error
+	GetUserLedger(ctx context.Context, userID int, ledgerReq dto.LedgerRequest) ([]LedgerEntry, int, error)
+	GetUserBalanceProjection(ctx context.Context, userID int) ([]LedgerBalance, error)
+}
@@ -1,20 +1,67 @@
 package model
 
-import "time"
+import (
+	"context"
+	"time"
+
+	"core-engine/internal/app/domains/dto"
+)
 
 type MatchOrder struct {
-	ID              int        `json:"id" gorm:"column:id;type:int;primaryKey;autoIncrement"`
-	PairID          int        `json:"pair_id" gorm:"column:pair_id;type:int"`
-	TakerOrderID    int        `json:"taker_order_id" gorm:"column:taker_order_id;type:int"`
-	MakerOrderID    int        `json:"maker_order_id" gorm:"column:maker_order_id;type:int"`
-	Quantity        float64    `json:"quantity" gorm:"column:quantity;type:double"`
-	Price           float64    `json:"price" gorm:"column:price;type:double"`
-	TransactionTime int64      `json:"transaction_time" gorm:"column:transaction_time;type:bigint"` // Transaction time
-	CreatedAt       time.Time  `json:"-" gorm:"column:created_at;type:datetime"`
-	UpdatedAt       time.Time  `json:"-" gorm:"column:updated_at;type:datetime"`
-	DeletedAt       *time.Time `json:"-" gorm:"column:deleted_at;type:datetime"`
+	ID               int        `json:"id" gorm:"column:id;type:int;primaryKey;autoIncrement"`
+	PairID           int        `json:"pair_id" gorm:"column:pair_id;type:int"`
+	TakerOrderID     int        `json:"taker_order_id" gorm:"column:taker_order_id;type:int"`
+	MakerOrderID     int        `json:"maker_order_id" gorm:"column:maker_order_id;type:int"`
+	Quantity         float64    `json:"quantity" gorm:"column:quantity;type:double"`
+	Price            float64    `json:"price" gorm:"column:price;type:double"`
+	TakerFee         float64    `json:"taker_fee" gorm:"column:taker_fee;type:double"`
+	TakerFeeCryptoID int        `json:"taker_fee_crypto_id" gorm:"column:taker_fee_crypto_id;type:int"`
+	MakerFee         float64    `json:"maker_fee" gorm:"column:maker_fee;type:double"` // Negative fee is a rebate
+	MakerFeeCryptoID int        `json:"maker_fee_crypto_id" gorm:"column:maker_fee_crypto_id;type:int"`
+	TradeSequence    int64      `json:"trade_sequence" gorm:"column:trade_sequence;type:bigint"`     // Unique per pair, assigned by the matching engine
+	TransactionTime  int64      `json:"transaction_time" gorm:"column:transaction_time;type:bigint"` // Transaction time
+	CreatedAt        time.Time  `json:"-" gorm:"column:created_at;type:datetime"`
+	UpdatedAt        time.Time  `json:"-" gorm:"column:updated_at;type:datetime"`
+	DeletedAt        *time.Time `json:"-" gorm:"column:deleted_at;type:datetime"`
 }
 
 func (MatchOrder) TableName() string {
 	return "match_orders"
 }
+
+type TradeRole string
+
+const (
+	TradeRoleTaker TradeRole = "TAKER"
+	TradeRoleMaker TradeRole = "MAKER"
+)
+
+// UserTrade is one side of a match order, seen by the owner of the order
+type UserTrade struct {
+	MatchOrderID    int       `json:"match_order_id" gorm:"column:match_order_id"`
+	OrderID         int       `json:"order_id" gorm:"column:order_id"`
+	PairID          int       `json:"pair_id" gorm:"column:pair_id"`
+	PairCode        string    `json:"pair_code" gorm:"column:pair_code"`
+	Side            Side      `json:"side" gorm:"column:side"`
+	Role            TradeRole `json:"role" gorm:"column:role"`
+	Price           float64   `json:"price" gorm:"column:price"`
+	Quantity        float64   `json:"quantity" gorm:"column:quantity"`
+	Fee             float64   `json:"fee" gorm:"column:fee"` // Negative fee is a maker rebate
+	FeeCryptoID     int       `json:"fee_crypto_id" gorm:"column:fee_crypto_id"`
+	TransactionTime int64     `json:"transaction_time" gorm:"column:transaction_time"`
+}
+
+// TradeFilter is the validated trade list request, newest trade first
+type TradeFilter struct {
+	PairID      int
+	StartTime   int64
+	EndTime     int64
+	BeforeMatch int // Cursor, only trade before this match order and order ID is returned
+	BeforeOrder int
+	Limit       int
+}
+
+type TradeUsecase interface {
+	// GetTrades returns a page of trades of the logged in user and the cursor of the next page, empty on the last page
+	GetTrades(ctx context.Context, listReq dto.TradeListRequest) ([]UserTrade, string, error)
+}
@@ -27,46 +27,137 @@ const (
 )
 
 const (
-	OrderStatusComplete Status = "COMPLETE"
-	OrderStatusFailed   Status = "FAILED"
-	OrderStatusProgress Status = "PROGRESS"
-	OrderStatusPartial  Status = "PARTIAL"
+	OrderStatusComplete  Status = "COMPLETE"
+	OrderStatusFailed    Status = "FAILED"
+	OrderStatusProgress  Status = "PROGRESS"
+	OrderStatusPartial   Status = "PARTIAL"
+	OrderStatusCancelled Status = "CANCELLED"
 )
 
 var (
 	ErrInsufficientBalance = errors.New("Insufficient balance")
+	ErrWalletNotFound      = errors.New("wallet not found")
+	ErrInvalidQuoteOrder   = errors.New("quote quantity is only allowed for MARKET BUY order without quantity")
+	ErrTradeAlreadySettled = errors.New("trade already settled")
+	ErrInvalidOrderFilter  = errors.New("invalid order filter")
+	ErrInvalidOrderAmount  = errors.New("price and quantity must be greater than zero")
 )
 
+// ValidateOrderRequest rejects an order the matching engine can not fill before any balance is locked,
+// every order needs a positive price and quantity except a quote market buy which only needs its quote quantity
+func ValidateOrderRequest(orderReq dto.OrderRequest) error {
+	if orderReq.QuoteQuantity > 0 {
+		if Side(orderReq.Side) != OrderSideBuy || Type(orderReq.Type) != OrderTypeMarket || orderReq.Quantity != 0 {
+			return ErrInvalidQuoteOrder
+		}
+		return nil
+	}
+
+	if orderReq.Price <= 0 || orderReq.Quantity <= 0 {
+		return ErrInvalidOrderAmount
+	}
+
+	return nil
+}
+
 type Order struct {
-	ID              int        `json:"id" gorm:"column:id;type:int;primaryKey;autoIncrement"`
-	UserID          int        `json:"user_id" gorm:"column:user_id;type:int"`
-	PairID          int        `json:"pair_id" gorm:"column:pair_id;type:int"`
-	Quantity        float64    `json:"quantity" gorm:"column:quantity;type:double"`
-	FilledQuantity  float64    `json:"filled_quantity" gorm:"column:filled_quantity;type:double"`
-	Price           float64    `json:"price" gorm:"column:price;type:double"`
-	Type            Type       `json:"type" gorm:"column:type;type:text"`
-	Side            Side       `json:"side" gorm:"column:side;type:text"`
-	Status          Status     `json:"status" gorm:"column:status;type:text"`
-	TransactionTime int64      `json:"transaction_time" gorm:"column:transaction_time;type:bigint"` // Transaction time
-	CreatedAt       time.Time  `json:"-" gorm:"column:created_at;type:datetime"`
-	UpdatedAt       time.Time  `json:"-" gorm:"column:updated_at;type:datetime"`
-	DeletedAt       *time.Time `json:"-" gorm:"column:deleted_at;type:datetime"`
+	ID                  int        `json:"id" gorm:"column:id;type:int;primaryKey;autoIncrement"`
+	UserID              int        `json:"user_id" gorm:"column:user_id;type:int"`
+	PairID              int        `json:"pair_id" gorm:"column:pair_id;type:int"`
+	Quantity            float64    `json:"quantity" gorm:"column:quantity;type:double"`
+	FilledQuantity      float64    `json:"filled_quantity" gorm:"column:filled_quantity;type:double"`
+	Price               float64    `json:"price" gorm:"column:price;type:double"`
+	QuoteQuantity       float64    `json:"quote_quantity" gorm:"column:quote_quantity;type:double"`               // Reserved secondary crypto of a quote market buy
+	FilledQuoteQuantity float64    `json:"filled_quote_quantity" gorm:"column:filled_quote_quantity;type:double"` // Secondary crypto already traded
+	Type                Type       `json:"type" gorm:"column:type;type:text"`
+	Side                Side       `json:"side" gorm:"column:side;type:text"`
+	Status              Status     `json:"status" gorm:"column:status;type:text"`
+	TransactionTime     int64      `json:"transaction_time" gorm:"column:transaction_time;type:bigint"` // Transaction time
+	CreatedAt           time.Time  `json:"-" gorm:"column:created_at;type:datetime"`
+	UpdatedAt           time.Time  `json:"-" gorm:"column:updated_at;type:datetime"`
+	DeletedAt           *time.Time `json:"-" gorm:"column:deleted_at;type:datetime"`
+}
+
+// Finished returns true when the order no longer holds any reserved balance
+func (order Order) Finished() bool {
+	return order.Status == OrderStatusComplete || order.Status == OrderStatusFailed || order.Status == OrderStatusCancelled
+}
+
+// UnlockAmount returns the reserved balance released by an order update. A quote order releases the budget
+// it did not spend, other orders release the lock of the quantity removed from the book or rejected by it.
+func (order Order) UnlockAmount(updateReq dto.OrderUpdateRequest) float64 {
+	switch {
+	case order.QuoteQuantity > 0:
+		return order.QuoteQuantity - updateReq.FilledQuoteQuantity
+	case order.Side == OrderSideBuy:
+		return updateReq.RemainingQuantity * order.Price
+	}
+
+	return updateReq.RemainingQuantity
 }
 
 func (Order) TableName() string {
 	return "orders"
 }
 
+// Fill add a trade into the order and update its status
+func (order *Order) Fill(quantity, price float64) {
+	order.FilledQuantity += quantity
+	order.FilledQuoteQuantity += quantity * price
+
+	switch {
+	case order.QuoteQuantity > 0:
+		// Quote order is finished by the order update from matching engine, which may come first
+		if order.Status == OrderStatusProgress {
+			order.Status = OrderStatusPartial
+		}
+	case order.FilledQuantity == order.Quantity:
+		order.Status = OrderStatusComplete
+	default:
+		order.Status = OrderStatusPartial
+	}
+}
+
 type OrderUsecase interface {
 	ProcessOrder(ctx context.Context, orderReq dto.OrderRequest) (Order, error)
 	MatchOrder(ctx context.Context, tradeReq dto.TradeRequest) error
+	MatchOrders(ctx context.Context, tradeReqs dto.BulkTradeRequest) error
+
+	// Order history of the logged in user
+	GetOrders(ctx context.Context, listReq dto.OrderListRequest) ([]Order, int, error)
+	GetOpenOrders(ctx context.Context, listReq dto.OrderListRequest) ([]Order, int, error)
+	GetOrder(ctx context.Context, id int) (Order, error)
+	CompleteOrder(ctx context.Context, updateReq dto.OrderUpdateRequest) error
+}
+
+// Open order is still waiting in the order book
+var OrderOpenStatuses = []Status{OrderStatusProgress, OrderStatusPartial}
+
+// OrderFilter is the validated order list request
+type OrderFilter struct {
+	PairID        int
+	Statuses      []Status
+	Side          Side
+	StartTime     int64
+	EndTime       int64
+	SortBy        string
+	SortDirection string
+	Page          int
+	Limit         int
 }
 
 type OrderRepository interface {
 	// User Order
 	SaveOrder(ctx context.Context, order Order) (Order, error)
 	GetOrder(ctx context.Context, id int) (Order, error)
+	GetOrderForUpdate(ctx context.Context, id int) (Order, error)
+	GetOrdersForUpdate(ctx context.Context, ids []int) ([]Order, error) // Rows are locked in ID order
+	GetUserOrder(ctx context.Context, userID, id int) (Order, error)
+	GetUserOrders(ctx context.Context, userID int, filter OrderFilter) ([]Order, int, error)
 
 	// Matching Order
-	SaveMatchOrder(ctx context.Context, matchOrder MatchOrder) error
+	SaveMatchOrder(ctx context.Context, matchOrder MatchOrder) (MatchOrder, error) // ErrTradeAlreadySettled when the trade sequence of the pair already exist
+	IsTradeSettled(ctx context.Context, pairID int, tradeSequence int64) (bool, error)
+	GetSettledTradeSequences(ctx context.Context, pairID int, tradeSequences []int64) (map[int64]bool, error)
+	GetUserTrades(ctx context.Context, userID int, filter TradeFilter) ([]UserTrade, error)
 }
@@ -0,0 +1,60 @@
+package model
+
+import (
+	"errors"
+	"testing"
+
+	"core-engine/internal/app/domains/dto"
+)
+
+func TestValidateOrderRequest(t *testing.T) {
+	tests := []struct {
+		name  string
+		order dto.OrderRequest
+		want  error
+	}{
+		{"limit", dto.OrderRequest{Side: "BUY", Type: "LIMIT", Price: 10, Quantity: 1}, nil},
+		{"market with price", dto.OrderRequest{Side: "SELL", Type: "MARKET", Price: 10, Quantity: 1}, nil},
+		{"quote market buy", dto.OrderRequest{Side: "BUY", Type: "MARKET", QuoteQuantity: 100}, nil},
+		{"market without price", dto.OrderRequest{Side: "BUY", Type: "MARKET", Quantity: 1}, ErrInvalidOrderAmount},
+		{"zero quantity", dto.OrderRequest{Side: "SELL", Type: "LIMIT", Price: 10}, ErrInvalidOrderAmount},
+		{"negative price", dto.OrderRequest{Side: "SELL", Type: "LIMIT", Price: -1, Quantity: 1}, ErrInvalidOrderAmount},
+		{"quote sell", dto.OrderRequest{Side: "SELL", Type: "MARKET", QuoteQuantity: 100}, ErrInvalidQuoteOrder},
+		{"quote with quantity", dto.OrderRequest{Side: "BUY", Type: "MARKET", Quantity: 1, QuoteQuantity: 100}, ErrInvalidQuoteOrder},
+	}
+
+	for _, test := range tests {
+		if err := ValidateOrderRequest(test.order); !errors.Is(err, test.want) {
+			t.Errorf("%v: got %v, want %v", test.name, err, test.want)
+		}
+	}
+}
+
+func TestOrderUnlockAmount(t *testing.T) {
+	tests := []struct {
+		name   string
+		order  Order
+		update dto.OrderUpdateRequest
+		want   float64
+	}{
+		{"quote buy refund", Order{QuoteQuantity: 1000, Side: OrderSideBuy}, dto.OrderUpdateRequest{FilledQuoteQuantity: 600}, 400},
+		{"cancelled buy", Order{Price: 100, Quantity: 5, Side: OrderSideBuy}, dto.OrderUpdateRequest{Status: "CANCELLED", RemainingQuantity: 2}, 200},
+		{"cancelled sell", Order{Price: 100, Quantity: 5, Side: OrderSideSell}, dto.OrderUpdateRequest{Status: "CANCELLED", RemainingQuantity: 2}, 2},
+		{"rejected buy", Order{Price: 100, Quantity: 5, Side: OrderSideBuy}, dto.OrderUpdateRequest{Status: "FAILED", RemainingQuantity: 5}, 500},
+	}
+
+	for _, test := range tests {
+		if got := test.order.UnlockAmount(test.update); got != test.want {
+			t.Errorf("%v: got %v, want %v", test.name, got, test.want)
+		}
+	}
+
+	for _, status := range []Status{OrderStatusComplete, OrderStatusFailed, OrderStatusCancelled} {
+		if !(Order{Status: status}).Finished() {
+			t.Errorf("%v order must be finished", status)
+		}
+	}
+	if (Order{Status: OrderStatusPartial}).Finished() {
+		t.Error("partial order must not be finished")
+	}
+}
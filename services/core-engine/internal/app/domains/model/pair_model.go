@@ -1,12 +1,51 @@
 package model
 
-import "time"
+import (
+	"context"
+	"errors"
+	"math"
+	"regexp"
+	"time"
+
+	"core-engine/internal/app/domains/dto"
+)
+
+const (
+	MatchingAlgorithmFIFO           = "FIFO"
+	MatchingAlgorithmProRata        = "PRO_RATA"
+	MatchingAlgorithmFIFOTopProRata = "FIFO_TOP_PRO_RATA"
+
+	maxCryptoPrecision = 18
+	stepTolerance      = 1e-9 // Float residue when checking a value is a multiple of a step
+)
+
+var (
+	ErrPairInactive             = errors.New("pair is not active")
+	ErrPairExists               = errors.New("pair code already exists")
+	ErrCryptoExists             = errors.New("crypto symbol already exists")
+	ErrInvalidCode              = errors.New("code must be upper case letters and digits")
+	ErrInvalidPrecision         = errors.New("precision must be between 0 and 18")
+	ErrInvalidTradingRule       = errors.New("trading rule can not be negative")
+	ErrInvalidMatchingAlgorithm = errors.New("matching algorithm must be FIFO, PRO_RATA or FIFO_TOP_PRO_RATA")
+	ErrSamePairCrypto           = errors.New("base and quote crypto must be different")
+	ErrPriceTickSize            = errors.New("price is not a multiple of the pair tick size")
+	ErrQuantityLotSize          = errors.New("quantity is not a multiple of the pair lot size")
+	ErrQuantityTooSmall         = errors.New("quantity is below the pair minimum quantity")
+
+	codePattern = regexp.MustCompile(`^[A-Z0-9]{2,32}$`)
+)
 
 type Pair struct {
 	ID                int        `json:"id" gorm:"column:id;type:int;primaryKey;autoIncrement"`
-	Code              string     `json:"code" gorm:"column:code;type:varchar;size:255"`
+	Code              string     `json:"code" gorm:"column:code;type:varchar;size:255"` // Also the Kafka topic of the pair orders
 	PrimaryCryptoID   int        `json:"primary_crypto_id" gorm:"column:primary_crypto_id;type:int"`
 	SecondaryCryptoID int        `json:"secondary_crypto_id" gorm:"column:secondary_crypto_id;type:int"`
+	TickSize          float64    `json:"tick_size" gorm:"column:tick_size;type:double"`       // Price increment, 0 accepts any price
+	LotSize           float64    `json:"lot_size" gorm:"column:lot_size;type:double"`         // Quantity increment, 0 accepts any quantity
+	MinQuantity       float64    `json:"min_quantity" gorm:"column:min_quantity;type:double"` // Smallest order quantity
+	MatchingAlgorithm string     `json:"matching_algorithm" gorm:"column:matching_algorithm;type:varchar;size:32;default:FIFO"`
+	MinAllocation     float64    `json:"min_allocation" gorm:"column:min_allocation;type:double"` // Pro-rata share below this is given to the oldest order
+	Status            bool       `json:"status" gorm:"column:status;type:bool"`
 	CreatedAt         time.Time  `json:"created_at" gorm:"column:created_at;type:datetime"`
 	UpdatedAt         time.Time  `json:"updated_at" gorm:"column:updated_at;type:datetime"`
 	DeletedAt         *time.Time `json:"deleted_at" gorm:"column:deleted_at;type:datetime"`
@@ -15,3 +54,114 @@ type Pair struct {
 func (Pair) TableName() string {
 	return "pairs"
 }
+
+// Validate checks the pair definition before it is saved
+func (p Pair) Validate() error {
+	if !codePattern.MatchString(p.Code) {
+		return ErrInvalidCode
+	}
+
+	if p.PrimaryCryptoID == p.SecondaryCryptoID {
+		return ErrSamePairCrypto
+	}
+
+	if p.TickSize < 0 || p.LotSize < 0 || p.MinQuantity < 0 || p.MinAllocation < 0 {
+		return ErrInvalidTradingRule
+	}
+
+	switch p.MatchingAlgorithm {
+	case MatchingAlgorithmFIFO, MatchingAlgorithmProRata, MatchingAlgorithmFIFOTopProRata:
+	default:
+		return ErrInvalidMatchingAlgorithm
+	}
+
+	return nil
+}
+
+// ValidateOrder checks an order against the trading rules, zero price or quantity is left to the order type
+// validation, a market order has no price and a quote order has no quantity
+func (p Pair) ValidateOrder(price, quantity float64) error {
+	if price > 0 && !isMultipleOf(price, p.TickSize) {
+		return ErrPriceTickSize
+	}
+
+	if quantity > 0 {
+		if quantity < p.MinQuantity-stepTolerance {
+			return ErrQuantityTooSmall
+		}
+
+		if !isMultipleOf(quantity, p.LotSize) {
+			return ErrQuantityLotSize
+		}
+	}
+
+	return nil
+}
+
+// isMultipleOf returns true when value is a whole number of step, zero step accepts every value
+func isMultipleOf(value, step float64) bool {
+	if step <= 0 {
+		return true
+	}
+
+	steps := value / step
+	return math.Abs(steps-math.Round(steps)) <= stepTolerance*math.Max(1, steps)
+}
+
+// PairTicker is the last trade price of a pair and the price 24 hours ago
+type PairTicker struct {
+	PairID            int     `json:"pair_id" gorm:"column:pair_id"`
+	PairCode          string  `json:"pair_code" gorm:"column:pair_code"`
+	PrimaryCryptoID   int     `json:"primary_crypto_id" gorm:"column:primary_crypto_id"`
+	SecondaryCryptoID int     `json:"secondary_crypto_id" gorm:"column:secondary_crypto_id"`
+	LastPrice         float64 `json:"last_price" gorm:"column:last_price"`
+	OpenPrice         float64 `json:"open_price" gorm:"column:open_price"` // Last price before the 24 hours window, or the first price in it
+}
+
+type Crypto struct {
+	ID                 int        `json:"id" gorm:"column:id;type:int;primaryKey;autoIncrement"`
+	Symbol             string     `json:"symbol" gorm:"column:symbol;type:varchar;size:128"`
+	Precision          int        `json:"precision" gorm:"column:precision;type:int"` // Decimal places shown and accepted for the amount
+	Status             bool       `json:"status" gorm:"column:status;type:bool"`
+	TransferDailyLimit float64    `json:"transfer_daily_limit" gorm:"column:transfer_daily_limit;type:double"` // Zero disables transfer
+	CreatedAt          time.Time  `json:"created_at" gorm:"column:created_at;type:datetime"`
+	UpdatedAt          time.Time  `json:"updated_at" gorm:"column:updated_at;type:datetime"`
+	DeletedAt          *time.Time `json:"deleted_at" gorm:"column:deleted_at;type:datetime"`
+}
+
+func (Crypto) TableName() string {
+	return "crypto"
+}
+
+// Validate checks the crypto definition before it is saved
+func (c Crypto) Validate() error {
+	if !codePattern.MatchString(c.Symbol) {
+		return ErrInvalidCode
+	}
+
+	if c.Precision < 0 || c.Precision > maxCryptoPrecision {
+		return ErrInvalidPrecision
+	}
+
+	if c.TransferDailyLimit < 0 {
+		return ErrInvalidTradingRule
+	}
+
+	return nil
+}
+
+// MarketUsecase manages the listed crypto and trading pairs, every call is made by an admin
+type MarketUsecase interface {
+	CreateCrypto(ctx context.Context, cryptoReq dto.CryptoRequest) (Crypto, error)
+	UpdateCrypto(ctx context.Context, id int, cryptoReq dto.CryptoUpdateRequest) (Crypto, error)
+	CreatePair(ctx context.Context, pairReq dto.PairRequest) (Pair, error) // Create the order topic and register the pair to the matching engines
+	UpdatePair(ctx context.Context, id int, pairReq dto.PairUpdateRequest) (Pair, error)
+}
+
+type MarketRepository interface {
+	SaveCrypto(ctx context.Context, crypto Crypto) (Crypto, error)
+	GetCryptoForUpdate(ctx context.Context, id int) (Crypto, error) // Any status
+	GetCryptoBySymbol(ctx context.Context, symbol string) (Crypto, error)
+	SavePair(ctx context.Context, pair Pair) (Pair, error)
+	GetPairForUpdate(ctx context.Context, id int) (Pair, error)
+}
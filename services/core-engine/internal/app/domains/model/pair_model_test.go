@@ -0,0 +1,60 @@
+package model
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPairValidateOrder(t *testing.T) {
+	pair := Pair{TickSize: 0.01, LotSize: 0.1, MinQuantity: 0.5}
+
+	tests := []struct {
+		name            string
+		price, quantity float64
+		want            error
+	}{
+		{"valid", 1.23, 0.7, nil},
+		{"float residue", 0.3, 0.3 * 3, nil},
+		{"market order", 0, 1, nil},
+		{"quote order", 0, 0, nil},
+		{"off tick", 1.234, 1, ErrPriceTickSize},
+		{"off lot", 1, 0.75, ErrQuantityLotSize},
+		{"below minimum", 1, 0.4, ErrQuantityTooSmall},
+	}
+
+	for _, test := range tests {
+		if err := pair.ValidateOrder(test.price, test.quantity); !errors.Is(err, test.want) {
+			t.Errorf("%v: got %v, want %v", test.name, err, test.want)
+		}
+	}
+
+	if err := (Pair{}).ValidateOrder(1.23456, 0.000001); err != nil {
+		t.Errorf("pair without rules: got %v, want nil", err)
+	}
+}
+
+func TestPairValidate(t *testing.T) {
+	valid := Pair{Code: "DOGEIDRT", PrimaryCryptoID: 6, SecondaryCryptoID: 1, MatchingAlgorithm: MatchingAlgorithmFIFO}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("valid pair: got %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(p *Pair)
+		want   error
+	}{
+		{"lower case code", func(p *Pair) { p.Code = "dogeidrt" }, ErrInvalidCode},
+		{"same crypto", func(p *Pair) { p.SecondaryCryptoID = p.PrimaryCryptoID }, ErrSamePairCrypto},
+		{"negative rule", func(p *Pair) { p.LotSize = -1 }, ErrInvalidTradingRule},
+		{"unknown algorithm", func(p *Pair) { p.MatchingAlgorithm = "LIFO" }, ErrInvalidMatchingAlgorithm},
+	}
+
+	for _, test := range tests {
+		pair := valid
+		test.modify(&pair)
+		if err := pair.Validate(); !errors.Is(err, test.want) {
+			t.Errorf("%v: got %v, want %v", test.name, err, test.want)
+		}
+	}
+}
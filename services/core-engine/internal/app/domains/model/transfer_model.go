@@ -0,0 +1,50 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"core-engine/internal/app/domains/dto"
+)
+
+type TransferDirection string
+
+const (
+	TransferDirectionIn  TransferDirection = "IN"
+	TransferDirectionOut TransferDirection = "OUT"
+)
+
+var (
+	ErrSelfTransfer        = errors.New("can not transfer to yourself")
+	ErrTransferDisabled    = errors.New("transfer is disabled for this crypto")
+	ErrTransferOverLimit   = errors.New("amount is over the daily transfer limit")
+	ErrReceiverUnavailable = errors.New("receiver is not found or blocked")
+)
+
+// Transfer moves available balance between two users of the exchange
+type Transfer struct {
+	ID         int               `json:"id" gorm:"column:id;type:int;primaryKey;autoIncrement"`
+	SenderID   int               `json:"sender_id" gorm:"column:sender_id;type:int"`
+	ReceiverID int               `json:"receiver_id" gorm:"column:receiver_id;type:int"`
+	CryptoID   int               `json:"crypto_id" gorm:"column:crypto_id;type:int"`
+	Amount     float64           `json:"amount" gorm:"column:amount;type:double"`
+	Note       string            `json:"note" gorm:"column:note;type:varchar;size:256"`
+	Direction  TransferDirection `json:"direction" gorm:"-"` // Seen from the logged in user
+	CreatedAt  time.Time         `json:"created_at" gorm:"column:created_at;type:datetime"`
+}
+
+func (Transfer) TableName() string {
+	return "transfers"
+}
+
+type TransferUsecase interface {
+	Transfer(ctx context.Context, transferReq dto.TransferRequest) (Transfer, error)
+	GetTransfers(ctx context.Context, listReq dto.TransferListRequest) ([]Transfer, int, error) // Sent and received, newest first
+}
+
+type TransferRepository interface {
+	SaveTransfer(ctx context.Context, transfer Transfer) (Transfer, error)
+	GetTransferredAmount(ctx context.Context, senderID, cryptoID int, since time.Time) (float64, error) // Sent since the given time
+	GetUserTransfers(ctx context.Context, userID int, listReq dto.TransferListRequest) ([]Transfer, int, error)
+}
@@ -3,35 +3,133 @@ package model
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"core-engine/internal/app/domains/dto"
 )
 
+const (
+	UserRoleUser    = "USER"
+	UserRoleSupport = "SUPPORT" // Read only access to the admin API
+	UserRoleAdmin   = "ADMIN"
+)
+
+var (
+	ErrRefreshTokenExpired  = errors.New("refresh token expired")
+	ErrRefreshTokenReused   = errors.New("refresh token reused, every token of the login is revoked")
+	ErrReasonRequired       = errors.New("reason is required")
+	ErrTwoFactorEnabled     = errors.New("two factor authentication is already enabled")
+	ErrTwoFactorNotEnrolled = errors.New("two factor authentication is not enrolled")
+)
+
 type User struct {
-	ID          int        `json:"id" gorm:"column:id;type:int;primaryKey;autoIncrement"`
-	FullName    string     `json:"full_name" gorm:"column:full_name;type:varchar;size:255"`
-	Email       string     `json:"email" gorm:"column:email;type:varchar;size:255"`
-	PhoneNumber string     `json:"phone_number" gorm:"column:phone_number;type:varchar;size:255"`
-	Password    string     `json:"password" gorm:"column:password;type:varchar;size:255"`
-	Status      bool       `json:"status" gorm:"column:status;type:tinyint"`
-	CreatedAt   time.Time  `json:"created_at" gorm:"column:created_at;type:datetime"`
-	UpdatedAt   time.Time  `json:"updated_at" gorm:"column:updated_at;type:datetime"`
-	DeletedAt   *time.Time `json:"deleted_at" gorm:"column:deleted_at;type:datetime"`
+	ID           int        `json:"id" gorm:"column:id;type:int;primaryKey;autoIncrement"`
+	FullName     string     `json:"full_name" gorm:"column:full_name;type:varchar;size:255"`
+	Email        string     `json:"email" gorm:"column:email;type:varchar;size:255"`
+	PhoneNumber  string     `json:"phone_number" gorm:"column:phone_number;type:varchar;size:255"`
+	Password     string     `json:"password" gorm:"column:password;type:varchar;size:255"`
+	Status       bool       `json:"status" gorm:"column:status;type:tinyint"`
+	FeeTier      string     `json:"fee_tier" gorm:"column:fee_tier;type:varchar;size:32;default:DEFAULT"`
+	Role         string     `json:"role" gorm:"column:role;type:varchar;size:32;default:USER"`
+	TotpSecret   string     `json:"-" gorm:"column:totp_secret;type:varchar;size:255"` // Encrypted, set on enrollment
+	TotpEnabled  bool       `json:"totp_enabled" gorm:"column:totp_enabled;type:bool"`
+	TotpLastStep int64      `json:"-" gorm:"column:totp_last_step;type:bigint"` // Step of the last accepted code, a code is never accepted twice
+	CreatedAt    time.Time  `json:"created_at" gorm:"column:created_at;type:datetime"`
+	UpdatedAt    time.Time  `json:"updated_at" gorm:"column:updated_at;type:datetime"`
+	DeletedAt    *time.Time `json:"deleted_at" gorm:"column:deleted_at;type:datetime"`
 }
 
 func (User) TableName() string {
 	return "users"
 }
 
+// RefreshToken is stored as a hash, the raw token is only returned to the user
+//
 //counterfeiter:generate -o ./mock . Usecase
+type RefreshToken struct {
+	ID            int        `json:"id" gorm:"column:id;type:int;primaryKey;autoIncrement"`
+	UserID        int        `json:"user_id" gorm:"column:user_id;type:int"`
+	FamilyID      string     `json:"family_id" gorm:"column:family_id;type:varchar;size:64"` // Same for every token rotated from one login
+	TokenHash     string     `json:"-" gorm:"column:token_hash;type:varchar;size:64"`
+	AccessTokenID string     `json:"access_token_id" gorm:"column:access_token_id;type:varchar;size:64"`
+	ExpiresAt     time.Time  `json:"expires_at" gorm:"column:expires_at;type:datetime"`
+	RevokedAt     *time.Time `json:"revoked_at" gorm:"column:revoked_at;type:datetime"`
+	CreatedAt     time.Time  `json:"created_at" gorm:"column:created_at;type:datetime"`
+}
+
+func (RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
+
+// RecoveryCode replaces a TOTP code once when the authenticator is lost, stored as a hash
+type RecoveryCode struct {
+	ID        int        `json:"id" gorm:"column:id;type:int;primaryKey;autoIncrement"`
+	UserID    int        `json:"user_id" gorm:"column:user_id;type:int"`
+	CodeHash  string     `json:"-" gorm:"column:code_hash;type:varchar;size:64"`
+	UsedAt    *time.Time `json:"used_at" gorm:"column:used_at;type:datetime"`
+	CreatedAt time.Time  `json:"created_at" gorm:"column:created_at;type:datetime"`
+}
+
+func (RecoveryCode) TableName() string {
+	return "recovery_codes"
+}
+
 type UserUsecase interface {
 	Login(ctx context.Context, loginReq dto.LoginRequest) (dto.LoginResponse, error)
 	Register(ctx context.Context, registerReq dto.RegisterRequest) (User, error)
+	Refresh(ctx context.Context, refreshReq dto.RefreshRequest) (dto.LoginResponse, error) // Rotate the refresh token
+	Logout(ctx context.Context) error                                                      // Revoke the access token of the context and its refresh token
+	ChangePassword(ctx context.Context, passwordReq dto.ChangePasswordRequest) error       // End every session of the user
+}
+
+// TwoFactorUsecase manages the TOTP of the logged in user, Verify is used by every sensitive action
+type TwoFactorUsecase interface {
+	Enroll(ctx context.Context) (dto.TwoFactorEnrollResponse, error)              // New secret, only active once enabled
+	Enable(ctx context.Context, code string) (dto.TwoFactorEnableResponse, error) // Returns the recovery codes once
+	Disable(ctx context.Context, code string) error
+	Verify(ctx context.Context, userID int, code string) error // Nil when the user has not enabled 2FA, a recovery code is also accepted
+}
+
+// FailureCounter counts the failed attempts of an ID within a window
+type FailureCounter interface {
+	Failures(ctx context.Context, id string) (int64, error)
+	AddFailure(ctx context.Context, id string, window time.Duration) (int64, error) // Returns the failures including this one
+	Reset(ctx context.Context, id string) error
+}
+
+type AdminUsecase interface {
+	GetUser(ctx context.Context, id int) (UserDetail, error)
+	FindUser(ctx context.Context, email string) (UserDetail, error)
+	FreezeUser(ctx context.Context, id int, reason string) (User, error) // Block login, trading and transfer, and end every session
+	UnfreezeUser(ctx context.Context, id int, reason string) (User, error)
+	AdjustBalance(ctx context.Context, userID int, adjustmentReq dto.AdjustmentRequest) (BalanceAdjustment, error)
+}
+
+// UserDetail is the user seen by support and admin, without the password hash
+type UserDetail struct {
+	User
+	Balances []Balance `json:"balances"`
 }
 
 //counterfeiter:generate -o ./mock . Repository
 type UserRepository interface {
 	FindUserByEmail(ctx context.Context, email string) (User, error)
+	FindUserByID(ctx context.Context, id int) (User, error)
+	UpdateUserStatus(ctx context.Context, id int, status bool) error
 	RegisterNewUser(ctx context.Context, user User) (User, error)
+
+	// Refresh token
+	SaveRefreshToken(ctx context.Context, token RefreshToken) (RefreshToken, error)
+	GetRefreshTokenForUpdate(ctx context.Context, tokenHash string) (RefreshToken, error)
+	GetRefreshTokenByAccessTokenID(ctx context.Context, accessTokenID string) (RefreshToken, error)
+	RevokeRefreshTokenFamily(ctx context.Context, familyID string) ([]RefreshToken, error) // Returns the tokens revoked by this call
+	RevokeUserRefreshTokens(ctx context.Context, userID int) ([]RefreshToken, error)
+
+	// Password and two factor
+	GetUserForUpdate(ctx context.Context, id int) (User, error)
+	UpdatePassword(ctx context.Context, id int, passwordHash string) error
+	UpdateTotp(ctx context.Context, user User) error // Secret, enabled flag and last step
+	ReplaceRecoveryCodes(ctx context.Context, userID int, codeHashes []string) error
+	UseRecoveryCode(ctx context.Context, userID int, codeHash string) (bool, error) // False when the code is unknown or already used
 }
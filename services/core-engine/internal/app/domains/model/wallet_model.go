@@ -11,7 +11,8 @@ type Wallet struct {
 	ID        int        `json:"id" gorm:"column:id;type:int;primaryKey;autoIncrement"`
 	UserID    int        `json:"user_id" gorm:"column:user_id;type:int"`
 	CryptoID  int        `json:"crypto_id" gorm:"column:crypto_id;type:int"`
-	Quantity  float64    `json:"quantity" gorm:"column:quantity;type:text"`
+	Available float64    `json:"available" gorm:"column:available;type:double"` // Free to use for new order or withdrawal
+	Locked    float64    `json:"locked" gorm:"column:locked;type:double"`       // Reserved by open orders
 	CreatedAt time.Time  `json:"created_at" gorm:"column:created_at;type:datetime"`
 	UpdatedAt time.Time  `json:"updated_at" gorm:"column:updated_at;type:datetime"`
 	DeletedAt *time.Time `json:"deleted_at" gorm:"column:deleted_at;type:datetime"`
@@ -24,23 +25,94 @@ func (Wallet) TableName() string {
 func (userWallet Wallet) IsEnoughBalance(orderReq dto.OrderRequest) bool {
 	switch Side(orderReq.Side) {
 	case OrderSideSell:
-		return userWallet.Quantity >= orderReq.Quantity
+		return userWallet.Available >= orderReq.Quantity
 
 	case OrderSideBuy:
+		if orderReq.QuoteQuantity > 0 {
+			return userWallet.Available >= orderReq.QuoteQuantity
+		}
+
 		totalBuyAmount := orderReq.Price * orderReq.Quantity
-		return userWallet.Quantity >= totalBuyAmount
+		return userWallet.Available >= totalBuyAmount
 	}
 
 	return false
 }
 
+// Balance is the available and locked amount of one crypto, zero when the user has no wallet yet
+type Balance struct {
+	CryptoID  int     `json:"crypto_id" gorm:"column:crypto_id"`
+	Symbol    string  `json:"symbol" gorm:"column:symbol"`
+	Available float64 `json:"available" gorm:"column:available"`
+	Locked    float64 `json:"locked" gorm:"column:locked"`
+}
+
+// PortfolioAsset is a holding valued in the quote crypto
+type PortfolioAsset struct {
+	CryptoID         int     `json:"crypto_id"`
+	Symbol           string  `json:"symbol"`
+	Quantity         float64 `json:"quantity"` // Available and locked
+	Price            float64 `json:"price"`    // Zero when the crypto has no traded pair against the quote crypto
+	Value            float64 `json:"value"`
+	Change24h        float64 `json:"change_24h"` // Value change caused by the price change of the last 24 hours
+	ChangePercent24h float64 `json:"change_percent_24h"`
+}
+
+type Portfolio struct {
+	QuoteSymbol      string           `json:"quote_symbol"`
+	TotalValue       float64          `json:"total_value"`
+	Change24h        float64          `json:"change_24h"`
+	ChangePercent24h float64          `json:"change_percent_24h"`
+	Assets           []PortfolioAsset `json:"assets"`
+}
+
+type WalletUsecase interface {
+	GetBalances(ctx context.Context) ([]Balance, error)
+	GetPortfolio(ctx context.Context) (Portfolio, error)
+}
+
+// BalanceAdjustment is a manual correction of the available balance by an admin
+type BalanceAdjustment struct {
+	ID        int       `json:"id" gorm:"column:id;type:int;primaryKey;autoIncrement"`
+	UserID    int       `json:"user_id" gorm:"column:user_id;type:int"`
+	CryptoID  int       `json:"crypto_id" gorm:"column:crypto_id;type:int"`
+	Amount    float64   `json:"amount" gorm:"column:amount;type:double"` // Negative is a debit
+	Reason    string    `json:"reason" gorm:"column:reason;type:varchar;size:512"`
+	AdminID   int       `json:"admin_id" gorm:"column:admin_id;type:int"`
+	CreatedAt time.Time `json:"created_at" gorm:"column:created_at;type:datetime"`
+}
+
+func (BalanceAdjustment) TableName() string {
+	return "balance_adjustments"
+}
+
+// BalanceChange is the net change of the available and locked balance of one wallet
+type BalanceChange struct {
+	UserID    int
+	CryptoID  int
+	Available float64
+	Locked    float64
+}
+
 type WalletRepository interface {
 	// Crypto Pair
 	GetPairDetail(ctx context.Context, code string) (Pair, error)
 	GetPairDetailByID(ctx context.Context, id int) (Pair, error)
+	GetCrypto(ctx context.Context, id int) (Crypto, error) // Active crypto only
 
 	// Wallet
 	Save(ctx context.Context, wallet Wallet) error
 	GetUserWallet(ctx context.Context, userID, cryptoID int) (Wallet, error)
-	UpdateUserWallet(ctx context.Context, userID, cryptoID int, amount float64) error
+	GetUserBalances(ctx context.Context, userID int) ([]Balance, error) // Every active crypto
+	GetPairTickers(ctx context.Context, since int64) ([]PairTicker, error)
+	SaveBalanceAdjustment(ctx context.Context, adjustment BalanceAdjustment) (BalanceAdjustment, error)
+
+	// Balance movement, every operation fails with ErrWalletNotFound when the wallet does not exist
+	// and with ErrMissingLedgerJournal when the context has no ledger journal to post the change
+	LockBalance(ctx context.Context, userID, cryptoID int, amount float64) error          // Available to locked, ErrInsufficientBalance when not enough
+	UnlockBalance(ctx context.Context, userID, cryptoID int, amount float64) error        // Locked back to available, on cancel, expiry or refund
+	ConsumeLockedBalance(ctx context.Context, userID, cryptoID int, amount float64) error // Spend locked balance on fill
+	CreditBalance(ctx context.Context, userID, cryptoID int, amount float64) error        // Add to available, negative amount is a debit
+	DebitBalance(ctx context.Context, userID, cryptoID int, amount float64) error         // Take from available, ErrInsufficientBalance when not enough
+	ApplyBalanceChange(ctx context.Context, change BalanceChange) error                   // Net change of a batch, ledger entries are posted by the caller
 }
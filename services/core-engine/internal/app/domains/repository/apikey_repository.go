@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"core-engine/internal/app/domains/model"
+	gormpkg "core-engine/pkg/gorm"
+)
+
+type apiKeyRepository struct {
+	readDB  *gorm.DB
+	writeDB *gorm.DB
+}
+
+// NewApiKeyRepository returns new api key Repository.
+func NewApiKeyRepository(readDB *gorm.DB, writeDB *gorm.DB) *apiKeyRepository {
+	return &apiKeyRepository{
+		readDB:  readDB,
+		writeDB: writeDB,
+	}
+}
+
+func (r *apiKeyRepository) SaveApiKey(ctx context.Context, apiKey model.ApiKey) (model.ApiKey, error) {
+	writeDB := r.writeDB
+	if tx := gormpkg.GetTransactionFromContext(ctx); tx != nil {
+		writeDB = tx
+	}
+
+	if err := writeDB.WithContext(ctx).Save(&apiKey).Error; err != nil {
+		return model.ApiKey{}, err
+	}
+
+	return apiKey, nil
+}
+
+// GetApiKeyByKeyID reads from the write database, a revoked key must stop working at once
+func (r *apiKeyRepository) GetApiKeyByKeyID(ctx context.Context, keyID string) (model.ApiKey, error) {
+	var apiKey model.ApiKey
+	if err := r.writeDB.WithContext(ctx).Where("key_id = ? AND revoked_at IS NULL", keyID).First(&apiKey).Error; err != nil {
+		return model.ApiKey{}, err
+	}
+
+	return apiKey, nil
+}
+
+func (r *apiKeyRepository) GetUserApiKeys(ctx context.Context, userID int) ([]model.ApiKey, error) {
+	var apiKeys []model.ApiKey
+	if err := r.readDB.WithContext(ctx).Where("user_id = ?", userID).Order("id DESC").Find(&apiKeys).Error; err != nil {
+		return nil, err
+	}
+
+	return apiKeys, nil
+}
+
+func (r *apiKeyRepository) RevokeApiKey(ctx context.Context, userID, id int) (model.ApiKey, error) {
+	writeDB := r.writeDB
+	if tx := gormpkg.GetTransactionFromContext(ctx); tx != nil {
+		writeDB = tx
+	}
+
+	var apiKey model.ApiKey
+	result := writeDB.WithContext(ctx).Model(&apiKey).Clauses(clause.Returning{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return model.ApiKey{}, result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return model.ApiKey{}, gorm.ErrRecordNotFound
+	}
+
+	return apiKey, nil
+}
+
+func (r *apiKeyRepository) TouchApiKey(ctx context.Context, id int, usedAt time.Time) error {
+	return r.writeDB.WithContext(ctx).Model(&model.ApiKey{}).Where("id = ?", id).Update("last_used_at", usedAt).Error
+}
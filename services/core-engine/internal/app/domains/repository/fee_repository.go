@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+
+	"core-engine/internal/app/domains/model"
+)
+
+type feeRepository struct {
+	readDB  *gorm.DB
+	writeDB *gorm.DB
+}
+
+// NewFeeRepository returns new fee Repository.
+func NewFeeRepository(readDB *gorm.DB, writeDB *gorm.DB) *feeRepository {
+	return &feeRepository{
+		readDB:  readDB,
+		writeDB: writeDB,
+	}
+}
+
+func (r *feeRepository) GetUserFeeSchedule(ctx context.Context, pairID, userID int) (model.FeeSchedule, error) {
+	var schedule model.FeeSchedule
+
+	// Specific pair first, then the user tier before the default tier
+	err := r.readDB.WithContext(ctx).
+		Select("fee_schedules.*").
+		Joins("JOIN users ON users.id = ?", userID).
+		Where("fee_schedules.pair_id IN (?, 0) AND fee_schedules.tier IN (users.fee_tier, ?)", pairID, model.FeeTierDefault).
+		Where("fee_schedules.status = true AND fee_schedules.deleted_at IS NULL").
+		Order("fee_schedules.pair_id DESC").
+		Order(gorm.Expr("fee_schedules.tier = ?", model.FeeTierDefault)).
+		Take(&schedule).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.FeeSchedule{}, nil // No schedule means no fee
+		}
+		return model.FeeSchedule{}, err
+	}
+
+	return schedule, nil
+}
@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"core-engine/internal/app/domains/model"
+	gormpkg "core-engine/pkg/gorm"
+)
+
+type fundingRepository struct {
+	readDB  *gorm.DB
+	writeDB *gorm.DB
+}
+
+// NewFundingRepository returns new deposit and withdrawal Repository.
+func NewFundingRepository(readDB *gorm.DB, writeDB *gorm.DB) *fundingRepository {
+	return &fundingRepository{
+		readDB:  readDB,
+		writeDB: writeDB,
+	}
+}
+
+func (r *fundingRepository) SaveDeposit(ctx context.Context, deposit model.Deposit) (model.Deposit, error) {
+	writeDB := r.writeDB
+	if tx := gormpkg.GetTransactionFromContext(ctx); tx != nil {
+		writeDB = tx
+	}
+
+	if err := writeDB.WithContext(ctx).Save(&deposit).Error; err != nil {
+		return model.Deposit{}, err
+	}
+
+	return deposit, nil
+}
+
+func (r *fundingRepository) GetDepositByTxHash(ctx context.Context, cryptoID int, txHash string) (model.Deposit, error) {
+	var deposit model.Deposit
+	if err := r.readDB.WithContext(ctx).Where("crypto_id = ? AND tx_hash = ?", cryptoID, txHash).First(&deposit).Error; err != nil {
+		return model.Deposit{}, err
+	}
+
+	return deposit, nil
+}
+
+func (r *fundingRepository) GetDepositForUpdate(ctx context.Context, id int) (model.Deposit, error) {
+	writeDB := r.writeDB
+	if tx := gormpkg.GetTransactionFromContext(ctx); tx != nil {
+		writeDB = tx
+	}
+
+	var deposit model.Deposit
+	if err := writeDB.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&deposit, id).Error; err != nil {
+		return model.Deposit{}, err
+	}
+
+	return deposit, nil
+}
+
+func (r *fundingRepository) GetDepositsByStatus(ctx context.Context, statuses []model.DepositStatus, limit int) ([]model.Deposit, error) {
+	var deposits []model.Deposit
+	if err := r.readDB.WithContext(ctx).Where("status IN ?", statuses).Order("id").Limit(limit).Find(&deposits).Error; err != nil {
+		return nil, err
+	}
+
+	return deposits, nil
+}
+
+func (r *fundingRepository) GetUserDeposits(ctx context.Context, userID, page, limit int) ([]model.Deposit, int, error) {
+	query := r.readDB.WithContext(ctx).Model(&model.Deposit{}).Where("user_id = ?", userID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var deposits []model.Deposit
+	if err := query.Scopes(gormpkg.CreatePaginationQuery(page, limit, "id", "DESC")).Find(&deposits).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return deposits, int(total), nil
+}
+
+func (r *fundingRepository) SaveWithdrawal(ctx context.Context, withdrawal model.Withdrawal) (model.Withdrawal, error) {
+	writeDB := r.writeDB
+	if tx := gormpkg.GetTransactionFromContext(ctx); tx != nil {
+		writeDB = tx
+	}
+
+	if err := writeDB.WithContext(ctx).Save(&withdrawal).Error; err != nil {
+		return model.Withdrawal{}, err
+	}
+
+	return withdrawal, nil
+}
+
+func (r *fundingRepository) GetWithdrawalForUpdate(ctx context.Context, id int) (model.Withdrawal, error) {
+	writeDB := r.writeDB
+	if tx := gormpkg.GetTransactionFromContext(ctx); tx != nil {
+		writeDB = tx
+	}
+
+	var withdrawal model.Withdrawal
+	if err := writeDB.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&withdrawal, id).Error; err != nil {
+		return model.Withdrawal{}, err
+	}
+
+	return withdrawal, nil
+}
+
+func (r *fundingRepository) GetWithdrawalsByStatus(ctx context.Context, statuses []model.WithdrawalStatus, limit int) ([]model.Withdrawal, error) {
+	var withdrawals []model.Withdrawal
+	if err := r.readDB.WithContext(ctx).Where("status IN ?", statuses).Order("id").Limit(limit).Find(&withdrawals).Error; err != nil {
+		return nil, err
+	}
+
+	return withdrawals, nil
+}
+
+func (r *fundingRepository) GetUserWithdrawals(ctx context.Context, userID, page, limit int) ([]model.Withdrawal, int, error) {
+	query := r.readDB.WithContext(ctx).Model(&model.Withdrawal{}).Where("user_id = ?", userID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var withdrawals []model.Withdrawal
+	if err := query.Scopes(gormpkg.CreatePaginationQuery(page, limit, "id", "DESC")).Find(&withdrawals).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return withdrawals, int(total), nil
+}
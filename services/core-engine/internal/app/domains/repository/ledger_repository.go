@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"core-engine/internal/app/domains/dto"
+	"core-engine/internal/app/domains/model"
+	gormpkg "core-engine/pkg/gorm"
+)
+
+type ledgerRepository struct {
+	readDB  *gorm.DB
+	writeDB *gorm.DB
+}
+
+// NewLedgerRepository returns new ledger Repository.
+func NewLedgerRepository(readDB *gorm.DB, writeDB *gorm.DB) *ledgerRepository {
+	return &ledgerRepository{
+		readDB:  readDB,
+		writeDB: writeDB,
+	}
+}
+
+func (r *ledgerRepository) SaveJournal(ctx context.Context, journal *model.LedgerJournal) error {
+	return r.SaveJournals(ctx, []*model.LedgerJournal{journal})
+}
+
+// SaveJournals insert the entries of every journal at once, nothing is saved when one of them does not balance
+func (r *ledgerRepository) SaveJournals(ctx context.Context, journals []*model.LedgerJournal) error {
+	var posted []*model.LedgerJournal
+	for _, journal := range journals {
+		if !journal.Balanced() {
+			return model.ErrUnbalancedJournal
+		}
+		if len(journal.Entries) != 0 {
+			posted = append(posted, journal)
+		}
+	}
+
+	if len(posted) == 0 {
+		return nil
+	}
+
+	writeDB := r.writeDB
+	if tx := gormpkg.GetTransactionFromContext(ctx); tx != nil {
+		writeDB = tx
+	}
+
+	var journalIDs []int64
+	if err := writeDB.WithContext(ctx).Raw(`SELECT nextval('ledger_journal_seq') FROM generate_series(1, ?)`, len(posted)).Scan(&journalIDs).Error; err != nil {
+		return err
+	}
+
+	var entries []model.LedgerEntry
+	for i, journal := range posted {
+		for j := range journal.Entries {
+			journal.Entries[j].JournalID = journalIDs[i]
+			journal.Entries[j].ReferenceType = journal.ReferenceType
+			journal.Entries[j].ReferenceID = journal.ReferenceID
+		}
+		entries = append(entries, journal.Entries...)
+	}
+
+	if err := writeDB.WithContext(ctx).Create(&entries).Error; err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *ledgerRepository) GetUserLedger(ctx context.Context, userID int, ledgerReq dto.LedgerRequest) ([]model.LedgerEntry, int, error) {
+	query := r.readDB.WithContext(ctx).Model(&model.LedgerEntry{}).Where("user_id = ?", userID)
+	if ledgerReq.CryptoID != 0 {
+		query = query.Where("crypto_id = ?", ledgerReq.CryptoID)
+	}
+	if ledgerReq.EntryType != "" {
+		query = query.Where("entry_type = ?", ledgerReq.EntryType)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var entries []model.LedgerEntry
+	if err := query.Scopes(gormpkg.CreatePaginationQuery(ledgerReq.Page, ledgerReq.Limit, "id", "DESC")).Find(&entries).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return entries, int(total), nil
+}
+
+func (r *ledgerRepository) GetUserBalanceProjection(ctx context.Context, userID int) ([]model.LedgerBalance, error) {
+	rawQuery := `
+		SELECT
+			COALESCE(w.crypto_id, l.crypto_id) AS crypto_id,
+			COALESCE(w.available, 0) AS available,
+			COALESCE(w.locked, 0) AS locked,
+			COALESCE(l.available, 0) AS ledger_available,
+			COALESCE(l.locked, 0) AS ledger_locked
+		FROM (
+			SELECT crypto_id, SUM(available) AS available, SUM(locked) AS locked
+			FROM wallet WHERE user_id = ? AND deleted_at IS NULL GROUP BY crypto_id
+		) w
+		FULL JOIN (
+			SELECT
+				crypto_id,
+				SUM(amount) FILTER (WHERE account = ?) AS available,
+				SUM(amount) FILTER (WHERE account = ?) AS locked
+			FROM ledger_entries WHERE user_id = ? GROUP BY crypto_id
+		) l ON l.crypto_id = w.crypto_id
+		ORDER BY 1`
+
+	var balances []model.LedgerBalance
+	err := r.readDB.WithContext(ctx).
+		Raw(rawQuery, userID, model.LedgerAccountAvailable, model.LedgerAccountLocked, userID).
+		Scan(&balances).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return balances, nil
+}
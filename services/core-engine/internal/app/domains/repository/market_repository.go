@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"core-engine/internal/app/domains/model"
+	gormpkg "core-engine/pkg/gorm"
+)
+
+type marketRepository struct {
+	readDB  *gorm.DB
+	writeDB *gorm.DB
+}
+
+// NewMarketRepository returns new market Repository.
+func NewMarketRepository(readDB *gorm.DB, writeDB *gorm.DB) *marketRepository {
+	return &marketRepository{
+		readDB:  readDB,
+		writeDB: writeDB,
+	}
+}
+
+func (r *marketRepository) SaveCrypto(ctx context.Context, crypto model.Crypto) (model.Crypto, error) {
+	writeDB := r.writeDB
+	if tx := gormpkg.GetTransactionFromContext(ctx); tx != nil {
+		writeDB = tx
+	}
+
+	if err := writeDB.WithContext(ctx).Save(&crypto).Error; err != nil {
+		return model.Crypto{}, err
+	}
+
+	return crypto, nil
+}
+
+func (r *marketRepository) GetCryptoForUpdate(ctx context.Context, id int) (model.Crypto, error) {
+	writeDB := r.writeDB
+	if tx := gormpkg.GetTransactionFromContext(ctx); tx != nil {
+		writeDB = tx
+	}
+
+	var crypto model.Crypto
+	if err := writeDB.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).Where("deleted_at IS NULL").First(&crypto, id).Error; err != nil {
+		return model.Crypto{}, err
+	}
+
+	return crypto, nil
+}
+
+func (r *marketRepository) GetCryptoBySymbol(ctx context.Context, symbol string) (model.Crypto, error) {
+	var crypto model.Crypto
+	if err := r.readDB.WithContext(ctx).Where("symbol = ? AND deleted_at IS NULL", symbol).First(&crypto).Error; err != nil {
+		return model.Crypto{}, err
+	}
+
+	return crypto, nil
+}
+
+func (r *marketRepository) SavePair(ctx context.Context, pair model.Pair) (model.Pair, error) {
+	writeDB := r.writeDB
+	if tx := gormpkg.GetTransactionFromContext(ctx); tx != nil {
+		writeDB = tx
+	}
+
+	if err := writeDB.WithContext(ctx).Save(&pair).Error; err != nil {
+		return model.Pair{}, err
+	}
+
+	return pair, nil
+}
+
+func (r *marketRepository) GetPairForUpdate(ctx context.Context, id int) (model.Pair, error) {
+	writeDB := r.writeDB
+	if tx := gormpkg.GetTransactionFromContext(ctx); tx != nil {
+		writeDB = tx
+	}
+
+	var pair model.Pair
+	if err := writeDB.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).Where("deleted_at IS NULL").First(&pair, id).Error; err != nil {
+		return model.Pair{}, err
+	}
+
+	return pair, nil
+}
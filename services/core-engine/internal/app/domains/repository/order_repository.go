@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"core-engine/internal/app/domains/model"
 	gormpkg "core-engine/pkg/gorm"
@@ -44,15 +45,174 @@ func (r *orderRepository) GetOrder(ctx context.Context, id int) (model.Order, er
 	return order, nil
 }
 
-func (r *orderRepository) SaveMatchOrder(ctx context.Context, matchOrder model.MatchOrder) error {
+// GetOrderForUpdate lock the order row until the transaction in the context is finished
+func (r *orderRepository) GetOrderForUpdate(ctx context.Context, id int) (model.Order, error) {
 	writeDB := r.writeDB
 	if tx := gormpkg.GetTransactionFromContext(ctx); tx != nil {
 		writeDB = tx
 	}
 
-	if err := writeDB.WithContext(ctx).Save(&matchOrder).Error; err != nil {
-		return err
+	var order model.Order
+	if err := writeDB.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, id).Error; err != nil {
+		return model.Order{}, err
+	}
+
+	return order, nil
+}
+
+func (r *orderRepository) GetUserOrder(ctx context.Context, userID, id int) (model.Order, error) {
+	var order model.Order
+	if err := r.readDB.WithContext(ctx).Where("user_id = ?", userID).First(&order, id).Error; err != nil {
+		return model.Order{}, err
+	}
+
+	return order, nil
+}
+
+func (r *orderRepository) GetUserOrders(ctx context.Context, userID int, filter model.OrderFilter) ([]model.Order, int, error) {
+	query := r.readDB.WithContext(ctx).Model(&model.Order{}).Where("user_id = ?", userID)
+	if filter.PairID != 0 {
+		query = query.Where("pair_id = ?", filter.PairID)
+	}
+	if len(filter.Statuses) != 0 {
+		query = query.Where("status IN ?", filter.Statuses)
+	}
+	if filter.Side != "" {
+		query = query.Where("side = ?", filter.Side)
+	}
+	if filter.StartTime != 0 {
+		query = query.Where("transaction_time >= ?", filter.StartTime)
+	}
+	if filter.EndTime != 0 {
+		query = query.Where("transaction_time < ?", filter.EndTime)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var orders []model.Order
+	if err := query.Scopes(gormpkg.CreatePaginationQuery(filter.Page, filter.Limit, filter.SortBy, filter.SortDirection)).Find(&orders).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return orders, int(total), nil
+}
+
+func (r *orderRepository) GetOrdersForUpdate(ctx context.Context, ids []int) ([]model.Order, error) {
+	writeDB := r.writeDB
+	if tx := gormpkg.GetTransactionFromContext(ctx); tx != nil {
+		writeDB = tx
+	}
+
+	var orders []model.Order
+	if err := writeDB.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).Where("id IN ?", ids).Order("id").Find(&orders).Error; err != nil {
+		return nil, err
+	}
+
+	if len(orders) != len(ids) {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return orders, nil
+}
+
+func (r *orderRepository) SaveMatchOrder(ctx context.Context, matchOrder model.MatchOrder) (model.MatchOrder, error) {
+	writeDB := r.writeDB
+	if tx := gormpkg.GetTransactionFromContext(ctx); tx != nil {
+		writeDB = tx
+	}
+
+	// The unique trade sequence reject a concurrent settlement of the same trade
+	result := writeDB.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&matchOrder)
+	if result.Error != nil {
+		return model.MatchOrder{}, result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return model.MatchOrder{}, model.ErrTradeAlreadySettled
+	}
+
+	return matchOrder, nil
+}
+
+func (r *orderRepository) IsTradeSettled(ctx context.Context, pairID int, tradeSequence int64) (bool, error) {
+	writeDB := r.writeDB
+	if tx := gormpkg.GetTransactionFromContext(ctx); tx != nil {
+		writeDB = tx
+	}
+
+	var count int64
+	err := writeDB.WithContext(ctx).
+		Model(&model.MatchOrder{}).
+		Where("pair_id = ? AND trade_sequence = ?", pairID, tradeSequence).
+		Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+func (r *orderRepository) GetSettledTradeSequences(ctx context.Context, pairID int, tradeSequences []int64) (map[int64]bool, error) {
+	writeDB := r.writeDB
+	if tx := gormpkg.GetTransactionFromContext(ctx); tx != nil {
+		writeDB = tx
+	}
+
+	var settled []int64
+	err := writeDB.WithContext(ctx).
+		Model(&model.MatchOrder{}).
+		Where("pair_id = ? AND trade_sequence IN ?", pairID, tradeSequences).
+		Pluck("trade_sequence", &settled).Error
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[int64]bool, len(settled))
+	for _, sequence := range settled {
+		result[sequence] = true
+	}
+
+	return result, nil
+}
+
+func (r *orderRepository) GetUserTrades(ctx context.Context, userID int, filter model.TradeFilter) ([]model.UserTrade, error) {
+	// Taker and maker side are separate branch so each one use the index of its order column
+	rawQuery := `
+		SELECT m.id AS match_order_id, o.id AS order_id, m.pair_id, p.code AS pair_code, o.side, 'TAKER' AS role,
+			m.price, m.quantity, m.taker_fee AS fee, m.taker_fee_crypto_id AS fee_crypto_id, m.transaction_time
+		FROM match_orders m
+		JOIN orders o ON o.id = m.taker_order_id
+		JOIN pairs p ON p.id = m.pair_id
+		WHERE o.user_id = ?
+		UNION ALL
+		SELECT m.id AS match_order_id, o.id AS order_id, m.pair_id, p.code AS pair_code, o.side, 'MAKER' AS role,
+			m.price, m.quantity, m.maker_fee AS fee, m.maker_fee_crypto_id AS fee_crypto_id, m.transaction_time
+		FROM match_orders m
+		JOIN orders o ON o.id = m.maker_order_id
+		JOIN pairs p ON p.id = m.pair_id
+		WHERE o.user_id = ?`
+
+	query := r.readDB.WithContext(ctx).Table("(?) AS t", r.readDB.Raw(rawQuery, userID, userID))
+	if filter.PairID != 0 {
+		query = query.Where("pair_id = ?", filter.PairID)
+	}
+	if filter.StartTime != 0 {
+		query = query.Where("transaction_time >= ?", filter.StartTime)
+	}
+	if filter.EndTime != 0 {
+		query = query.Where("transaction_time < ?", filter.EndTime)
+	}
+	if filter.BeforeMatch != 0 {
+		query = query.Where("(match_order_id, order_id) < (?, ?)", filter.BeforeMatch, filter.BeforeOrder)
+	}
+
+	var trades []model.UserTrade
+	if err := query.Order("match_order_id DESC, order_id DESC").Limit(filter.Limit).Find(&trades).Error; err != nil {
+		return nil, err
 	}
 
-	return nil
+	return trades, nil
 }
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"core-engine/internal/app/domains/dto"
+	"core-engine/internal/app/domains/model"
+	gormpkg "core-engine/pkg/gorm"
+)
+
+type transferRepository struct {
+	readDB  *gorm.DB
+	writeDB *gorm.DB
+}
+
+// NewTransferRepository returns new transfer Repository.
+func NewTransferRepository(readDB *gorm.DB, writeDB *gorm.DB) *transferRepository {
+	return &transferRepository{
+		readDB:  readDB,
+		writeDB: writeDB,
+	}
+}
+
+func (r *transferRepository) SaveTransfer(ctx context.Context, transfer model.Transfer) (model.Transfer, error) {
+	writeDB := r.writeDB
+	if tx := gormpkg.GetTransactionFromContext(ctx); tx != nil {
+		writeDB = tx
+	}
+
+	if err := writeDB.WithContext(ctx).Save(&transfer).Error; err != nil {
+		return model.Transfer{}, err
+	}
+
+	return transfer, nil
+}
+
+// GetTransferredAmount reads from the transaction of the context, the amount must include transfers committed just before
+func (r *transferRepository) GetTransferredAmount(ctx context.Context, senderID, cryptoID int, since time.Time) (float64, error) {
+	writeDB := r.writeDB
+	if tx := gormpkg.GetTransactionFromContext(ctx); tx != nil {
+		writeDB = tx
+	}
+
+	var amount float64
+	err := writeDB.WithContext(ctx).Model(&model.Transfer{}).
+		Select("COALESCE(SUM(amount), 0)").
+		Where("sender_id = ? AND crypto_id = ? AND created_at >= ?", senderID, cryptoID, since).
+		Scan(&amount).Error
+	if err != nil {
+		return 0, err
+	}
+
+	return amount, nil
+}
+
+func (r *transferRepository) GetUserTransfers(ctx context.Context, userID int, listReq dto.TransferListRequest) ([]model.Transfer, int, error) {
+	query := r.readDB.WithContext(ctx).Model(&model.Transfer{}).Where("(sender_id = ? OR receiver_id = ?)", userID, userID)
+	if listReq.CryptoID != 0 {
+		query = query.Where("crypto_id = ?", listReq.CryptoID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var transfers []model.Transfer
+	if err := query.Scopes(gormpkg.CreatePaginationQuery(listReq.Page, listReq.Limit, "id", "DESC")).Find(&transfers).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return transfers, int(total), nil
+}
@@ -2,10 +2,13 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"core-engine/internal/app/domains/model"
+	gormpkg "core-engine/pkg/gorm"
 )
 
 type userRepository struct {
@@ -30,10 +33,189 @@ func (r *userRepository) FindUserByEmail(ctx context.Context, email string) (mod
 	return user, nil
 }
 
+func (r *userRepository) FindUserByID(ctx context.Context, id int) (model.User, error) {
+	var user model.User
+	if err := r.readDB.WithContext(ctx).First(&user, id).Error; err != nil {
+		return model.User{}, err
+	}
+
+	return user, nil
+}
+
+func (r *userRepository) UpdateUserStatus(ctx context.Context, id int, status bool) error {
+	writeDB := r.writeDB
+	if tx := gormpkg.GetTransactionFromContext(ctx); tx != nil {
+		writeDB = tx
+	}
+
+	result := writeDB.WithContext(ctx).Model(&model.User{}).Where("id = ?", id).Update("status", status)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
 func (r *userRepository) RegisterNewUser(ctx context.Context, user model.User) (model.User, error) {
-	if err := r.writeDB.WithContext(ctx).Save(&user).Error; err != nil {
+	writeDB := r.writeDB
+	if tx := gormpkg.GetTransactionFromContext(ctx); tx != nil {
+		writeDB = tx
+	}
+
+	if err := writeDB.WithContext(ctx).Save(&user).Error; err != nil {
+		return model.User{}, err
+	}
+
+	return user, nil
+}
+
+func (r *userRepository) SaveRefreshToken(ctx context.Context, token model.RefreshToken) (model.RefreshToken, error) {
+	writeDB := r.writeDB
+	if tx := gormpkg.GetTransactionFromContext(ctx); tx != nil {
+		writeDB = tx
+	}
+
+	if err := writeDB.WithContext(ctx).Save(&token).Error; err != nil {
+		return model.RefreshToken{}, err
+	}
+
+	return token, nil
+}
+
+func (r *userRepository) GetRefreshTokenForUpdate(ctx context.Context, tokenHash string) (model.RefreshToken, error) {
+	writeDB := r.writeDB
+	if tx := gormpkg.GetTransactionFromContext(ctx); tx != nil {
+		writeDB = tx
+	}
+
+	var token model.RefreshToken
+	if err := writeDB.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).Where("token_hash = ?", tokenHash).First(&token).Error; err != nil {
+		return model.RefreshToken{}, err
+	}
+
+	return token, nil
+}
+
+func (r *userRepository) GetRefreshTokenByAccessTokenID(ctx context.Context, accessTokenID string) (model.RefreshToken, error) {
+	var token model.RefreshToken
+	if err := r.readDB.WithContext(ctx).Where("access_token_id = ?", accessTokenID).First(&token).Error; err != nil {
+		return model.RefreshToken{}, err
+	}
+
+	return token, nil
+}
+
+func (r *userRepository) RevokeRefreshTokenFamily(ctx context.Context, familyID string) ([]model.RefreshToken, error) {
+	writeDB := r.writeDB
+	if tx := gormpkg.GetTransactionFromContext(ctx); tx != nil {
+		writeDB = tx
+	}
+
+	var tokens []model.RefreshToken
+	err := writeDB.WithContext(ctx).Model(&tokens).
+		Clauses(clause.Returning{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+func (r *userRepository) RevokeUserRefreshTokens(ctx context.Context, userID int) ([]model.RefreshToken, error) {
+	writeDB := r.writeDB
+	if tx := gormpkg.GetTransactionFromContext(ctx); tx != nil {
+		writeDB = tx
+	}
+
+	var tokens []model.RefreshToken
+	err := writeDB.WithContext(ctx).Model(&tokens).
+		Clauses(clause.Returning{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+func (r *userRepository) GetUserForUpdate(ctx context.Context, id int) (model.User, error) {
+	writeDB := r.writeDB
+	if tx := gormpkg.GetTransactionFromContext(ctx); tx != nil {
+		writeDB = tx
+	}
+
+	var user model.User
+	if err := writeDB.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, id).Error; err != nil {
 		return model.User{}, err
 	}
 
 	return user, nil
 }
+
+func (r *userRepository) UpdatePassword(ctx context.Context, id int, passwordHash string) error {
+	writeDB := r.writeDB
+	if tx := gormpkg.GetTransactionFromContext(ctx); tx != nil {
+		writeDB = tx
+	}
+
+	return writeDB.WithContext(ctx).Model(&model.User{}).Where("id = ?", id).Update("password", passwordHash).Error
+}
+
+func (r *userRepository) UpdateTotp(ctx context.Context, user model.User) error {
+	writeDB := r.writeDB
+	if tx := gormpkg.GetTransactionFromContext(ctx); tx != nil {
+		writeDB = tx
+	}
+
+	return writeDB.WithContext(ctx).Model(&model.User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+		"totp_secret":    user.TotpSecret,
+		"totp_enabled":   user.TotpEnabled,
+		"totp_last_step": user.TotpLastStep,
+	}).Error
+}
+
+// ReplaceRecoveryCodes deletes every previous code of the user
+func (r *userRepository) ReplaceRecoveryCodes(ctx context.Context, userID int, codeHashes []string) error {
+	writeDB := r.writeDB
+	if tx := gormpkg.GetTransactionFromContext(ctx); tx != nil {
+		writeDB = tx
+	}
+
+	if err := writeDB.WithContext(ctx).Where("user_id = ?", userID).Delete(&model.RecoveryCode{}).Error; err != nil {
+		return err
+	}
+
+	if len(codeHashes) == 0 {
+		return nil
+	}
+
+	codes := make([]model.RecoveryCode, 0, len(codeHashes))
+	for _, codeHash := range codeHashes {
+		codes = append(codes, model.RecoveryCode{UserID: userID, CodeHash: codeHash})
+	}
+
+	return writeDB.WithContext(ctx).Create(&codes).Error
+}
+
+func (r *userRepository) UseRecoveryCode(ctx context.Context, userID int, codeHash string) (bool, error) {
+	writeDB := r.writeDB
+	if tx := gormpkg.GetTransactionFromContext(ctx); tx != nil {
+		writeDB = tx
+	}
+
+	result := writeDB.WithContext(ctx).Model(&model.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
@@ -40,6 +40,15 @@ func (r *walletRepository) GetPairDetailByID(ctx context.Context, id int) (model
 	return pair, nil
 }
 
+func (r *walletRepository) GetCrypto(ctx context.Context, id int) (model.Crypto, error) {
+	var crypto model.Crypto
+	if err := r.readDB.WithContext(ctx).Where("status = true").First(&crypto, id).Error; err != nil {
+		return model.Crypto{}, err
+	}
+
+	return crypto, nil
+}
+
 func (r *walletRepository) Save(ctx context.Context, wallet model.Wallet) error {
 	writeDB := r.writeDB
 	if tx := gormpkg.GetTransactionFromContext(ctx); tx != nil {
@@ -62,17 +71,147 @@ func (r *walletRepository) GetUserWallet(ctx context.Context, userID, cryptoID i
 	return wallet, nil
 }
 
-// Increase user wallet
-func (r *walletRepository) UpdateUserWallet(ctx context.Context, userID, cryptoID int, amount float64) error {
+func (r *walletRepository) GetUserBalances(ctx context.Context, userID int) ([]model.Balance, error) {
+	rawQuery := `
+		SELECT c.id AS crypto_id, c.symbol, COALESCE(SUM(w.available), 0) AS available, COALESCE(SUM(w.locked), 0) AS locked
+		FROM crypto c
+		LEFT JOIN wallet w ON w.crypto_id = c.id AND w.user_id = ? AND w.deleted_at IS NULL
+		WHERE c.id <> 0 AND c.status = true AND c.deleted_at IS NULL
+		GROUP BY c.id, c.symbol
+		ORDER BY c.id`
+
+	var balances []model.Balance
+	if err := r.readDB.WithContext(ctx).Raw(rawQuery, userID).Scan(&balances).Error; err != nil {
+		return nil, err
+	}
+
+	return balances, nil
+}
+
+// GetPairTickers returns the last price of every active pair, and its price at the since unix time
+func (r *walletRepository) GetPairTickers(ctx context.Context, since int64) ([]model.PairTicker, error) {
+	rawQuery := `
+		SELECT p.id AS pair_id, p.code AS pair_code, p.primary_crypto_id, p.secondary_crypto_id,
+			COALESCE(last_trade.price, 0) AS last_price,
+			COALESCE(open_trade.price, first_trade.price, 0) AS open_price
+		FROM pairs p
+		LEFT JOIN LATERAL (
+			SELECT price FROM match_orders WHERE pair_id = p.id ORDER BY id DESC LIMIT 1
+		) last_trade ON true
+		LEFT JOIN LATERAL (
+			SELECT price FROM match_orders WHERE pair_id = p.id AND transaction_time < ? ORDER BY id DESC LIMIT 1
+		) open_trade ON true
+		LEFT JOIN LATERAL (
+			SELECT price FROM match_orders WHERE pair_id = p.id AND transaction_time >= ? ORDER BY id LIMIT 1
+		) first_trade ON true
+		WHERE p.id <> 0 AND p.status = true AND p.deleted_at IS NULL`
+
+	var tickers []model.PairTicker
+	if err := r.readDB.WithContext(ctx).Raw(rawQuery, since, since).Scan(&tickers).Error; err != nil {
+		return nil, err
+	}
+
+	return tickers, nil
+}
+
+func (r *walletRepository) SaveBalanceAdjustment(ctx context.Context, adjustment model.BalanceAdjustment) (model.BalanceAdjustment, error) {
 	writeDB := r.writeDB
 	if tx := gormpkg.GetTransactionFromContext(ctx); tx != nil {
 		writeDB = tx
 	}
 
-	rawQuery := `UPDATE wallet SET quantity = quantity + ? WHERE user_id = ? AND crypto_id = ?`
-	if err := writeDB.WithContext(ctx).Exec(rawQuery, amount, userID, cryptoID).Error; err != nil {
+	if err := writeDB.WithContext(ctx).Save(&adjustment).Error; err != nil {
+		return model.BalanceAdjustment{}, err
+	}
+
+	return adjustment, nil
+}
+
+// Float residue tolerated when taking from locked balance, the rest is rejected by the wallet check constraint
+const balanceTolerance = 1e-9
+
+func (r *walletRepository) LockBalance(ctx context.Context, userID, cryptoID int, amount float64) error {
+	rawQuery := `UPDATE wallet SET available = available - ?, locked = locked + ? WHERE user_id = ? AND crypto_id = ? AND available >= ?`
+	if err := r.updateBalance(ctx, model.ErrInsufficientBalance, rawQuery, amount, amount, userID, cryptoID, amount); err != nil {
+		return err
+	}
+
+	return r.post(ctx, userID, cryptoID, -amount, amount)
+}
+
+func (r *walletRepository) UnlockBalance(ctx context.Context, userID, cryptoID int, amount float64) error {
+	rawQuery := `UPDATE wallet SET locked = GREATEST(locked - ?, 0), available = available + ? WHERE user_id = ? AND crypto_id = ? AND locked >= ? - ?`
+	if err := r.updateBalance(ctx, model.ErrInsufficientBalance, rawQuery, amount, amount, userID, cryptoID, amount, balanceTolerance); err != nil {
 		return err
 	}
 
+	return r.post(ctx, userID, cryptoID, amount, -amount)
+}
+
+func (r *walletRepository) ConsumeLockedBalance(ctx context.Context, userID, cryptoID int, amount float64) error {
+	rawQuery := `UPDATE wallet SET locked = GREATEST(locked - ?, 0) WHERE user_id = ? AND crypto_id = ? AND locked >= ? - ?`
+	if err := r.updateBalance(ctx, model.ErrInsufficientBalance, rawQuery, amount, userID, cryptoID, amount, balanceTolerance); err != nil {
+		return err
+	}
+
+	return r.post(ctx, userID, cryptoID, 0, -amount)
+}
+
+func (r *walletRepository) CreditBalance(ctx context.Context, userID, cryptoID int, amount float64) error {
+	rawQuery := `UPDATE wallet SET available = available + ? WHERE user_id = ? AND crypto_id = ?`
+	if err := r.updateBalance(ctx, model.ErrWalletNotFound, rawQuery, amount, userID, cryptoID); err != nil {
+		return err
+	}
+
+	return r.post(ctx, userID, cryptoID, amount, 0)
+}
+
+func (r *walletRepository) DebitBalance(ctx context.Context, userID, cryptoID int, amount float64) error {
+	rawQuery := `UPDATE wallet SET available = available - ? WHERE user_id = ? AND crypto_id = ? AND available >= ?`
+	if err := r.updateBalance(ctx, model.ErrInsufficientBalance, rawQuery, amount, userID, cryptoID, amount); err != nil {
+		return err
+	}
+
+	return r.post(ctx, userID, cryptoID, -amount, 0)
+}
+
+// Record the change of available and locked balance into the ledger journal of the context
+func (r *walletRepository) post(ctx context.Context, userID, cryptoID int, available, locked float64) error {
+	journal := model.GetLedgerJournalFromContext(ctx)
+	if journal == nil {
+		return model.ErrMissingLedgerJournal
+	}
+
+	journal.PostBalanceChange(userID, cryptoID, available, locked)
+	return nil
+}
+
+// ApplyBalanceChange add the net change of many movements in a single update, the caller posts the ledger entries of every movement
+func (r *walletRepository) ApplyBalanceChange(ctx context.Context, change model.BalanceChange) error {
+	errNoRow := model.ErrWalletNotFound
+	if change.Locked < 0 {
+		errNoRow = model.ErrInsufficientBalance
+	}
+
+	rawQuery := `UPDATE wallet SET available = available + ?, locked = GREATEST(locked + ?, 0) WHERE user_id = ? AND crypto_id = ? AND locked + ? >= - ?`
+	return r.updateBalance(ctx, errNoRow, rawQuery, change.Available, change.Locked, change.UserID, change.CryptoID, change.Locked, balanceTolerance)
+}
+
+// Run a single balance update, errNoRow is returned when no wallet matched the condition
+func (r *walletRepository) updateBalance(ctx context.Context, errNoRow error, rawQuery string, values ...interface{}) error {
+	writeDB := r.writeDB
+	if tx := gormpkg.GetTransactionFromContext(ctx); tx != nil {
+		writeDB = tx
+	}
+
+	result := writeDB.WithContext(ctx).Exec(rawQuery, values...)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return errNoRow
+	}
+
 	return nil
 }
@@ -0,0 +1,192 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/gerins/log"
+	"gorm.io/gorm"
+
+	"core-engine/config"
+	"core-engine/internal/app/domains/dto"
+	"core-engine/internal/app/domains/model"
+	serverError "core-engine/pkg/error"
+	gormpkg "core-engine/pkg/gorm"
+	"core-engine/pkg/jwt"
+)
+
+type adminUsecase struct {
+	writeDB          *gorm.DB
+	securityConfig   config.Security
+	userRepository   model.UserRepository
+	walletRepository model.WalletRepository
+	ledgerRepository model.LedgerRepository
+	denyList         jwt.DenyList
+}
+
+// NewAdminUsecase returns new admin usecase.
+func NewAdminUsecase(
+	writeDB *gorm.DB,
+	securityConfig config.Security,
+	userRepository model.UserRepository,
+	walletRepository model.WalletRepository,
+	ledgerRepository model.LedgerRepository,
+	denyList jwt.DenyList,
+) *adminUsecase {
+	return &adminUsecase{
+		writeDB:          writeDB,
+		securityConfig:   securityConfig,
+		userRepository:   userRepository,
+		walletRepository: walletRepository,
+		ledgerRepository: ledgerRepository,
+		denyList:         denyList,
+	}
+}
+
+func (u *adminUsecase) GetUser(ctx context.Context, id int) (model.UserDetail, error) {
+	user, err := u.userRepository.FindUserByID(ctx, id)
+	if err != nil {
+		return model.UserDetail{}, err
+	}
+
+	return u.userDetail(ctx, user)
+}
+
+func (u *adminUsecase) FindUser(ctx context.Context, email string) (model.UserDetail, error) {
+	user, err := u.userRepository.FindUserByEmail(ctx, strings.TrimSpace(email))
+	if err != nil {
+		return model.UserDetail{}, err
+	}
+
+	return u.userDetail(ctx, user)
+}
+
+// FreezeUser blocks the user and revokes every session, the access token already issued is denied until it expires
+func (u *adminUsecase) FreezeUser(ctx context.Context, id int, reason string) (model.User, error) {
+	return u.setUserStatus(ctx, id, false, reason)
+}
+
+func (u *adminUsecase) UnfreezeUser(ctx context.Context, id int, reason string) (model.User, error) {
+	return u.setUserStatus(ctx, id, true, reason)
+}
+
+// AdjustBalance credits or debits the available balance, the counterpart is posted to the external account
+func (u *adminUsecase) AdjustBalance(ctx context.Context, userID int, adjustmentReq dto.AdjustmentRequest) (model.BalanceAdjustment, error) {
+	tokenPayload := jwt.GetPayloadFromContext(ctx)
+
+	adjustmentReq.Reason = strings.TrimSpace(adjustmentReq.Reason)
+	if adjustmentReq.Reason == "" {
+		return model.BalanceAdjustment{}, serverError.ErrInvalidRequest(model.ErrReasonRequired)
+	}
+
+	if adjustmentReq.Amount == 0 {
+		return model.BalanceAdjustment{}, serverError.ErrInvalidRequest(nil)
+	}
+
+	if _, err := u.userRepository.FindUserByID(ctx, userID); err != nil {
+		return model.BalanceAdjustment{}, err
+	}
+
+	if _, err := u.walletRepository.GetCrypto(ctx, adjustmentReq.CryptoID); err != nil {
+		return model.BalanceAdjustment{}, serverError.ErrInvalidRequest(err)
+	}
+
+	ctx, tx := gormpkg.InitTransactionToContext(ctx, u.writeDB)
+	defer tx.WithContext(ctx).Rollback()
+
+	adjustment, err := u.walletRepository.SaveBalanceAdjustment(ctx, model.BalanceAdjustment{
+		UserID:   userID,
+		CryptoID: adjustmentReq.CryptoID,
+		Amount:   adjustmentReq.Amount,
+		Reason:   adjustmentReq.Reason,
+		AdminID:  tokenPayload.UserID,
+	})
+	if err != nil {
+		return model.BalanceAdjustment{}, serverError.ErrGeneralDatabaseError(err)
+	}
+
+	journal := model.NewLedgerJournal(model.LedgerEntryAdjustment, model.LedgerReferenceAdjustment)
+	journal.ReferenceID = adjustment.ID
+	ctx = model.SaveLedgerJournalToContext(ctx, journal)
+
+	if adjustment.Amount > 0 {
+		err = creditWallet(ctx, u.walletRepository, userID, adjustment.CryptoID, adjustment.Amount)
+	} else {
+		err = u.walletRepository.DebitBalance(ctx, userID, adjustment.CryptoID, -adjustment.Amount)
+	}
+	if errors.Is(err, model.ErrInsufficientBalance) || errors.Is(err, model.ErrWalletNotFound) {
+		return model.BalanceAdjustment{}, serverError.ErrInsufficientBalance(err)
+	}
+	if err != nil {
+		return model.BalanceAdjustment{}, serverError.ErrGeneralDatabaseError(err)
+	}
+
+	journal.PostExternal(adjustment.CryptoID, adjustment.Amount)
+
+	if err := u.ledgerRepository.SaveJournal(ctx, journal); err != nil {
+		return model.BalanceAdjustment{}, serverError.ErrGeneralDatabaseError(err)
+	}
+
+	if err := tx.WithContext(ctx).Commit().Error; err != nil {
+		return model.BalanceAdjustment{}, serverError.ErrGeneralDatabaseError(err)
+	}
+
+	log.Context(ctx).Infof("admin %v adjusted crypto %v of user %v by %v, %v", adjustment.AdminID, adjustment.CryptoID, userID, adjustment.Amount, adjustment.Reason)
+	return adjustment, nil
+}
+
+func (u *adminUsecase) setUserStatus(ctx context.Context, id int, status bool, reason string) (model.User, error) {
+	tokenPayload := jwt.GetPayloadFromContext(ctx)
+
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return model.User{}, serverError.ErrInvalidRequest(model.ErrReasonRequired)
+	}
+
+	if id == tokenPayload.UserID {
+		return model.User{}, serverError.ErrInvalidRequest(nil) // Admin can not freeze itself out
+	}
+
+	ctx, tx := gormpkg.InitTransactionToContext(ctx, u.writeDB)
+	defer tx.WithContext(ctx).Rollback()
+
+	if err := u.userRepository.UpdateUserStatus(ctx, id, status); err != nil {
+		return model.User{}, err
+	}
+
+	if !status {
+		revoked, err := u.userRepository.RevokeUserRefreshTokens(ctx, id)
+		if err != nil {
+			return model.User{}, serverError.ErrGeneralDatabaseError(err)
+		}
+
+		if err := denyAccessTokens(ctx, u.denyList, revoked, u.securityConfig.Jwt.Duration); err != nil {
+			return model.User{}, err
+		}
+	}
+
+	if err := tx.WithContext(ctx).Commit().Error; err != nil {
+		return model.User{}, serverError.ErrGeneralDatabaseError(err)
+	}
+
+	log.Context(ctx).Infof("admin %v set status of user %v to %v, %v", tokenPayload.UserID, id, status, reason)
+
+	user, err := u.userRepository.FindUserByID(ctx, id)
+	if err != nil {
+		return model.User{}, err
+	}
+
+	user.Password = ""
+	return user, nil
+}
+
+func (u *adminUsecase) userDetail(ctx context.Context, user model.User) (model.UserDetail, error) {
+	balances, err := u.walletRepository.GetUserBalances(ctx, user.ID)
+	if err != nil {
+		return model.UserDetail{}, serverError.ErrGeneralDatabaseError(err)
+	}
+
+	user.Password = ""
+	return model.UserDetail{User: user, Balances: balances}, nil
+}
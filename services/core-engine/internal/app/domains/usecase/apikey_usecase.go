@@ -0,0 +1,208 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/gerins/log"
+	"gorm.io/gorm"
+
+	"core-engine/config"
+	"core-engine/internal/app/domains/dto"
+	"core-engine/internal/app/domains/model"
+	"core-engine/pkg/apikey"
+	serverError "core-engine/pkg/error"
+	"core-engine/pkg/jwt"
+	"core-engine/pkg/secret"
+)
+
+const apiKeyTouchInterval = time.Minute // Last used time is only written once in this interval
+
+type apiKeyUsecase struct {
+	securityConfig   config.Security
+	apiKeyRepository model.ApiKeyRepository
+	userRepository   model.UserRepository
+	twoFactorUsecase model.TwoFactorUsecase
+}
+
+// NewApiKeyUsecase returns new api key usecase.
+func NewApiKeyUsecase(
+	securityConfig config.Security,
+	apiKeyRepository model.ApiKeyRepository,
+	userRepository model.UserRepository,
+	twoFactorUsecase model.TwoFactorUsecase,
+) *apiKeyUsecase {
+	return &apiKeyUsecase{
+		securityConfig:   securityConfig,
+		apiKeyRepository: apiKeyRepository,
+		userRepository:   userRepository,
+		twoFactorUsecase: twoFactorUsecase,
+	}
+}
+
+// CreateApiKey returns the secret once, only its encrypted form is stored
+func (u *apiKeyUsecase) CreateApiKey(ctx context.Context, apiKeyReq dto.ApiKeyRequest) (model.ApiKeyCreated, error) {
+	tokenPayload := jwt.GetPayloadFromContext(ctx)
+
+	scopes, err := parseApiKeyScopes(apiKeyReq.Scopes)
+	if err != nil {
+		return model.ApiKeyCreated{}, serverError.ErrInvalidRequest(err)
+	}
+
+	allowlist, err := parseIPAllowlist(apiKeyReq.IPAllowlist)
+	if err != nil {
+		return model.ApiKeyCreated{}, serverError.ErrInvalidRequest(err)
+	}
+
+	if apikey.HasScope(scopes, model.ApiKeyScopeWithdraw) && allowlist == "" {
+		return model.ApiKeyCreated{}, serverError.ErrInvalidRequest(model.ErrWithdrawKeyAllowlist)
+	}
+
+	if err := u.twoFactorUsecase.Verify(ctx, tokenPayload.UserID, apiKeyReq.TotpCode); err != nil {
+		return model.ApiKeyCreated{}, err
+	}
+
+	keyID, keySecret, err := apikey.NewKey()
+	if err != nil {
+		return model.ApiKeyCreated{}, serverError.ErrGeneralError(err)
+	}
+
+	secretEncrypted, err := secret.Encrypt(u.securityConfig.ApiKey.EncryptionKey, keySecret)
+	if err != nil {
+		return model.ApiKeyCreated{}, serverError.ErrGeneralError(err)
+	}
+
+	apiKey, err := u.apiKeyRepository.SaveApiKey(ctx, model.ApiKey{
+		UserID:          tokenPayload.UserID,
+		Name:            strings.TrimSpace(apiKeyReq.Name),
+		KeyID:           keyID,
+		SecretEncrypted: secretEncrypted,
+		Scopes:          scopes,
+		IPAllowlist:     allowlist,
+	})
+	if err != nil {
+		return model.ApiKeyCreated{}, serverError.ErrGeneralDatabaseError(err)
+	}
+
+	return model.ApiKeyCreated{ApiKey: apiKey, Secret: keySecret}, nil
+}
+
+func (u *apiKeyUsecase) GetApiKeys(ctx context.Context) ([]model.ApiKey, error) {
+	tokenPayload := jwt.GetPayloadFromContext(ctx)
+
+	apiKeys, err := u.apiKeyRepository.GetUserApiKeys(ctx, tokenPayload.UserID)
+	if err != nil {
+		return nil, serverError.ErrGeneralDatabaseError(err)
+	}
+
+	return apiKeys, nil
+}
+
+func (u *apiKeyUsecase) RevokeApiKey(ctx context.Context, id int) (model.ApiKey, error) {
+	tokenPayload := jwt.GetPayloadFromContext(ctx)
+
+	apiKey, err := u.apiKeyRepository.RevokeApiKey(ctx, tokenPayload.UserID, id)
+	if err != nil {
+		return model.ApiKey{}, err
+	}
+
+	return apiKey, nil
+}
+
+// Authenticate verifies a signed request and returns the payload of the key owner. The role is always
+// USER, an API key never carries the admin privilege of its owner.
+func (u *apiKeyUsecase) Authenticate(ctx context.Context, request apikey.Request) (jwt.Payload, error) {
+	now := time.Now()
+
+	if !request.WithinWindow(now, u.securityConfig.ApiKey.RecvWindow) {
+		return jwt.Payload{}, serverError.ErrUnauthorized(model.ErrApiKeyExpiredRequest)
+	}
+
+	apiKey, err := u.apiKeyRepository.GetApiKeyByKeyID(ctx, request.KeyID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return jwt.Payload{}, serverError.ErrUnauthorized(model.ErrApiKeyNotFound)
+	}
+	if err != nil {
+		return jwt.Payload{}, serverError.ErrGeneralDatabaseError(err)
+	}
+
+	keySecret, err := secret.Decrypt(u.securityConfig.ApiKey.EncryptionKey, apiKey.SecretEncrypted)
+	if err != nil {
+		return jwt.Payload{}, serverError.ErrGeneralError(err)
+	}
+
+	if !request.Verify(keySecret) {
+		return jwt.Payload{}, serverError.ErrUnauthorized(model.ErrApiKeySignature)
+	}
+
+	if !apiKey.AllowsIP(request.ClientIP) {
+		return jwt.Payload{}, serverError.ErrUnauthorized(model.ErrApiKeyIPNotAllowed)
+	}
+
+	user, err := u.userRepository.FindUserByID(ctx, apiKey.UserID)
+	if err != nil {
+		return jwt.Payload{}, serverError.ErrGeneralDatabaseError(err)
+	}
+
+	if !user.Status {
+		return jwt.Payload{}, serverError.ErrUserBlocked(nil)
+	}
+
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) > apiKeyTouchInterval {
+		if err := u.apiKeyRepository.TouchApiKey(ctx, apiKey.ID, now); err != nil {
+			log.Context(ctx).Error(err) // Only informational, the request goes on
+		}
+	}
+
+	return jwt.Payload{
+		ID:     apiKey.KeyID,
+		UserID: user.ID,
+		Email:  user.Email,
+		Exp:    now.Add(u.securityConfig.ApiKey.RecvWindow).Unix(),
+		Role:   model.UserRoleUser,
+		Scopes: apiKey.Scopes,
+	}, nil
+}
+
+// parseApiKeyScopes returns the scopes as a sorted, comma separated list without duplicate
+func parseApiKeyScopes(scopes []string) (string, error) {
+	requested := make(map[string]bool)
+	for _, scope := range scopes {
+		scope = strings.ToUpper(strings.TrimSpace(scope))
+		switch scope {
+		case model.ApiKeyScopeRead, model.ApiKeyScopeTrade, model.ApiKeyScopeWithdraw:
+			requested[scope] = true
+		default:
+			return "", model.ErrInvalidApiKeyScope
+		}
+	}
+
+	var result []string
+	for _, scope := range []string{model.ApiKeyScopeRead, model.ApiKeyScopeTrade, model.ApiKeyScopeWithdraw} {
+		if requested[scope] {
+			result = append(result, scope)
+		}
+	}
+
+	if len(result) == 0 {
+		return "", model.ErrInvalidApiKeyScope
+	}
+
+	return strings.Join(result, ","), nil
+}
+
+func parseIPAllowlist(entries []string) (string, error) {
+	result := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if _, _, err := net.ParseCIDR(entry); err != nil && net.ParseIP(entry) == nil {
+			return "", model.ErrInvalidIPAllowlist
+		}
+		result = append(result, entry)
+	}
+
+	return strings.Join(result, ","), nil
+}
@@ -0,0 +1,241 @@
+package usecase
+
+import (
+	"context"
+	"sort"
+
+	"github.com/gerins/log"
+
+	"core-engine/internal/app/domains/dto"
+	"core-engine/internal/app/domains/model"
+	gormpkg "core-engine/pkg/gorm"
+)
+
+type walletKey struct {
+	UserID   int
+	CryptoID int
+}
+
+type feeScheduleKey struct {
+	PairID int
+	UserID int
+}
+
+// MatchOrders settles a batch of trades in a single transaction.
+//
+// Instead of a redis lock per trade, every order row of the batch is locked in ID order, then the net
+// change of every wallet is applied once in user and crypto order. Both orders are deterministic, so
+// batches of different partitions can not deadlock on each other. Every trade still gets its own match
+// order and ledger journal. Trades already settled, or repeated in the batch, are skipped.
+func (u *orderUsecase) MatchOrders(ctx context.Context, tradeReqs dto.BulkTradeRequest) error {
+	defer log.Context(ctx).RecordDuration("MatchOrders").Stop()
+
+	ctx, tx := gormpkg.InitTransactionToContext(ctx, u.writeDB)
+	defer tx.WithContext(ctx).Rollback()
+
+	trades, err := u.unsettledTrades(ctx, tradeReqs)
+	if err != nil {
+		return err
+	}
+
+	if len(trades) == 0 {
+		return tx.WithContext(ctx).Commit().Error
+	}
+
+	// Lock every order of the batch, the rows are locked in ID order to prevent deadlock
+	orderIDs := make([]int, 0, len(trades)*2)
+	for _, tradeReq := range trades {
+		orderIDs = append(orderIDs, tradeReq.TakerOrderID, tradeReq.MakerOrderID)
+	}
+	orderIDs = uniqueSortedInts(orderIDs)
+
+	orderList, err := u.orderRepository.GetOrdersForUpdate(ctx, orderIDs)
+	if err != nil {
+		return err
+	}
+
+	orders := make(map[int]model.Order, len(orderList))
+	for _, order := range orderList {
+		orders[order.ID] = order
+	}
+
+	var (
+		pairs       = make(map[int]model.Pair)
+		schedules   = make(map[feeScheduleKey]model.FeeSchedule)
+		changes     = make(map[walletKey]*model.BalanceChange)
+		matchOrders = make([]model.MatchOrder, 0, len(trades))
+		journals    = make([]*model.LedgerJournal, 0, len(trades))
+	)
+
+	for _, tradeReq := range trades {
+		pair, err := u.batchPair(ctx, pairs, tradeReq.PairID)
+		if err != nil {
+			return err
+		}
+
+		// Later trade of the same order sees the fill of the earlier one
+		takerOrder, makerOrder := orders[tradeReq.TakerOrderID], orders[tradeReq.MakerOrderID]
+		takerOrder.Fill(tradeReq.Quantity, tradeReq.Price)
+		makerOrder.Fill(tradeReq.Quantity, tradeReq.Price)
+		orders[takerOrder.ID], orders[makerOrder.ID] = takerOrder, makerOrder
+
+		takerSchedule, err := u.batchFeeSchedule(ctx, schedules, tradeReq.PairID, takerOrder.UserID)
+		if err != nil {
+			return err
+		}
+
+		makerSchedule, err := u.batchFeeSchedule(ctx, schedules, tradeReq.PairID, makerOrder.UserID)
+		if err != nil {
+			return err
+		}
+
+		result := settleTrade(tradeReq, pair, takerOrder, makerOrder, takerSchedule, makerSchedule, u.feeConfig.AccountUserID)
+
+		journal := model.NewLedgerJournal(model.LedgerEntryFill, model.LedgerReferenceTrade)
+		for _, movement := range result.Movements {
+			available, locked := movement.balanceChange()
+
+			journal.EntryType = movement.ledgerEntryType(u.feeConfig.AccountUserID)
+			journal.PostBalanceChange(movement.UserID, movement.CryptoID, available, locked)
+
+			key := walletKey{UserID: movement.UserID, CryptoID: movement.CryptoID}
+			if changes[key] == nil {
+				changes[key] = &model.BalanceChange{UserID: movement.UserID, CryptoID: movement.CryptoID}
+			}
+			changes[key].Available += available
+			changes[key].Locked += locked
+		}
+
+		journals = append(journals, journal)
+		matchOrders = append(matchOrders, model.MatchOrder{
+			PairID:           tradeReq.PairID,
+			TakerOrderID:     tradeReq.TakerOrderID,
+			MakerOrderID:     tradeReq.MakerOrderID,
+			Quantity:         tradeReq.Quantity,
+			Price:            tradeReq.Price,
+			TakerFee:         result.TakerFee,
+			TakerFeeCryptoID: result.TakerFeeCryptoID,
+			MakerFee:         result.MakerFee,
+			MakerFeeCryptoID: result.MakerFeeCryptoID,
+			TradeSequence:    tradeReq.Sequence,
+			TransactionTime:  tradeReq.TradeTime,
+		})
+	}
+
+	// Apply the net change of every wallet in deterministic order
+	keys := make([]walletKey, 0, len(changes))
+	for key := range changes {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].UserID != keys[j].UserID {
+			return keys[i].UserID < keys[j].UserID
+		}
+		return keys[i].CryptoID < keys[j].CryptoID
+	})
+
+	for _, key := range keys {
+		if change := changes[key]; change.Available != 0 || change.Locked != 0 {
+			if err := applyBalanceChange(ctx, u.walletRepository, *change); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, id := range orderIDs {
+		if _, err := u.orderRepository.SaveOrder(ctx, orders[id]); err != nil {
+			return err
+		}
+	}
+
+	for i, matchOrder := range matchOrders {
+		// Settled concurrently by another consumer, the whole batch is retried and skip it next time
+		matchOrder, err := u.orderRepository.SaveMatchOrder(ctx, matchOrder)
+		if err != nil {
+			return err
+		}
+		journals[i].ReferenceID = matchOrder.ID
+	}
+
+	if err := u.ledgerRepository.SaveJournals(ctx, journals); err != nil {
+		return err
+	}
+
+	return tx.WithContext(ctx).Commit().Error
+}
+
+// unsettledTrades returns the trades of the batch not settled yet, in the same order
+func (u *orderUsecase) unsettledTrades(ctx context.Context, tradeReqs dto.BulkTradeRequest) (dto.BulkTradeRequest, error) {
+	sequences := make(map[int][]int64)
+	for _, tradeReq := range tradeReqs {
+		if tradeReq.Sequence != 0 {
+			sequences[tradeReq.PairID] = append(sequences[tradeReq.PairID], tradeReq.Sequence)
+		}
+	}
+
+	settled := make(map[int]map[int64]bool, len(sequences))
+	for pairID, pairSequences := range sequences {
+		pairSettled, err := u.orderRepository.GetSettledTradeSequences(ctx, pairID, pairSequences)
+		if err != nil {
+			return nil, err
+		}
+		settled[pairID] = pairSettled
+	}
+
+	trades := make(dto.BulkTradeRequest, 0, len(tradeReqs))
+	for _, tradeReq := range tradeReqs {
+		if tradeReq.Sequence != 0 {
+			if settled[tradeReq.PairID][tradeReq.Sequence] {
+				log.Context(ctx).Infof("trade %v of pair %v already settled", tradeReq.Sequence, tradeReq.PairID)
+				continue
+			}
+			settled[tradeReq.PairID][tradeReq.Sequence] = true // Redelivered within the same batch
+		}
+
+		trades = append(trades, tradeReq)
+	}
+
+	return trades, nil
+}
+
+func (u *orderUsecase) batchPair(ctx context.Context, pairs map[int]model.Pair, pairID int) (model.Pair, error) {
+	if pair, ok := pairs[pairID]; ok {
+		return pair, nil
+	}
+
+	pair, err := u.walletRepository.GetPairDetailByID(ctx, pairID)
+	if err != nil {
+		return model.Pair{}, err
+	}
+
+	pairs[pairID] = pair
+	return pair, nil
+}
+
+func (u *orderUsecase) batchFeeSchedule(ctx context.Context, schedules map[feeScheduleKey]model.FeeSchedule, pairID, userID int) (model.FeeSchedule, error) {
+	key := feeScheduleKey{PairID: pairID, UserID: userID}
+	if schedule, ok := schedules[key]; ok {
+		return schedule, nil
+	}
+
+	schedule, err := u.feeRepository.GetUserFeeSchedule(ctx, pairID, userID)
+	if err != nil {
+		return model.FeeSchedule{}, err
+	}
+
+	schedules[key] = schedule
+	return schedule, nil
+}
+
+func uniqueSortedInts(values []int) []int {
+	sort.Ints(values)
+
+	result := values[:0]
+	for i, value := range values {
+		if i == 0 || value != values[i-1] {
+			result = append(result, value)
+		}
+	}
+
+	return result
+}
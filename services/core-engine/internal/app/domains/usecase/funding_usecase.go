@@ -0,0 +1,432 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/gerins/log"
+	"gorm.io/gorm"
+
+	"core-engine/config"
+	"core-engine/internal/app/domains/dto"
+	"core-engine/internal/app/domains/model"
+	"core-engine/pkg/chain"
+	serverError "core-engine/pkg/error"
+	gormpkg "core-engine/pkg/gorm"
+	"core-engine/pkg/jwt"
+)
+
+const (
+	chainSyncBatchSize     = 100  // Deposits and withdrawals moved forward by one chain sync, the rest waits for the next one
+	depositAmountTolerance = 1e-9 // Float residue between the recorded and the received amount
+)
+
+type fundingUsecase struct {
+	writeDB           *gorm.DB
+	chainAdapter      chain.Adapter
+	fundingRepository model.FundingRepository
+	userRepository    model.UserRepository
+	walletRepository  model.WalletRepository
+	ledgerRepository  model.LedgerRepository
+	twoFactorUsecase  model.TwoFactorUsecase
+	chainConfig       config.Chain
+}
+
+// NewFundingUsecase returns new deposit and withdrawal usecase.
+func NewFundingUsecase(
+	writeDB *gorm.DB,
+	chainAdapter chain.Adapter,
+	fundingRepository model.FundingRepository,
+	userRepository model.UserRepository,
+	walletRepository model.WalletRepository,
+	ledgerRepository model.LedgerRepository,
+	twoFactorUsecase model.TwoFactorUsecase,
+	chainConfig config.Chain,
+) *fundingUsecase {
+	return &fundingUsecase{
+		writeDB:           writeDB,
+		chainAdapter:      chainAdapter,
+		fundingRepository: fundingRepository,
+		userRepository:    userRepository,
+		walletRepository:  walletRepository,
+		ledgerRepository:  ledgerRepository,
+		twoFactorUsecase:  twoFactorUsecase,
+		chainConfig:       chainConfig,
+	}
+}
+
+// GetDeposits returns the deposits of the logged in user, newest first
+func (u *fundingUsecase) GetDeposits(ctx context.Context, listReq dto.FundingListRequest) ([]model.Deposit, int, error) {
+	tokenPayload := jwt.GetPayloadFromContext(ctx)
+
+	deposits, total, err := u.fundingRepository.GetUserDeposits(ctx, tokenPayload.UserID, listReq.Page, listReq.Limit)
+	if err != nil {
+		return nil, 0, serverError.ErrGeneralDatabaseError(err)
+	}
+
+	return deposits, total, nil
+}
+
+// GetWithdrawals returns the withdrawals of the logged in user, newest first
+func (u *fundingUsecase) GetWithdrawals(ctx context.Context, listReq dto.FundingListRequest) ([]model.Withdrawal, int, error) {
+	tokenPayload := jwt.GetPayloadFromContext(ctx)
+
+	withdrawals, total, err := u.fundingRepository.GetUserWithdrawals(ctx, tokenPayload.UserID, listReq.Page, listReq.Limit)
+	if err != nil {
+		return nil, 0, serverError.ErrGeneralDatabaseError(err)
+	}
+
+	return withdrawals, total, nil
+}
+
+// RecordDeposit registers a deposit seen on the chain, the amount is held in the pending account until it has enough confirmations
+func (u *fundingUsecase) RecordDeposit(ctx context.Context, depositReq dto.DepositRequest) (model.Deposit, error) {
+	depositReq.TxHash = strings.TrimSpace(depositReq.TxHash)
+	depositReq.Address = strings.TrimSpace(depositReq.Address)
+	if depositReq.UserID <= 0 || depositReq.Amount <= 0 || depositReq.TxHash == "" || depositReq.Address == "" {
+		return model.Deposit{}, serverError.ErrInvalidRequest(nil)
+	}
+
+	if _, err := u.walletRepository.GetCrypto(ctx, depositReq.CryptoID); err != nil {
+		return model.Deposit{}, serverError.ErrInvalidRequest(err)
+	}
+
+	// The same chain transaction is recorded once, the unique index reject concurrent duplicate
+	existing, err := u.fundingRepository.GetDepositByTxHash(ctx, depositReq.CryptoID, depositReq.TxHash)
+	if err == nil {
+		return existing, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return model.Deposit{}, serverError.ErrGeneralDatabaseError(err)
+	}
+
+	ctx, tx := gormpkg.InitTransactionToContext(ctx, u.writeDB)
+	defer tx.WithContext(ctx).Rollback()
+
+	deposit, err := u.fundingRepository.SaveDeposit(ctx, model.Deposit{
+		UserID:   depositReq.UserID,
+		CryptoID: depositReq.CryptoID,
+		Amount:   depositReq.Amount,
+		Address:  depositReq.Address,
+		TxHash:   depositReq.TxHash,
+		Status:   model.DepositStatusPending,
+	})
+	if err != nil {
+		return model.Deposit{}, serverError.ErrGeneralDatabaseError(err)
+	}
+
+	journal := model.NewLedgerJournal(model.LedgerEntryDeposit, model.LedgerReferenceDeposit)
+	journal.ReferenceID = deposit.ID
+	journal.Post(deposit.UserID, deposit.CryptoID, model.LedgerAccountPending, deposit.Amount)
+	journal.PostExternal(deposit.CryptoID, deposit.Amount)
+
+	if err := u.ledgerRepository.SaveJournal(ctx, journal); err != nil {
+		return model.Deposit{}, serverError.ErrGeneralDatabaseError(err)
+	}
+
+	if err := tx.WithContext(ctx).Commit().Error; err != nil {
+		return model.Deposit{}, serverError.ErrGeneralDatabaseError(err)
+	}
+
+	return deposit, nil
+}
+
+// RequestWithdrawal locks the amount from the available balance of the logged in user until the withdrawal completes or fails
+func (u *fundingUsecase) RequestWithdrawal(ctx context.Context, withdrawalReq dto.WithdrawalRequest) (model.Withdrawal, error) {
+	tokenPayload := jwt.GetPayloadFromContext(ctx)
+
+	withdrawalReq.Address = strings.TrimSpace(withdrawalReq.Address)
+	if withdrawalReq.Amount <= 0 || withdrawalReq.Address == "" {
+		return model.Withdrawal{}, serverError.ErrInvalidRequest(nil)
+	}
+
+	if _, err := u.walletRepository.GetCrypto(ctx, withdrawalReq.CryptoID); err != nil {
+		return model.Withdrawal{}, serverError.ErrInvalidRequest(err)
+	}
+
+	userDetail, err := u.userRepository.FindUserByID(ctx, tokenPayload.UserID)
+	if err != nil {
+		return model.Withdrawal{}, serverError.ErrGeneralDatabaseError(err)
+	}
+
+	if !userDetail.Status {
+		return model.Withdrawal{}, serverError.ErrUserBlocked(nil) // Frozen account can not move funds out
+	}
+
+	// A request signed by an API key has no code to give, the WITHDRAW scope already needed a code and an IP allowlist
+	if tokenPayload.Scopes == "" {
+		if err := u.twoFactorUsecase.Verify(ctx, userDetail.ID, withdrawalReq.TotpCode); err != nil {
+			return model.Withdrawal{}, err
+		}
+	}
+
+	ctx, tx := gormpkg.InitTransactionToContext(ctx, u.writeDB)
+	defer tx.WithContext(ctx).Rollback()
+
+	withdrawal, err := u.fundingRepository.SaveWithdrawal(ctx, model.Withdrawal{
+		UserID:   tokenPayload.UserID,
+		CryptoID: withdrawalReq.CryptoID,
+		Amount:   withdrawalReq.Amount,
+		Address:  withdrawalReq.Address,
+		Status:   model.WithdrawalStatusRequested,
+	})
+	if err != nil {
+		return model.Withdrawal{}, serverError.ErrGeneralDatabaseError(err)
+	}
+
+	journal := model.NewLedgerJournal(model.LedgerEntryLock, model.LedgerReferenceWithdrawal)
+	journal.ReferenceID = withdrawal.ID
+	ctx = model.SaveLedgerJournalToContext(ctx, journal)
+
+	if err := u.walletRepository.LockBalance(ctx, withdrawal.UserID, withdrawal.CryptoID, withdrawal.Amount); err != nil {
+		if errors.Is(err, model.ErrInsufficientBalance) {
+			return model.Withdrawal{}, serverError.ErrInsufficientBalance(err)
+		}
+		return model.Withdrawal{}, serverError.ErrGeneralDatabaseError(err)
+	}
+
+	if err := u.ledgerRepository.SaveJournal(ctx, journal); err != nil {
+		return model.Withdrawal{}, serverError.ErrGeneralDatabaseError(err)
+	}
+
+	if err := tx.WithContext(ctx).Commit().Error; err != nil {
+		return model.Withdrawal{}, serverError.ErrGeneralDatabaseError(err)
+	}
+
+	return withdrawal, nil
+}
+
+// ApproveWithdrawal lets the withdrawal be broadcast on the next chain sync
+func (u *fundingUsecase) ApproveWithdrawal(ctx context.Context, id int) (model.Withdrawal, error) {
+	tokenPayload := jwt.GetPayloadFromContext(ctx)
+
+	return u.transitWithdrawal(ctx, id, model.WithdrawalStatusApproved, func(ctx context.Context, withdrawal *model.Withdrawal) error {
+		withdrawal.ApprovedBy = tokenPayload.UserID
+		return nil
+	})
+}
+
+// RejectWithdrawal fails the withdrawal and refunds the locked amount
+func (u *fundingUsecase) RejectWithdrawal(ctx context.Context, id int, reason string) (model.Withdrawal, error) {
+	tokenPayload := jwt.GetPayloadFromContext(ctx)
+
+	return u.transitWithdrawal(ctx, id, model.WithdrawalStatusFailed, func(ctx context.Context, withdrawal *model.Withdrawal) error {
+		withdrawal.ApprovedBy = tokenPayload.UserID
+		withdrawal.FailureReason = fmt.Sprintf("rejected: %v", strings.TrimSpace(reason))
+		return u.refundWithdrawal(ctx, withdrawal)
+	})
+}
+
+// SyncChain moves every open deposit and withdrawal forward with the state of its chain transaction.
+// A failing item is logged and retried on the next sync, it does not block the others.
+func (u *fundingUsecase) SyncChain(ctx context.Context) error {
+	deposits, err := u.fundingRepository.GetDepositsByStatus(ctx, []model.DepositStatus{model.DepositStatusPending, model.DepositStatusConfirming}, chainSyncBatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, deposit := range deposits {
+		if err := u.syncDeposit(ctx, deposit); err != nil {
+			log.Context(ctx).Errorf("failed syncing deposit %v, %v", deposit.ID, err)
+		}
+	}
+
+	withdrawals, err := u.fundingRepository.GetWithdrawalsByStatus(ctx, []model.WithdrawalStatus{model.WithdrawalStatusApproved, model.WithdrawalStatusBroadcast}, chainSyncBatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, withdrawal := range withdrawals {
+		if err := u.syncWithdrawal(ctx, withdrawal); err != nil {
+			log.Context(ctx).Errorf("failed syncing withdrawal %v, %v", withdrawal.ID, err)
+		}
+	}
+
+	return nil
+}
+
+func (u *fundingUsecase) syncDeposit(ctx context.Context, deposit model.Deposit) error {
+	crypto, err := u.walletRepository.GetCrypto(ctx, deposit.CryptoID)
+	if err != nil {
+		return err
+	}
+
+	transaction, err := u.chainAdapter.Transaction(ctx, crypto.Symbol, deposit.TxHash)
+	if err != nil {
+		return err
+	}
+
+	if transaction.Failed {
+		// Deposit has no failed state, the pending amount stays out of the wallet for investigation
+		log.Context(ctx).Warnf("deposit %v transaction %v failed on the chain", deposit.ID, deposit.TxHash)
+		return nil
+	}
+
+	// The recorded deposit is only trusted as far as the chain agrees, a mismatch stays pending for investigation
+	if !depositMatches(deposit, transaction) {
+		log.Context(ctx).Warnf("deposit %v of %v to %v does not match transaction %v of %v to %v on the chain",
+			deposit.ID, deposit.Amount, deposit.Address, deposit.TxHash, transaction.Amount, transaction.Address)
+		return nil
+	}
+
+	if transaction.Confirmations < u.chainConfig.RequiredConfirmations {
+		if transaction.Confirmations == 0 || deposit.Status == model.DepositStatusConfirming {
+			return nil
+		}
+
+		_, err := u.transitDeposit(ctx, deposit.ID, model.DepositStatusConfirming, func(ctx context.Context, deposit *model.Deposit) error {
+			deposit.Confirmations = transaction.Confirmations
+			return nil
+		})
+		return err
+	}
+
+	_, err = u.transitDeposit(ctx, deposit.ID, model.DepositStatusCredited, func(ctx context.Context, deposit *model.Deposit) error {
+		deposit.Confirmations = transaction.Confirmations
+		return u.creditDeposit(ctx, deposit)
+	})
+	return err
+}
+
+// depositMatches tells whether the chain transaction sent the recorded amount to the recorded address
+func depositMatches(deposit model.Deposit, transaction chain.Transaction) bool {
+	return transaction.Address == deposit.Address && math.Abs(transaction.Amount-deposit.Amount) <= depositAmountTolerance
+}
+
+// Move the deposit from the pending account to the available balance
+func (u *fundingUsecase) creditDeposit(ctx context.Context, deposit *model.Deposit) error {
+	model.GetLedgerJournalFromContext(ctx).Post(deposit.UserID, deposit.CryptoID, model.LedgerAccountPending, -deposit.Amount)
+	return creditWallet(ctx, u.walletRepository, deposit.UserID, deposit.CryptoID, deposit.Amount)
+}
+
+func (u *fundingUsecase) syncWithdrawal(ctx context.Context, withdrawal model.Withdrawal) error {
+	crypto, err := u.walletRepository.GetCrypto(ctx, withdrawal.CryptoID)
+	if err != nil {
+		return err
+	}
+
+	if withdrawal.Status == model.WithdrawalStatusApproved {
+		// Broadcast is idempotent by transfer ID, a crash before saving the hash sends the same transaction again
+		hash, err := u.chainAdapter.Broadcast(ctx, chain.Transfer{
+			ID:      fmt.Sprintf("withdrawal-%v", withdrawal.ID),
+			Symbol:  crypto.Symbol,
+			Address: withdrawal.Address,
+			Amount:  withdrawal.Amount,
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = u.transitWithdrawal(ctx, withdrawal.ID, model.WithdrawalStatusBroadcast, func(ctx context.Context, withdrawal *model.Withdrawal) error {
+			withdrawal.TxHash = hash
+			return nil
+		})
+		return err
+	}
+
+	transaction, err := u.chainAdapter.Transaction(ctx, crypto.Symbol, withdrawal.TxHash)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case transaction.Failed:
+		_, err = u.transitWithdrawal(ctx, withdrawal.ID, model.WithdrawalStatusFailed, func(ctx context.Context, withdrawal *model.Withdrawal) error {
+			withdrawal.FailureReason = "transaction failed on the chain"
+			return u.refundWithdrawal(ctx, withdrawal)
+		})
+
+	case transaction.Confirmations >= u.chainConfig.RequiredConfirmations:
+		_, err = u.transitWithdrawal(ctx, withdrawal.ID, model.WithdrawalStatusCompleted, func(ctx context.Context, withdrawal *model.Withdrawal) error {
+			model.GetLedgerJournalFromContext(ctx).PostExternal(withdrawal.CryptoID, -withdrawal.Amount)
+
+			return u.walletRepository.ConsumeLockedBalance(ctx, withdrawal.UserID, withdrawal.CryptoID, withdrawal.Amount)
+		})
+	}
+
+	return err
+}
+
+func (u *fundingUsecase) refundWithdrawal(ctx context.Context, withdrawal *model.Withdrawal) error {
+	model.GetLedgerJournalFromContext(ctx).EntryType = model.LedgerEntryRefund
+	return u.walletRepository.UnlockBalance(ctx, withdrawal.UserID, withdrawal.CryptoID, withdrawal.Amount)
+}
+
+// transitDeposit moves a locked deposit row to the next status, apply runs in the same transaction and posts its balance movement to the journal of the context
+func (u *fundingUsecase) transitDeposit(ctx context.Context, id int, status model.DepositStatus, apply func(ctx context.Context, deposit *model.Deposit) error) (model.Deposit, error) {
+	ctx, tx := gormpkg.InitTransactionToContext(ctx, u.writeDB)
+	defer tx.WithContext(ctx).Rollback()
+
+	deposit, err := u.fundingRepository.GetDepositForUpdate(ctx, id)
+	if err != nil {
+		return model.Deposit{}, err
+	}
+
+	if !deposit.Status.CanTransitionTo(status) {
+		return model.Deposit{}, serverError.ErrInvalidStateTransition(fmt.Errorf("%w, deposit %v from %v to %v", model.ErrInvalidTransition, id, deposit.Status, status))
+	}
+
+	journal := model.NewLedgerJournal(model.LedgerEntryDeposit, model.LedgerReferenceDeposit)
+	journal.ReferenceID = deposit.ID
+	ctx = model.SaveLedgerJournalToContext(ctx, journal)
+
+	deposit.Status = status
+	if err := apply(ctx, &deposit); err != nil {
+		return model.Deposit{}, err
+	}
+
+	if deposit, err = u.fundingRepository.SaveDeposit(ctx, deposit); err != nil {
+		return model.Deposit{}, err
+	}
+
+	if err := u.ledgerRepository.SaveJournal(ctx, journal); err != nil {
+		return model.Deposit{}, err
+	}
+
+	if err := tx.WithContext(ctx).Commit().Error; err != nil {
+		return model.Deposit{}, err
+	}
+
+	return deposit, nil
+}
+
+// transitWithdrawal moves a locked withdrawal row to the next status, apply runs in the same transaction and posts its balance movement to the journal of the context
+func (u *fundingUsecase) transitWithdrawal(ctx context.Context, id int, status model.WithdrawalStatus, apply func(ctx context.Context, withdrawal *model.Withdrawal) error) (model.Withdrawal, error) {
+	ctx, tx := gormpkg.InitTransactionToContext(ctx, u.writeDB)
+	defer tx.WithContext(ctx).Rollback()
+
+	withdrawal, err := u.fundingRepository.GetWithdrawalForUpdate(ctx, id)
+	if err != nil {
+		return model.Withdrawal{}, err
+	}
+
+	if !withdrawal.Status.CanTransitionTo(status) {
+		return model.Withdrawal{}, serverError.ErrInvalidStateTransition(fmt.Errorf("%w, withdrawal %v from %v to %v", model.ErrInvalidTransition, id, withdrawal.Status, status))
+	}
+
+	journal := model.NewLedgerJournal(model.LedgerEntryWithdrawal, model.LedgerReferenceWithdrawal)
+	journal.ReferenceID = withdrawal.ID
+	ctx = model.SaveLedgerJournalToContext(ctx, journal)
+
+	withdrawal.Status = status
+	if err := apply(ctx, &withdrawal); err != nil {
+		return model.Withdrawal{}, err
+	}
+
+	if withdrawal, err = u.fundingRepository.SaveWithdrawal(ctx, withdrawal); err != nil {
+		return model.Withdrawal{}, err
+	}
+
+	if err := u.ledgerRepository.SaveJournal(ctx, journal); err != nil {
+		return model.Withdrawal{}, err
+	}
+
+	if err := tx.WithContext(ctx).Commit().Error; err != nil {
+		return model.Withdrawal{}, err
+	}
+
+	return withdrawal, nil
+}
@@ -0,0 +1,32 @@
+package usecase
+
+import (
+	"testing"
+
+	"core-engine/internal/app/domains/model"
+	"core-engine/pkg/chain"
+)
+
+func TestDepositMatches(t *testing.T) {
+	deposit := model.Deposit{Amount: 0.3, Address: "exchange-addr"}
+
+	tests := []struct {
+		name        string
+		transaction chain.Transaction
+		want        bool
+	}{
+		{"same amount and address", chain.Transaction{Amount: 0.3, Address: "exchange-addr"}, true},
+		{"float residue", chain.Transaction{Amount: 0.1 + 0.2, Address: "exchange-addr"}, true},
+		{"less received than recorded", chain.Transaction{Amount: 0.03, Address: "exchange-addr"}, false},
+		{"sent to another address", chain.Transaction{Amount: 0.3, Address: "other-addr"}, false},
+		{"unknown transfer", chain.Transaction{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := depositMatches(deposit, tt.transaction); got != tt.want {
+				t.Errorf("depositMatches(%+v) = %v, want %v", tt.transaction, got, tt.want)
+			}
+		})
+	}
+}
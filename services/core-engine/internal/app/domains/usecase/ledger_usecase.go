@@ -0,0 +1,49 @@
+package usecase
+
+import (
+	"context"
+
+	"core-engine/internal/app/domains/dto"
+	"core-engine/internal/app/domains/model"
+	serverError "core-engine/pkg/error"
+	"core-engine/pkg/jwt"
+)
+
+type ledgerUsecase struct {
+	ledgerRepository model.LedgerRepository
+}
+
+// NewLedgerUsecase returns new ledger usecase.
+func NewLedgerUsecase(ledgerRepository model.LedgerRepository) *ledgerUsecase {
+	return &ledgerUsecase{
+		ledgerRepository: ledgerRepository,
+	}
+}
+
+// GetLedger returns the ledger history of the logged in user, newest first
+func (u *ledgerUsecase) GetLedger(ctx context.Context, ledgerReq dto.LedgerRequest) ([]model.LedgerEntry, int, error) {
+	tokenPayload := jwt.GetPayloadFromContext(ctx)
+
+	entries, total, err := u.ledgerRepository.GetUserLedger(ctx, tokenPayload.UserID, ledgerReq)
+	if err != nil {
+		return nil, 0, serverError.ErrGeneralDatabaseError(err)
+	}
+
+	return entries, total, nil
+}
+
+// GetBalanceProjection compares every wallet of the logged in user with the balance rebuilt from its ledger
+func (u *ledgerUsecase) GetBalanceProjection(ctx context.Context) ([]model.LedgerBalance, error) {
+	tokenPayload := jwt.GetPayloadFromContext(ctx)
+
+	balances, err := u.ledgerRepository.GetUserBalanceProjection(ctx, tokenPayload.UserID)
+	if err != nil {
+		return nil, serverError.ErrGeneralDatabaseError(err)
+	}
+
+	for i := range balances {
+		balances[i].Verify()
+	}
+
+	return balances, nil
+}
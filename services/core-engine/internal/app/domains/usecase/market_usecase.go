@@ -0,0 +1,296 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/gerins/log"
+	"gorm.io/gorm"
+
+	"core-engine/config"
+	"core-engine/internal/app/domains/dto"
+	"core-engine/internal/app/domains/model"
+	serverError "core-engine/pkg/error"
+	gormpkg "core-engine/pkg/gorm"
+	"core-engine/pkg/jwt"
+	"core-engine/pkg/kafka"
+)
+
+const pairTopicPartitions = 1 // Every order of a pair is matched in the order it was published
+
+type marketUsecase struct {
+	writeDB          *gorm.DB
+	kafkaProducer    kafka.Producer
+	topicManager     kafka.TopicManager
+	marketRepository model.MarketRepository
+	walletRepository model.WalletRepository
+	brokerConfig     config.MessageBroker
+	feeConfig        config.Fee
+}
+
+// NewMarketUsecase returns new market usecase.
+func NewMarketUsecase(
+	writeDB *gorm.DB,
+	kafkaProducer kafka.Producer,
+	topicManager kafka.TopicManager,
+	marketRepository model.MarketRepository,
+	walletRepository model.WalletRepository,
+	brokerConfig config.MessageBroker,
+	feeConfig config.Fee,
+) *marketUsecase {
+	return &marketUsecase{
+		writeDB:          writeDB,
+		kafkaProducer:    kafkaProducer,
+		topicManager:     topicManager,
+		marketRepository: marketRepository,
+		walletRepository: walletRepository,
+		brokerConfig:     brokerConfig,
+		feeConfig:        feeConfig,
+	}
+}
+
+func (u *marketUsecase) CreateCrypto(ctx context.Context, cryptoReq dto.CryptoRequest) (model.Crypto, error) {
+	crypto := model.Crypto{
+		Symbol:             strings.ToUpper(strings.TrimSpace(cryptoReq.Symbol)),
+		Precision:          cryptoReq.Precision,
+		Status:             cryptoReq.Status == nil || *cryptoReq.Status,
+		TransferDailyLimit: cryptoReq.TransferDailyLimit,
+	}
+
+	if err := crypto.Validate(); err != nil {
+		return model.Crypto{}, serverError.ErrInvalidRequest(err)
+	}
+
+	if err := u.checkSymbolUnused(ctx, crypto.Symbol); err != nil {
+		return model.Crypto{}, err
+	}
+
+	ctx, tx := gormpkg.InitTransactionToContext(ctx, u.writeDB)
+	defer tx.WithContext(ctx).Rollback()
+
+	crypto, err := u.marketRepository.SaveCrypto(ctx, crypto)
+	if err != nil {
+		return model.Crypto{}, serverError.ErrGeneralDatabaseError(err)
+	}
+
+	// The fee account receives the fee of every trade in the new crypto and pays its maker rebate
+	if err := u.walletRepository.Save(ctx, model.Wallet{UserID: u.feeConfig.AccountUserID, CryptoID: crypto.ID}); err != nil {
+		return model.Crypto{}, serverError.ErrGeneralDatabaseError(err)
+	}
+
+	if err := tx.WithContext(ctx).Commit().Error; err != nil {
+		return model.Crypto{}, serverError.ErrGeneralDatabaseError(err)
+	}
+
+	log.Context(ctx).Infof("admin %v listed crypto %v", jwt.GetPayloadFromContext(ctx).UserID, crypto.Symbol)
+	return crypto, nil
+}
+
+func (u *marketUsecase) UpdateCrypto(ctx context.Context, id int, cryptoReq dto.CryptoUpdateRequest) (model.Crypto, error) {
+	ctx, tx := gormpkg.InitTransactionToContext(ctx, u.writeDB)
+	defer tx.WithContext(ctx).Rollback()
+
+	crypto, err := u.marketRepository.GetCryptoForUpdate(ctx, id)
+	if err != nil {
+		return model.Crypto{}, err
+	}
+
+	if cryptoReq.Symbol != nil {
+		symbol := strings.ToUpper(strings.TrimSpace(*cryptoReq.Symbol))
+		if symbol != crypto.Symbol {
+			if err := u.checkSymbolUnused(ctx, symbol); err != nil {
+				return model.Crypto{}, err
+			}
+		}
+		crypto.Symbol = symbol
+	}
+	if cryptoReq.Precision != nil {
+		crypto.Precision = *cryptoReq.Precision
+	}
+	if cryptoReq.Status != nil {
+		crypto.Status = *cryptoReq.Status
+	}
+	if cryptoReq.TransferDailyLimit != nil {
+		crypto.TransferDailyLimit = *cryptoReq.TransferDailyLimit
+	}
+
+	if err := crypto.Validate(); err != nil {
+		return model.Crypto{}, serverError.ErrInvalidRequest(err)
+	}
+
+	crypto, err = u.marketRepository.SaveCrypto(ctx, crypto)
+	if err != nil {
+		return model.Crypto{}, serverError.ErrGeneralDatabaseError(err)
+	}
+
+	if err := tx.WithContext(ctx).Commit().Error; err != nil {
+		return model.Crypto{}, serverError.ErrGeneralDatabaseError(err)
+	}
+
+	log.Context(ctx).Infof("admin %v updated crypto %v", jwt.GetPayloadFromContext(ctx).UserID, crypto.ID)
+	return crypto, nil
+}
+
+// CreatePair saves the pair, creates the topic of its order and tells the matching engines to start a book.
+// The pair is only committed once both succeed, a failed call can be retried with the same request.
+func (u *marketUsecase) CreatePair(ctx context.Context, pairReq dto.PairRequest) (model.Pair, error) {
+	primaryCrypto, err := u.walletRepository.GetCrypto(ctx, pairReq.PrimaryCryptoID)
+	if err != nil {
+		return model.Pair{}, serverError.ErrInvalidRequest(err)
+	}
+
+	secondaryCrypto, err := u.walletRepository.GetCrypto(ctx, pairReq.SecondaryCryptoID)
+	if err != nil {
+		return model.Pair{}, serverError.ErrInvalidRequest(err)
+	}
+
+	pair := model.Pair{
+		Code:              strings.ToUpper(strings.TrimSpace(pairReq.Code)),
+		PrimaryCryptoID:   primaryCrypto.ID,
+		SecondaryCryptoID: secondaryCrypto.ID,
+		TickSize:          pairReq.TickSize,
+		LotSize:           pairReq.LotSize,
+		MinQuantity:       pairReq.MinQuantity,
+		MatchingAlgorithm: strings.ToUpper(strings.TrimSpace(pairReq.MatchingAlgorithm)),
+		MinAllocation:     pairReq.MinAllocation,
+		Status:            pairReq.Status == nil || *pairReq.Status,
+	}
+	if pair.Code == "" {
+		pair.Code = primaryCrypto.Symbol + secondaryCrypto.Symbol
+	}
+	if pair.MatchingAlgorithm == "" {
+		pair.MatchingAlgorithm = model.MatchingAlgorithmFIFO
+	}
+
+	if err := pair.Validate(); err != nil {
+		return model.Pair{}, serverError.ErrInvalidRequest(err)
+	}
+
+	_, err = u.walletRepository.GetPairDetail(ctx, pair.Code)
+	if err == nil {
+		return model.Pair{}, serverError.ErrInvalidRequest(model.ErrPairExists)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return model.Pair{}, serverError.ErrGeneralDatabaseError(err)
+	}
+
+	// The pair is committed inactive, an order accepted before its topic exists would never be matched
+	active := pair.Status
+	pair.Status = false
+
+	pair, err = u.marketRepository.SavePair(ctx, pair)
+	if err != nil {
+		return model.Pair{}, serverError.ErrGeneralDatabaseError(err)
+	}
+
+	if err := u.createPairTopic(ctx, pair); err != nil {
+		return model.Pair{}, err
+	}
+
+	// A failed activation leaves the pair inactive, it is activated by updating its status again
+	if active {
+		pair.Status = true
+		if pair, err = u.marketRepository.SavePair(ctx, pair); err != nil {
+			return model.Pair{}, serverError.ErrGeneralDatabaseError(err)
+		}
+	}
+
+	if err := u.publishPair(ctx, pair); err != nil {
+		return model.Pair{}, err
+	}
+
+	log.Context(ctx).Infof("admin %v listed pair %v", jwt.GetPayloadFromContext(ctx).UserID, pair.Code)
+	return pair, nil
+}
+
+// UpdatePair changes the trading rules or status, a running book keeps its matching rules until restarted
+func (u *marketUsecase) UpdatePair(ctx context.Context, id int, pairReq dto.PairUpdateRequest) (model.Pair, error) {
+	ctx, tx := gormpkg.InitTransactionToContext(ctx, u.writeDB)
+	defer tx.WithContext(ctx).Rollback()
+
+	pair, err := u.marketRepository.GetPairForUpdate(ctx, id)
+	if err != nil {
+		return model.Pair{}, err
+	}
+
+	if pairReq.TickSize != nil {
+		pair.TickSize = *pairReq.TickSize
+	}
+	if pairReq.LotSize != nil {
+		pair.LotSize = *pairReq.LotSize
+	}
+	if pairReq.MinQuantity != nil {
+		pair.MinQuantity = *pairReq.MinQuantity
+	}
+	if pairReq.MatchingAlgorithm != nil {
+		pair.MatchingAlgorithm = strings.ToUpper(strings.TrimSpace(*pairReq.MatchingAlgorithm))
+	}
+	if pairReq.MinAllocation != nil {
+		pair.MinAllocation = *pairReq.MinAllocation
+	}
+	if pairReq.Status != nil {
+		pair.Status = *pairReq.Status
+	}
+
+	if err := pair.Validate(); err != nil {
+		return model.Pair{}, serverError.ErrInvalidRequest(err)
+	}
+
+	pair, err = u.marketRepository.SavePair(ctx, pair)
+	if err != nil {
+		return model.Pair{}, serverError.ErrGeneralDatabaseError(err)
+	}
+
+	if err := tx.WithContext(ctx).Commit().Error; err != nil {
+		return model.Pair{}, serverError.ErrGeneralDatabaseError(err)
+	}
+
+	if err := u.registerPair(ctx, pair); err != nil {
+		return model.Pair{}, err
+	}
+
+	log.Context(ctx).Infof("admin %v updated pair %v", jwt.GetPayloadFromContext(ctx).UserID, pair.Code)
+	return pair, nil
+}
+
+// registerPair creates the order topic of a committed pair and publishes its latest state, keyed by code so
+// the compacted topic keeps one per pair. Both are idempotent, a failed registration is repaired by
+// updating the pair again.
+func (u *marketUsecase) registerPair(ctx context.Context, pair model.Pair) error {
+	if err := u.createPairTopic(ctx, pair); err != nil {
+		return err
+	}
+
+	return u.publishPair(ctx, pair)
+}
+
+// createPairTopic creates the topic the order of the pair is published to, named by the pair code
+func (u *marketUsecase) createPairTopic(ctx context.Context, pair model.Pair) error {
+	if err := u.topicManager.CreateTopic(ctx, pair.Code, pairTopicPartitions); err != nil {
+		return serverError.ErrGeneralError(err)
+	}
+
+	return nil
+}
+
+// publishPair sends the latest state of the pair to the pair registry read by the matching engine
+func (u *marketUsecase) publishPair(ctx context.Context, pair model.Pair) error {
+	if err := u.kafkaProducer.Send(ctx, u.brokerConfig.Producer.Topic.PairRegistry, pair.Code, pair); err != nil {
+		return serverError.ErrGeneralError(err)
+	}
+
+	return nil
+}
+
+func (u *marketUsecase) checkSymbolUnused(ctx context.Context, symbol string) error {
+	_, err := u.marketRepository.GetCryptoBySymbol(ctx, symbol)
+	if err == nil {
+		return serverError.ErrInvalidRequest(model.ErrCryptoExists)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return serverError.ErrGeneralDatabaseError(err)
+	}
+
+	return nil
+}
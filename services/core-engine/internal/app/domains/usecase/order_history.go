@@ -0,0 +1,119 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+
+	"core-engine/internal/app/domains/dto"
+	"core-engine/internal/app/domains/model"
+	serverError "core-engine/pkg/error"
+	"core-engine/pkg/jwt"
+)
+
+// Column allowed for sorting the order history, the value is passed to the query as is
+var orderSortColumns = map[string]bool{"id": true, "transaction_time": true, "price": true, "quantity": true}
+
+// Page size of the order history when the request has no limit
+const defaultOrderLimit = 100
+
+// GetOrders returns the order history of the logged in user
+func (u *orderUsecase) GetOrders(ctx context.Context, listReq dto.OrderListRequest) ([]model.Order, int, error) {
+	filter, err := u.orderFilter(ctx, listReq)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return u.getUserOrders(ctx, filter)
+}
+
+// GetOpenOrders returns the orders of the logged in user still waiting in the order book
+func (u *orderUsecase) GetOpenOrders(ctx context.Context, listReq dto.OrderListRequest) ([]model.Order, int, error) {
+	listReq.Status = ""
+
+	filter, err := u.orderFilter(ctx, listReq)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	filter.Statuses = model.OrderOpenStatuses
+	return u.getUserOrders(ctx, filter)
+}
+
+// GetOrder returns a single order, order of other user is not found
+func (u *orderUsecase) GetOrder(ctx context.Context, id int) (model.Order, error) {
+	tokenPayload := jwt.GetPayloadFromContext(ctx)
+	return u.orderRepository.GetUserOrder(ctx, tokenPayload.UserID, id)
+}
+
+func (u *orderUsecase) getUserOrders(ctx context.Context, filter model.OrderFilter) ([]model.Order, int, error) {
+	tokenPayload := jwt.GetPayloadFromContext(ctx)
+
+	orders, total, err := u.orderRepository.GetUserOrders(ctx, tokenPayload.UserID, filter)
+	if err != nil {
+		return nil, 0, serverError.ErrGeneralDatabaseError(err)
+	}
+
+	return orders, total, nil
+}
+
+func (u *orderUsecase) orderFilter(ctx context.Context, listReq dto.OrderListRequest) (model.OrderFilter, error) {
+	invalidFilter := serverError.ErrInvalidRequest(model.ErrInvalidOrderFilter)
+
+	filter := model.OrderFilter{
+		Side:          model.Side(strings.ToUpper(listReq.Side)),
+		StartTime:     listReq.StartTime,
+		EndTime:       listReq.EndTime,
+		SortBy:        listReq.SortBy,
+		SortDirection: strings.ToUpper(listReq.SortDirection),
+		Page:          listReq.Page,
+		Limit:         listReq.Limit,
+	}
+
+	if listReq.PairCode != "" {
+		pair, err := u.walletRepository.GetPairDetail(ctx, strings.ToUpper(listReq.PairCode))
+		if err != nil {
+			return model.OrderFilter{}, err
+		}
+		filter.PairID = pair.ID
+	}
+
+	if listReq.Status != "" {
+		status := model.Status(strings.ToUpper(listReq.Status))
+		switch status {
+		case model.OrderStatusComplete, model.OrderStatusFailed, model.OrderStatusProgress, model.OrderStatusPartial, model.OrderStatusCancelled:
+			filter.Statuses = []model.Status{status}
+		default:
+			return model.OrderFilter{}, invalidFilter
+		}
+	}
+
+	if filter.Side != "" && filter.Side != model.OrderSideBuy && filter.Side != model.OrderSideSell {
+		return model.OrderFilter{}, invalidFilter
+	}
+
+	if filter.SortBy != "" && !orderSortColumns[filter.SortBy] {
+		return model.OrderFilter{}, invalidFilter
+	}
+
+	if filter.SortDirection != "" && filter.SortDirection != "ASC" && filter.SortDirection != "DESC" {
+		return model.OrderFilter{}, invalidFilter
+	}
+
+	if filter.StartTime != 0 && filter.EndTime != 0 && filter.StartTime >= filter.EndTime {
+		return model.OrderFilter{}, invalidFilter
+	}
+
+	// Request without paging starts from the first page
+	if filter.Page == 0 {
+		filter.Page = 1
+	}
+	if filter.Limit == 0 {
+		filter.Limit = defaultOrderLimit
+	}
+
+	if filter.Page < 1 || filter.Limit < 1 || filter.Limit > 1000 {
+		return model.OrderFilter{}, invalidFilter
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,28 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	"core-engine/internal/app/domains/dto"
+)
+
+func TestOrderFilterPaging(t *testing.T) {
+	u := &orderUsecase{}
+
+	filter, err := u.orderFilter(context.Background(), dto.OrderListRequest{})
+	if err != nil || filter.Page != 1 || filter.Limit != defaultOrderLimit {
+		t.Fatalf("request without paging must use the default, got %+v %v", filter, err)
+	}
+
+	filter, err = u.orderFilter(context.Background(), dto.OrderListRequest{Page: 3, Limit: 20})
+	if err != nil || filter.Page != 3 || filter.Limit != 20 {
+		t.Fatalf("requested paging must be kept, got %+v %v", filter, err)
+	}
+
+	for _, listReq := range []dto.OrderListRequest{{Page: -1}, {Limit: -1}, {Limit: 1001}} {
+		if _, err := u.orderFilter(context.Background(), listReq); err == nil {
+			t.Fatalf("invalid paging %+v must be rejected", listReq)
+		}
+	}
+}
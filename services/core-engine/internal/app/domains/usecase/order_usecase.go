@@ -2,6 +2,7 @@ package usecase
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sort"
 	"time"
@@ -12,6 +13,7 @@ import (
 	"github.com/spf13/cast"
 	"gorm.io/gorm"
 
+	"core-engine/config"
 	"core-engine/internal/app/domains/dto"
 	"core-engine/internal/app/domains/model"
 	serverError "core-engine/pkg/error"
@@ -28,6 +30,9 @@ type orderUsecase struct {
 	orderRepository  model.OrderRepository
 	userRepository   model.UserRepository
 	walletRepository model.WalletRepository
+	feeRepository    model.FeeRepository
+	ledgerRepository model.LedgerRepository
+	feeConfig        config.Fee
 }
 
 // NewOrderUsecase returns new order usecase.
@@ -39,6 +44,9 @@ func NewOrderUsecase(
 	orderRepository model.OrderRepository,
 	userRepository model.UserRepository,
 	walletRepository model.WalletRepository,
+	feeRepository model.FeeRepository,
+	ledgerRepository model.LedgerRepository,
+	feeConfig config.Fee,
 ) *orderUsecase {
 	return &orderUsecase{
 		writeDB:          writeDB,
@@ -48,6 +56,9 @@ func NewOrderUsecase(
 		orderRepository:  orderRepository,
 		userRepository:   userRepository,
 		walletRepository: walletRepository,
+		feeRepository:    feeRepository,
+		ledgerRepository: ledgerRepository,
+		feeConfig:        feeConfig,
 	}
 }
 
@@ -67,12 +78,25 @@ func (u *orderUsecase) ProcessOrder(ctx context.Context, orderReq dto.OrderReque
 		return model.Order{}, serverError.ErrUserBlocked(nil) // User already deactivated
 	}
 
+	// Reject an order without price or quantity before its balance is locked, it would never be matched
+	if err := model.ValidateOrderRequest(orderReq); err != nil {
+		return model.Order{}, serverError.ErrInvalidOrderRequest(err)
+	}
+
 	// Check crypto pair detail
 	cryptoPairDetail, err := u.walletRepository.GetPairDetail(ctx, orderReq.PairCode)
 	if err != nil {
 		return model.Order{}, serverError.ErrGeneralDatabaseError(err)
 	}
 
+	if !cryptoPairDetail.Status {
+		return model.Order{}, serverError.ErrInvalidOrderRequest(model.ErrPairInactive)
+	}
+
+	if err := cryptoPairDetail.ValidateOrder(orderReq.Price, orderReq.Quantity); err != nil {
+		return model.Order{}, serverError.ErrInvalidOrderRequest(err)
+	}
+
 	targetCryptoID := cryptoPairDetail.PrimaryCryptoID
 	if model.Side(orderReq.Side) == model.OrderSideBuy {
 		// When buying, check if user have enough secondary balance for buying primary crypto
@@ -108,18 +132,22 @@ func (u *orderUsecase) ProcessOrder(ctx context.Context, orderReq dto.OrderReque
 	ctx, tx := gormpkg.InitTransactionToContext(ctx, u.writeDB)
 	defer tx.WithContext(ctx).Rollback()
 
-	// Deduct user wallet balance
-	var errBalanceUpdate error
-	switch model.Side(orderReq.Side) {
-	case model.OrderSideSell:
-		errBalanceUpdate = u.walletRepository.UpdateUserWallet(ctx, userDetail.ID, userWallet.CryptoID, -orderReq.Quantity)
+	journal := model.NewLedgerJournal(model.LedgerEntryLock, model.LedgerReferenceOrder)
+	ctx = model.SaveLedgerJournalToContext(ctx, journal)
 
-	case model.OrderSideBuy:
-		totalAmount := orderReq.Price * orderReq.Quantity
-		errBalanceUpdate = u.walletRepository.UpdateUserWallet(ctx, userDetail.ID, userWallet.CryptoID, -totalAmount)
+	// Lock the order amount until the order is filled, cancelled or completed
+	lockAmount := orderReq.Quantity
+	if model.Side(orderReq.Side) == model.OrderSideBuy {
+		lockAmount = orderReq.Price * orderReq.Quantity
+		if orderReq.QuoteQuantity > 0 {
+			lockAmount = orderReq.QuoteQuantity // Lock the whole budget, the unspent part is unlocked on completion
+		}
 	}
 
-	if errBalanceUpdate != nil {
+	if err := u.walletRepository.LockBalance(ctx, userDetail.ID, userWallet.CryptoID, lockAmount); err != nil {
+		if errors.Is(err, model.ErrInsufficientBalance) {
+			return model.Order{}, serverError.ErrInsufficientBalance(err)
+		}
 		return model.Order{}, serverError.ErrGeneralDatabaseError(err)
 	}
 
@@ -128,6 +156,7 @@ func (u *orderUsecase) ProcessOrder(ctx context.Context, orderReq dto.OrderReque
 		PairID:          cryptoPairDetail.ID,
 		Quantity:        orderReq.Quantity,
 		Price:           orderReq.Price,
+		QuoteQuantity:   orderReq.QuoteQuantity,
 		Type:            model.Type(orderReq.Type),
 		Side:            model.Side(orderReq.Side),
 		Status:          model.OrderStatusProgress,
@@ -140,6 +169,11 @@ func (u *orderUsecase) ProcessOrder(ctx context.Context, orderReq dto.OrderReque
 		return model.Order{}, err
 	}
 
+	journal.ReferenceID = order.ID
+	if err := u.ledgerRepository.SaveJournal(ctx, journal); err != nil {
+		return model.Order{}, serverError.ErrGeneralDatabaseError(err)
+	}
+
 	// Publish to matching engine
 	if err := u.kafkaProducer.Send(ctx, cryptoPairDetail.Code, cast.ToString(order.ID), order); err != nil {
 		return model.Order{}, err
@@ -185,81 +219,166 @@ func (u *orderUsecase) MatchOrder(ctx context.Context, tradeReq dto.TradeRequest
 		}
 	}()
 
-	// Get order detail from maker and taker
-	takerOrder, err := u.orderRepository.GetOrder(ctx, tradeReq.TakerOrderID)
+	ctx, tx := gormpkg.InitTransactionToContext(ctx, u.writeDB)
+	defer tx.WithContext(ctx).Rollback()
+
+	journal := model.NewLedgerJournal(model.LedgerEntryFill, model.LedgerReferenceTrade)
+	ctx = model.SaveLedgerJournalToContext(ctx, journal)
+
+	// Get order detail from maker and taker, the rows are locked in ID order to prevent deadlock
+	orders := make(map[int]model.Order, 2)
+	orderIDs := []int{tradeReq.TakerOrderID, tradeReq.MakerOrderID}
+	sort.Ints(orderIDs)
+	for _, id := range orderIDs {
+		order, err := u.orderRepository.GetOrderForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		orders[id] = order
+	}
+
+	// Redelivered trade, a concurrent duplicate waits on the order rows until the first one is committed
+	if tradeReq.Sequence != 0 {
+		settled, err := u.orderRepository.IsTradeSettled(ctx, tradeReq.PairID, tradeReq.Sequence)
+		if err != nil {
+			return err
+		}
+
+		if settled {
+			log.Context(ctx).Infof("trade %v of pair %v already settled", tradeReq.Sequence, tradeReq.PairID)
+			return nil
+		}
+	}
+
+	takerOrder, makerOrder := orders[tradeReq.TakerOrderID], orders[tradeReq.MakerOrderID]
+	takerOrder.Fill(tradeReq.Quantity, tradeReq.Price)
+	makerOrder.Fill(tradeReq.Quantity, tradeReq.Price)
+
+	takerSchedule, err := u.feeRepository.GetUserFeeSchedule(ctx, tradeReq.PairID, takerOrder.UserID)
 	if err != nil {
 		return err
 	}
 
-	makerOrder, err := u.orderRepository.GetOrder(ctx, tradeReq.MakerOrderID)
+	makerSchedule, err := u.feeRepository.GetUserFeeSchedule(ctx, tradeReq.PairID, makerOrder.UserID)
 	if err != nil {
 		return err
 	}
 
-	takerOrder.FilledQuantity += tradeReq.Quantity
-	makerOrder.FilledQuantity += tradeReq.Quantity
+	// Move the base and quote notional of this fill between both parties and the fee account
+	result := settleTrade(tradeReq, cryptoPairDetail, takerOrder, makerOrder, takerSchedule, makerSchedule, u.feeConfig.AccountUserID)
+	for _, movement := range result.Movements {
+		if err = u.applyMovement(ctx, movement); err != nil {
+			return err
+		}
+	}
+
+	// Update order status transaction
+	if _, err := u.orderRepository.SaveOrder(ctx, takerOrder); err != nil {
+		return err
+	}
+
+	if _, err := u.orderRepository.SaveOrder(ctx, makerOrder); err != nil {
+		return err
+	}
+
+	// Save to table match order
+	matchOrder := model.MatchOrder{
+		PairID:           tradeReq.PairID,
+		TakerOrderID:     tradeReq.TakerOrderID,
+		MakerOrderID:     tradeReq.MakerOrderID,
+		Quantity:         tradeReq.Quantity,
+		Price:            tradeReq.Price,
+		TakerFee:         result.TakerFee,
+		TakerFeeCryptoID: result.TakerFeeCryptoID,
+		MakerFee:         result.MakerFee,
+		MakerFeeCryptoID: result.MakerFeeCryptoID,
+		TradeSequence:    tradeReq.Sequence,
+		TransactionTime:  tradeReq.TradeTime,
+	}
 
-	// Partial filled
-	takerOrder.Status = model.OrderStatusPartial
-	makerOrder.Status = model.OrderStatusPartial
+	if matchOrder, err = u.orderRepository.SaveMatchOrder(ctx, matchOrder); err != nil {
+		if errors.Is(err, model.ErrTradeAlreadySettled) {
+			return nil // Acknowledge the duplicate, every balance movement is rolled back
+		}
+		return err
+	}
 
-	// Update status to complete filled
-	if takerOrder.FilledQuantity == takerOrder.Quantity {
-		takerOrder.Status = model.OrderStatusComplete
+	journal.ReferenceID = matchOrder.ID
+	if err := u.ledgerRepository.SaveJournal(ctx, journal); err != nil {
+		return err
 	}
-	if makerOrder.FilledQuantity == makerOrder.Quantity {
-		makerOrder.Status = model.OrderStatusComplete
+
+	return tx.WithContext(ctx).Commit().Error
+}
+
+// CompleteOrder finish an order that never rest in the book, was rejected or cancelled, and unlock its
+// unspent reserved balance
+func (u *orderUsecase) CompleteOrder(ctx context.Context, updateReq dto.OrderUpdateRequest) error {
+	defer log.Context(ctx).RecordDuration("CompleteOrder").Stop()
+
+	// Check crypto pair detail
+	cryptoPairDetail, err := u.walletRepository.GetPairDetailByID(ctx, updateReq.PairID)
+	if err != nil {
+		return err
 	}
 
 	ctx, tx := gormpkg.InitTransactionToContext(ctx, u.writeDB)
 	defer tx.WithContext(ctx).Rollback()
 
-	switch model.Side(tradeReq.Side) {
-	case model.OrderSideBuy:
-		// Update taker (buyer) primary pair wallet
-		if err = u.walletRepository.UpdateUserWallet(ctx, takerOrder.UserID, cryptoPairDetail.PrimaryCryptoID, tradeReq.Quantity); err != nil {
-			return err
-		}
+	order, err := u.orderRepository.GetOrderForUpdate(ctx, updateReq.OrderID)
+	if err != nil {
+		return err
+	}
 
-		// Update maker (seller) secondary pair wallet
-		if err = u.walletRepository.UpdateUserWallet(ctx, makerOrder.UserID, cryptoPairDetail.SecondaryCryptoID, tradeReq.Quantity); err != nil {
-			return err
-		}
+	// Redelivered update, already refunded
+	if order.Finished() {
+		return nil
+	}
 
-	case model.OrderSideSell:
-		// Update taker (seller) secondary pair wallet
-		if err = u.walletRepository.UpdateUserWallet(ctx, takerOrder.UserID, cryptoPairDetail.SecondaryCryptoID, tradeReq.Quantity); err != nil {
-			return err
-		}
+	// Unspent quote budget is refunded, the lock of a cancelled or rejected order is released
+	journal := model.NewLedgerJournal(model.LedgerEntryUnlock, model.LedgerReferenceOrder)
+	if order.QuoteQuantity > 0 {
+		journal.EntryType = model.LedgerEntryRefund
+	}
+	ctx = model.SaveLedgerJournalToContext(ctx, journal)
 
-		// Update maker (buyer) primary pair wallet
-		if err = u.walletRepository.UpdateUserWallet(ctx, makerOrder.UserID, cryptoPairDetail.PrimaryCryptoID, tradeReq.Quantity); err != nil {
+	unlockCryptoID := cryptoPairDetail.PrimaryCryptoID
+	if order.Side == model.OrderSideBuy {
+		unlockCryptoID = cryptoPairDetail.SecondaryCryptoID
+	}
+
+	// The matching engine knows the remaining quantity and the total spent even when some trades are not settled yet
+	if amount := order.UnlockAmount(updateReq); amount > 0 {
+		if err := u.walletRepository.UnlockBalance(ctx, order.UserID, unlockCryptoID, amount); err != nil {
 			return err
 		}
 	}
 
-	// Update order status transaction
-	if _, err := u.orderRepository.SaveOrder(ctx, takerOrder); err != nil {
+	order.Status = model.Status(updateReq.Status)
+	if _, err := u.orderRepository.SaveOrder(ctx, order); err != nil {
 		return err
 	}
 
-	if _, err := u.orderRepository.SaveOrder(ctx, makerOrder); err != nil {
+	journal.ReferenceID = order.ID
+	if err := u.ledgerRepository.SaveJournal(ctx, journal); err != nil {
 		return err
 	}
 
-	// Save to table match order
-	matchOrder := model.MatchOrder{
-		PairID:          tradeReq.PairID,
-		TakerOrderID:    tradeReq.TakerOrderID,
-		MakerOrderID:    tradeReq.MakerOrderID,
-		Quantity:        tradeReq.Quantity,
-		Price:           tradeReq.Price,
-		TransactionTime: tradeReq.TradeTime,
+	return tx.WithContext(ctx).Commit().Error
+}
+
+func (u *orderUsecase) applyMovement(ctx context.Context, movement walletMovement) error {
+	if journal := model.GetLedgerJournalFromContext(ctx); journal != nil {
+		journal.EntryType = movement.ledgerEntryType(u.feeConfig.AccountUserID)
 	}
 
-	if err = u.orderRepository.SaveMatchOrder(ctx, matchOrder); err != nil {
-		return err
+	switch movement.Type {
+	case movementConsume:
+		return u.walletRepository.ConsumeLockedBalance(ctx, movement.UserID, movement.CryptoID, movement.Amount)
+	case movementUnlock:
+		return u.walletRepository.UnlockBalance(ctx, movement.UserID, movement.CryptoID, movement.Amount)
 	}
 
-	return tx.WithContext(ctx).Commit().Error
+	// The buyer of a newly listed crypto and the fee account may not have its wallet yet
+	return creditWallet(ctx, u.walletRepository, movement.UserID, movement.CryptoID, movement.Amount)
 }
@@ -0,0 +1,127 @@
+package usecase
+
+import (
+	"math"
+
+	"core-engine/internal/app/domains/dto"
+	"core-engine/internal/app/domains/model"
+)
+
+type movementType int
+
+const (
+	movementCredit  movementType = iota // Add to available balance, negative amount is a debit
+	movementConsume                     // Spend from locked balance
+	movementUnlock                      // Release locked balance back to available
+)
+
+// walletMovement is a single balance change
+type walletMovement struct {
+	Type     movementType
+	UserID   int
+	CryptoID int
+	Amount   float64
+}
+
+type settlement struct {
+	Movements        []walletMovement
+	TakerFee         float64
+	TakerFeeCryptoID int
+	MakerFee         float64
+	MakerFeeCryptoID int
+}
+
+// settleTrade computes every balance change of a single fill.
+//
+// The buyer receives the base notional (quantity) in the primary crypto and the seller receives the
+// quote notional (quantity x price) in the secondary crypto, each minus its fee charged from the received
+// amount. A maker rebate is paid out of the taker fee in the same crypto and never exceeds it, so the fee
+// account is never debited. The spent notional is consumed from the balance locked when the order was
+// placed, and the excess lock of a limit buy filled below its own price is released. A quote order locked
+// its whole budget, the unspent part is unlocked when the order is completed.
+func settleTrade(
+	trade dto.TradeRequest,
+	pair model.Pair,
+	takerOrder model.Order,
+	makerOrder model.Order,
+	takerSchedule model.FeeSchedule,
+	makerSchedule model.FeeSchedule,
+	feeAccountUserID int,
+) settlement {
+	var (
+		baseAmount  = trade.Quantity
+		quoteAmount = trade.Quantity * trade.Price
+		buyOrder    = takerOrder
+		sellOrder   = makerOrder
+		result      settlement
+	)
+
+	// Taker is buying, maker is selling
+	takerAmount, makerAmount := baseAmount, quoteAmount
+	result.TakerFeeCryptoID, result.MakerFeeCryptoID = pair.PrimaryCryptoID, pair.SecondaryCryptoID
+
+	if model.Side(trade.Side) == model.OrderSideSell {
+		buyOrder, sellOrder = makerOrder, takerOrder
+		takerAmount, makerAmount = quoteAmount, baseAmount
+		result.TakerFeeCryptoID, result.MakerFeeCryptoID = pair.SecondaryCryptoID, pair.PrimaryCryptoID
+	}
+
+	result.TakerFee = takerSchedule.TakerFee(takerAmount)
+	result.MakerFee = makerSchedule.MakerFee(makerAmount)
+
+	// Negative maker fee is a rebate on the notional the maker gave, paid out of the taker fee in the same crypto
+	makerCryptoID, rebate := result.MakerFeeCryptoID, 0.0
+	if result.MakerFee < 0 {
+		rebate = math.Min(-makerSchedule.MakerFee(takerAmount), result.TakerFee)
+		result.MakerFee, result.MakerFeeCryptoID = -rebate, result.TakerFeeCryptoID
+	}
+
+	// Spend the locked notional of both side
+	result.add(movementConsume, sellOrder.UserID, pair.PrimaryCryptoID, baseAmount)
+	result.add(movementConsume, buyOrder.UserID, pair.SecondaryCryptoID, quoteAmount)
+
+	result.add(movementCredit, takerOrder.UserID, result.TakerFeeCryptoID, takerAmount-result.TakerFee)
+	result.add(movementCredit, makerOrder.UserID, makerCryptoID, makerAmount-math.Max(result.MakerFee, 0))
+	result.add(movementCredit, makerOrder.UserID, result.TakerFeeCryptoID, rebate)
+	result.add(movementCredit, feeAccountUserID, result.TakerFeeCryptoID, result.TakerFee-rebate)
+	result.add(movementCredit, feeAccountUserID, makerCryptoID, math.Max(result.MakerFee, 0))
+
+	// Price improvement, the buy order locked its own price for this quantity
+	if buyOrder.QuoteQuantity == 0 && buyOrder.Price > trade.Price {
+		result.add(movementUnlock, buyOrder.UserID, pair.SecondaryCryptoID, (buyOrder.Price-trade.Price)*baseAmount)
+	}
+
+	return result
+}
+
+// balanceChange returns the change of the available and locked balance made by the movement
+func (movement walletMovement) balanceChange() (available, locked float64) {
+	switch movement.Type {
+	case movementConsume:
+		return 0, -movement.Amount
+	case movementUnlock:
+		return movement.Amount, -movement.Amount
+	}
+
+	return movement.Amount, 0
+}
+
+// ledgerEntryType returns how the movement is recorded in the ledger
+func (movement walletMovement) ledgerEntryType(feeAccountUserID int) model.LedgerEntryType {
+	switch {
+	case movement.Type == movementUnlock:
+		return model.LedgerEntryUnlock
+	case movement.Type == movementCredit && movement.UserID == feeAccountUserID:
+		return model.LedgerEntryFee
+	}
+
+	return model.LedgerEntryFill
+}
+
+func (s *settlement) add(movement movementType, userID, cryptoID int, amount float64) {
+	if amount == 0 {
+		return
+	}
+
+	s.Movements = append(s.Movements, walletMovement{Type: movement, UserID: userID, CryptoID: cryptoID, Amount: amount})
+}
@@ -0,0 +1,266 @@
+package usecase
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"core-engine/internal/app/domains/dto"
+	"core-engine/internal/app/domains/model"
+)
+
+func TestSettleTrade(t *testing.T) {
+	const (
+		doge       = 6
+		idrt       = 1
+		feeAccount = 7
+		buyer      = 1
+		seller     = 2
+	)
+
+	var (
+		pair    = model.Pair{ID: 2, Code: "DOGEIDRT", PrimaryCryptoID: doge, SecondaryCryptoID: idrt}
+		charged = model.FeeSchedule{MakerRate: 0.125, TakerRate: 0.25}
+		rebate  = model.FeeSchedule{MakerRate: -0.0625, TakerRate: 0.25}
+	)
+
+	tests := []struct {
+		name          string
+		trade         dto.TradeRequest
+		taker         model.Order
+		maker         model.Order
+		takerSchedule model.FeeSchedule
+		makerSchedule model.FeeSchedule
+		want          settlement
+	}{
+		{
+			name:  "buy taker at its own price",
+			trade: dto.TradeRequest{Quantity: 4, Price: 100, Side: string(model.OrderSideBuy)},
+			taker: model.Order{UserID: buyer, Price: 100, Side: model.OrderSideBuy},
+			maker: model.Order{UserID: seller, Price: 100, Side: model.OrderSideSell},
+			want: settlement{
+				Movements: []walletMovement{
+					consume(seller, doge, 4),
+					consume(buyer, idrt, 400),
+					credit(buyer, doge, 4),
+					credit(seller, idrt, 400),
+				},
+				TakerFeeCryptoID: doge,
+				MakerFeeCryptoID: idrt,
+			},
+		},
+		{
+			name:  "buy taker with price improvement release the excess reservation",
+			trade: dto.TradeRequest{Quantity: 4, Price: 90, Side: string(model.OrderSideBuy)},
+			taker: model.Order{UserID: buyer, Price: 100, Side: model.OrderSideBuy},
+			maker: model.Order{UserID: seller, Price: 90, Side: model.OrderSideSell},
+			want: settlement{
+				Movements: []walletMovement{
+					consume(seller, doge, 4),
+					consume(buyer, idrt, 360),
+					credit(buyer, doge, 4),
+					credit(seller, idrt, 360),
+					unlock(buyer, idrt, 40),
+				},
+				TakerFeeCryptoID: doge,
+				MakerFeeCryptoID: idrt,
+			},
+		},
+		{
+			name:          "buy taker with fee",
+			trade:         dto.TradeRequest{Quantity: 4, Price: 90, Side: string(model.OrderSideBuy)},
+			taker:         model.Order{UserID: buyer, Price: 100, Side: model.OrderSideBuy},
+			maker:         model.Order{UserID: seller, Price: 90, Side: model.OrderSideSell},
+			takerSchedule: charged,
+			makerSchedule: charged,
+			want: settlement{
+				Movements: []walletMovement{
+					consume(seller, doge, 4),
+					consume(buyer, idrt, 360),
+					credit(buyer, doge, 3),
+					credit(seller, idrt, 315),
+					credit(feeAccount, doge, 1),
+					credit(feeAccount, idrt, 45),
+					unlock(buyer, idrt, 40),
+				},
+				TakerFee:         1,
+				TakerFeeCryptoID: doge,
+				MakerFee:         45,
+				MakerFeeCryptoID: idrt,
+			},
+		},
+		{
+			name:  "quote buy taker keep the reservation until completion",
+			trade: dto.TradeRequest{Quantity: 4, Price: 90, Side: string(model.OrderSideBuy)},
+			taker: model.Order{UserID: buyer, QuoteQuantity: 1000, Type: model.OrderTypeMarket, Side: model.OrderSideBuy},
+			maker: model.Order{UserID: seller, Price: 90, Side: model.OrderSideSell},
+			want: settlement{
+				Movements: []walletMovement{
+					consume(seller, doge, 4),
+					consume(buyer, idrt, 360),
+					credit(buyer, doge, 4),
+					credit(seller, idrt, 360),
+				},
+				TakerFeeCryptoID: doge,
+				MakerFeeCryptoID: idrt,
+			},
+		},
+		{
+			name:  "sell taker at maker price",
+			trade: dto.TradeRequest{Quantity: 2, Price: 110, Side: string(model.OrderSideSell)},
+			taker: model.Order{UserID: seller, Price: 100, Side: model.OrderSideSell},
+			maker: model.Order{UserID: buyer, Price: 110, Side: model.OrderSideBuy},
+			want: settlement{
+				Movements: []walletMovement{
+					consume(seller, doge, 2),
+					consume(buyer, idrt, 220),
+					credit(seller, idrt, 220),
+					credit(buyer, doge, 2),
+				},
+				TakerFeeCryptoID: idrt,
+				MakerFeeCryptoID: doge,
+			},
+		},
+		{
+			name:          "sell taker with maker rebate",
+			trade:         dto.TradeRequest{Quantity: 16, Price: 110, Side: string(model.OrderSideSell)},
+			taker:         model.Order{UserID: seller, Price: 100, Side: model.OrderSideSell},
+			maker:         model.Order{UserID: buyer, Price: 110, Side: model.OrderSideBuy},
+			takerSchedule: charged,
+			makerSchedule: rebate,
+			want: settlement{
+				Movements: []walletMovement{
+					consume(seller, doge, 16),
+					consume(buyer, idrt, 1760),
+					credit(seller, idrt, 1320),
+					credit(buyer, doge, 16),
+					credit(buyer, idrt, 110),
+					credit(feeAccount, idrt, 330),
+				},
+				TakerFee:         440,
+				TakerFeeCryptoID: idrt,
+				MakerFee:         -110,
+				MakerFeeCryptoID: idrt,
+			},
+		},
+		{
+			name:          "maker rebate is capped by the taker fee",
+			trade:         dto.TradeRequest{Quantity: 4, Price: 100, Side: string(model.OrderSideBuy)},
+			taker:         model.Order{UserID: buyer, Price: 100, Side: model.OrderSideBuy},
+			maker:         model.Order{UserID: seller, Price: 100, Side: model.OrderSideSell},
+			takerSchedule: model.FeeSchedule{TakerRate: 0.125},
+			makerSchedule: model.FeeSchedule{MakerRate: -0.25},
+			want: settlement{
+				Movements: []walletMovement{
+					consume(seller, doge, 4),
+					consume(buyer, idrt, 400),
+					credit(buyer, doge, 3.5),
+					credit(seller, idrt, 400),
+					credit(seller, doge, 0.5),
+				},
+				TakerFee:         0.5,
+				TakerFeeCryptoID: doge,
+				MakerFee:         -0.5,
+				MakerFeeCryptoID: doge,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := settleTrade(tt.trade, pair, tt.taker, tt.maker, tt.takerSchedule, tt.makerSchedule, feeAccount)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("settleTrade()\n got %+v\nwant %+v", got, tt.want)
+			}
+
+			// Every fill must conserve each asset, what is consumed from the locked balance is credited
+			consumed, credited := map[int]float64{}, map[int]float64{}
+			for _, movement := range got.Movements {
+				switch movement.Type {
+				case movementConsume:
+					consumed[movement.CryptoID] += movement.Amount
+				case movementCredit:
+					credited[movement.CryptoID] += movement.Amount
+				}
+			}
+
+			if !reflect.DeepEqual(consumed, credited) {
+				t.Fatalf("assets not conserved, consumed %v credited %v", consumed, credited)
+			}
+
+			// The ledger journal of the fill must balance
+			journal := model.NewLedgerJournal(model.LedgerEntryFill, model.LedgerReferenceTrade)
+			for _, movement := range got.Movements {
+				available, locked := movement.balanceChange()
+				journal.PostBalanceChange(movement.UserID, movement.CryptoID, available, locked)
+			}
+
+			if !journal.Balanced() {
+				t.Fatalf("unbalanced ledger journal %+v", journal.Entries)
+			}
+		})
+	}
+}
+
+// fakeWalletRepository keeps the available balance of every wallet, a balance update of a missing wallet fails
+type fakeWalletRepository struct {
+	model.WalletRepository
+	available map[[2]int]float64
+}
+
+func (r *fakeWalletRepository) Save(ctx context.Context, wallet model.Wallet) error {
+	r.available[[2]int{wallet.UserID, wallet.CryptoID}] += 0
+	return nil
+}
+
+func (r *fakeWalletRepository) CreditBalance(ctx context.Context, userID, cryptoID int, amount float64) error {
+	return r.ApplyBalanceChange(ctx, model.BalanceChange{UserID: userID, CryptoID: cryptoID, Available: amount})
+}
+
+func (r *fakeWalletRepository) ApplyBalanceChange(ctx context.Context, change model.BalanceChange) error {
+	key := [2]int{change.UserID, change.CryptoID}
+	if _, ok := r.available[key]; !ok {
+		return model.ErrWalletNotFound
+	}
+
+	r.available[key] += change.Available
+	return nil
+}
+
+func TestSettleIntoMissingWallet(t *testing.T) {
+	const (
+		doge  = 6
+		buyer = 1
+	)
+
+	// The buyer of a newly listed crypto has no wallet of it yet
+	wallets := &fakeWalletRepository{available: map[[2]int]float64{}}
+	u := &orderUsecase{walletRepository: wallets}
+	if err := u.applyMovement(context.Background(), credit(buyer, doge, 4)); err != nil {
+		t.Fatalf("per trade settlement failed, %v", err)
+	}
+
+	if got := wallets.available[[2]int{buyer, doge}]; got != 4 {
+		t.Fatalf("buyer received %v, want 4", got)
+	}
+
+	wallets = &fakeWalletRepository{available: map[[2]int]float64{}}
+	if err := applyBalanceChange(context.Background(), wallets, model.BalanceChange{UserID: buyer, CryptoID: doge, Available: 6}); err != nil {
+		t.Fatalf("batch settlement failed, %v", err)
+	}
+	if got := wallets.available[[2]int{buyer, doge}]; got != 6 {
+		t.Fatalf("buyer received %v in the batch, want 6", got)
+	}
+}
+
+func credit(userID, cryptoID int, amount float64) walletMovement {
+	return walletMovement{Type: movementCredit, UserID: userID, CryptoID: cryptoID, Amount: amount}
+}
+
+func consume(userID, cryptoID int, amount float64) walletMovement {
+	return walletMovement{Type: movementConsume, UserID: userID, CryptoID: cryptoID, Amount: amount}
+}
+
+func unlock(userID, cryptoID int, amount float64) walletMovement {
+	return walletMovement{Type: movementUnlock, UserID: userID, CryptoID: cryptoID, Amount: amount}
+}
@@ -0,0 +1,93 @@
+package usecase
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"core-engine/internal/app/domains/dto"
+	"core-engine/internal/app/domains/model"
+	serverError "core-engine/pkg/error"
+	"core-engine/pkg/jwt"
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+type tradeUsecase struct {
+	orderRepository  model.OrderRepository
+	walletRepository model.WalletRepository
+}
+
+// NewTradeUsecase returns new trade usecase.
+func NewTradeUsecase(orderRepository model.OrderRepository, walletRepository model.WalletRepository) *tradeUsecase {
+	return &tradeUsecase{
+		orderRepository:  orderRepository,
+		walletRepository: walletRepository,
+	}
+}
+
+func (u *tradeUsecase) GetTrades(ctx context.Context, listReq dto.TradeListRequest) ([]model.UserTrade, string, error) {
+	tokenPayload := jwt.GetPayloadFromContext(ctx)
+
+	if listReq.Limit < 1 || listReq.Limit > 1000 {
+		return nil, "", serverError.ErrInvalidRequest(nil)
+	}
+
+	if listReq.StartTime != 0 && listReq.EndTime != 0 && listReq.StartTime >= listReq.EndTime {
+		return nil, "", serverError.ErrInvalidRequest(nil)
+	}
+
+	filter := model.TradeFilter{
+		StartTime: listReq.StartTime,
+		EndTime:   listReq.EndTime,
+		Limit:     listReq.Limit + 1, // One more row tells whether there is a next page
+	}
+
+	if listReq.Cursor != "" {
+		var err error
+		if filter.BeforeMatch, filter.BeforeOrder, err = decodeTradeCursor(listReq.Cursor); err != nil {
+			return nil, "", serverError.ErrInvalidRequest(err)
+		}
+	}
+
+	if listReq.PairCode != "" {
+		pair, err := u.walletRepository.GetPairDetail(ctx, strings.ToUpper(listReq.PairCode))
+		if err != nil {
+			return nil, "", err
+		}
+		filter.PairID = pair.ID
+	}
+
+	trades, err := u.orderRepository.GetUserTrades(ctx, tokenPayload.UserID, filter)
+	if err != nil {
+		return nil, "", serverError.ErrGeneralDatabaseError(err)
+	}
+
+	if len(trades) <= listReq.Limit {
+		return trades, "", nil
+	}
+
+	trades = trades[:listReq.Limit]
+	last := trades[len(trades)-1]
+	return trades, encodeTradeCursor(last.MatchOrderID, last.OrderID), nil
+}
+
+// The cursor is the position of the last returned trade, both side of a self trade share the match order ID
+func encodeTradeCursor(matchOrderID, orderID int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%v:%v", matchOrderID, orderID)))
+}
+
+func decodeTradeCursor(cursor string) (matchOrderID, orderID int, err error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, 0, errInvalidCursor
+	}
+
+	if _, err := fmt.Sscanf(string(raw), "%d:%d", &matchOrderID, &orderID); err != nil || matchOrderID <= 0 {
+		return 0, 0, errInvalidCursor
+	}
+
+	return matchOrderID, orderID, nil
+}
@@ -0,0 +1,25 @@
+package usecase
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+func TestTradeCursor(t *testing.T) {
+	cursor := encodeTradeCursor(120, 45)
+
+	matchOrderID, orderID, err := decodeTradeCursor(cursor)
+	if err != nil || matchOrderID != 120 || orderID != 45 {
+		t.Fatalf("decodeTradeCursor(%q) = %v, %v, %v", cursor, matchOrderID, orderID, err)
+	}
+
+	for _, invalid := range []string{"not base64!", encodeTradeCursorRaw("120"), encodeTradeCursorRaw("0:1")} {
+		if _, _, err := decodeTradeCursor(invalid); err != errInvalidCursor {
+			t.Fatalf("cursor %q must be rejected, got %v", invalid, err)
+		}
+	}
+}
+
+func encodeTradeCursorRaw(raw string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
@@ -0,0 +1,221 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gerins/log"
+	"github.com/go-redsync/redsync/v4"
+	"gorm.io/gorm"
+
+	"core-engine/internal/app/domains/dto"
+	"core-engine/internal/app/domains/model"
+	serverError "core-engine/pkg/error"
+	gormpkg "core-engine/pkg/gorm"
+	"core-engine/pkg/jwt"
+)
+
+const (
+	transferLimitWindow    = 24 * time.Hour // Daily transfer limit is counted over the last 24 hours
+	transferLimitTolerance = 1e-9           // Float residue of the summed amount
+)
+
+type transferUsecase struct {
+	writeDB            *gorm.DB
+	redisLock          *redsync.Redsync
+	transferRepository model.TransferRepository
+	userRepository     model.UserRepository
+	walletRepository   model.WalletRepository
+	ledgerRepository   model.LedgerRepository
+	twoFactorUsecase   model.TwoFactorUsecase
+}
+
+// NewTransferUsecase returns new transfer usecase.
+func NewTransferUsecase(
+	writeDB *gorm.DB,
+	redisLock *redsync.Redsync,
+	transferRepository model.TransferRepository,
+	userRepository model.UserRepository,
+	walletRepository model.WalletRepository,
+	ledgerRepository model.LedgerRepository,
+	twoFactorUsecase model.TwoFactorUsecase,
+) *transferUsecase {
+	return &transferUsecase{
+		writeDB:            writeDB,
+		redisLock:          redisLock,
+		transferRepository: transferRepository,
+		userRepository:     userRepository,
+		walletRepository:   walletRepository,
+		ledgerRepository:   ledgerRepository,
+		twoFactorUsecase:   twoFactorUsecase,
+	}
+}
+
+// Transfer moves available balance from the logged in user to another user in one transaction
+func (u *transferUsecase) Transfer(ctx context.Context, transferReq dto.TransferRequest) (model.Transfer, error) {
+	defer log.Context(ctx).RecordDuration("Transfer").Stop()
+
+	tokenPayload := jwt.GetPayloadFromContext(ctx)
+
+	if transferReq.Amount <= 0 || (transferReq.ReceiverID == 0 && transferReq.ReceiverEmail == "") {
+		return model.Transfer{}, serverError.ErrInvalidRequest(nil)
+	}
+
+	sender, err := u.userRepository.FindUserByID(ctx, tokenPayload.UserID)
+	if err != nil {
+		return model.Transfer{}, serverError.ErrGeneralDatabaseError(err)
+	}
+
+	if !sender.Status {
+		return model.Transfer{}, serverError.ErrUserBlocked(nil)
+	}
+
+	// A request signed by an API key has no code to give, the WITHDRAW scope already needed a code and an IP allowlist
+	if tokenPayload.Scopes == "" {
+		if err := u.twoFactorUsecase.Verify(ctx, sender.ID, transferReq.TotpCode); err != nil {
+			return model.Transfer{}, err
+		}
+	}
+
+	receiver, err := u.findReceiver(ctx, transferReq)
+	if err != nil {
+		return model.Transfer{}, err
+	}
+
+	if receiver.ID == sender.ID {
+		return model.Transfer{}, serverError.ErrInvalidRequest(model.ErrSelfTransfer)
+	}
+
+	crypto, err := u.walletRepository.GetCrypto(ctx, transferReq.CryptoID)
+	if err != nil {
+		return model.Transfer{}, serverError.ErrInvalidRequest(err)
+	}
+
+	if crypto.TransferDailyLimit <= 0 {
+		return model.Transfer{}, serverError.ErrInvalidRequest(model.ErrTransferDisabled)
+	}
+
+	// Lock the wallet of both users, the same key as order placement so a transfer and an order of the
+	// sender never spend the same balance. Keys are taken in user order to prevent deadlock.
+	userIDs := []int{sender.ID, receiver.ID}
+	sort.Ints(userIDs)
+
+	for _, userID := range userIDs {
+		mutex := u.redisLock.NewMutex(fmt.Sprintf("locking#member#%v#%v", userID, crypto.ID))
+		if err := mutex.Lock(); err != nil {
+			log.Context(ctx).Error(err)
+			return model.Transfer{}, err
+		}
+
+		defer func() {
+			if ok, err := mutex.Unlock(); !ok || err != nil {
+				log.Context(ctx).Error(err)
+			}
+		}()
+	}
+
+	ctx, tx := gormpkg.InitTransactionToContext(ctx, u.writeDB)
+	defer tx.WithContext(ctx).Rollback()
+
+	// Checked while holding the sender lock, a concurrent transfer can not pass the limit
+	transferred, err := u.transferRepository.GetTransferredAmount(ctx, sender.ID, crypto.ID, time.Now().Add(-transferLimitWindow))
+	if err != nil {
+		return model.Transfer{}, serverError.ErrGeneralDatabaseError(err)
+	}
+
+	if !withinTransferLimit(crypto.TransferDailyLimit, transferred, transferReq.Amount) {
+		return model.Transfer{}, serverError.ErrTransferLimitExceeded(model.ErrTransferOverLimit)
+	}
+
+	transfer, err := u.transferRepository.SaveTransfer(ctx, model.Transfer{
+		SenderID:   sender.ID,
+		ReceiverID: receiver.ID,
+		CryptoID:   crypto.ID,
+		Amount:     transferReq.Amount,
+		Note:       strings.TrimSpace(transferReq.Note),
+	})
+	if err != nil {
+		return model.Transfer{}, serverError.ErrGeneralDatabaseError(err)
+	}
+
+	journal := model.NewLedgerJournal(model.LedgerEntryTransfer, model.LedgerReferenceTransfer)
+	journal.ReferenceID = transfer.ID
+	ctx = model.SaveLedgerJournalToContext(ctx, journal)
+
+	if err := u.walletRepository.DebitBalance(ctx, sender.ID, crypto.ID, transfer.Amount); err != nil {
+		if errors.Is(err, model.ErrInsufficientBalance) || errors.Is(err, model.ErrWalletNotFound) {
+			return model.Transfer{}, serverError.ErrInsufficientBalance(err)
+		}
+		return model.Transfer{}, serverError.ErrGeneralDatabaseError(err)
+	}
+
+	if err := creditWallet(ctx, u.walletRepository, receiver.ID, crypto.ID, transfer.Amount); err != nil {
+		return model.Transfer{}, serverError.ErrGeneralDatabaseError(err)
+	}
+
+	if err := u.ledgerRepository.SaveJournal(ctx, journal); err != nil {
+		return model.Transfer{}, serverError.ErrGeneralDatabaseError(err)
+	}
+
+	if err := tx.WithContext(ctx).Commit().Error; err != nil {
+		return model.Transfer{}, serverError.ErrGeneralDatabaseError(err)
+	}
+
+	transfer.Direction = model.TransferDirectionOut
+	return transfer, nil
+}
+
+// GetTransfers returns the transfers sent and received by the logged in user
+func (u *transferUsecase) GetTransfers(ctx context.Context, listReq dto.TransferListRequest) ([]model.Transfer, int, error) {
+	tokenPayload := jwt.GetPayloadFromContext(ctx)
+
+	transfers, total, err := u.transferRepository.GetUserTransfers(ctx, tokenPayload.UserID, listReq)
+	if err != nil {
+		return nil, 0, serverError.ErrGeneralDatabaseError(err)
+	}
+
+	for i := range transfers {
+		transfers[i].Direction = model.TransferDirectionIn
+		if transfers[i].SenderID == tokenPayload.UserID {
+			transfers[i].Direction = model.TransferDirectionOut
+		}
+	}
+
+	return transfers, total, nil
+}
+
+func (u *transferUsecase) findReceiver(ctx context.Context, transferReq dto.TransferRequest) (model.User, error) {
+	var (
+		receiver model.User
+		err      error
+	)
+
+	if transferReq.ReceiverID != 0 {
+		receiver, err = u.userRepository.FindUserByID(ctx, transferReq.ReceiverID)
+	} else {
+		receiver, err = u.userRepository.FindUserByEmail(ctx, strings.TrimSpace(transferReq.ReceiverEmail))
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return model.User{}, serverError.ErrInvalidRequest(model.ErrReceiverUnavailable)
+	}
+	if err != nil {
+		return model.User{}, serverError.ErrGeneralDatabaseError(err)
+	}
+
+	// Blocked user, including the exchange accounts, can not receive transfer
+	if !receiver.Status {
+		return model.User{}, serverError.ErrInvalidRequest(model.ErrReceiverUnavailable)
+	}
+
+	return receiver, nil
+}
+
+// withinTransferLimit returns true when the amount fits in what is left of the daily limit
+func withinTransferLimit(dailyLimit, transferred, amount float64) bool {
+	return transferred+amount <= dailyLimit+transferLimitTolerance
+}
@@ -0,0 +1,27 @@
+package usecase
+
+import "testing"
+
+func TestWithinTransferLimit(t *testing.T) {
+	tests := []struct {
+		name        string
+		limit       float64
+		transferred float64
+		amount      float64
+		want        bool
+	}{
+		{"first transfer", 100, 0, 40, true},
+		{"exactly the limit", 100, 60, 40, true},
+		{"over the limit", 100, 60, 40.01, false},
+		{"float residue", 0.3, 0.1 + 0.1, 0.1, true},
+		{"limit already used", 100, 100, 0.0001, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := withinTransferLimit(tt.limit, tt.transferred, tt.amount); got != tt.want {
+				t.Errorf("withinTransferLimit(%v, %v, %v) = %v, want %v", tt.limit, tt.transferred, tt.amount, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,311 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gerins/log"
+	"gorm.io/gorm"
+
+	"core-engine/config"
+	"core-engine/internal/app/domains/dto"
+	"core-engine/internal/app/domains/model"
+	serverError "core-engine/pkg/error"
+	gormpkg "core-engine/pkg/gorm"
+	"core-engine/pkg/jwt"
+	"core-engine/pkg/secret"
+	"core-engine/pkg/totp"
+)
+
+const (
+	recoveryCodeCount           = 10
+	defaultTwoFactorMaxAttempts = 5
+	defaultTwoFactorLockout     = 15 * time.Minute
+)
+
+type twoFactorUsecase struct {
+	writeDB        *gorm.DB
+	securityConfig config.Security
+	userRepository model.UserRepository
+	failureCounter model.FailureCounter
+}
+
+// NewTwoFactorUsecase returns new TOTP two factor usecase, failureCounter keeps the invalid codes of each user.
+func NewTwoFactorUsecase(
+	writeDB *gorm.DB,
+	securityConfig config.Security,
+	userRepository model.UserRepository,
+	failureCounter model.FailureCounter,
+) *twoFactorUsecase {
+	if securityConfig.Totp.MaxAttempts <= 0 {
+		securityConfig.Totp.MaxAttempts = defaultTwoFactorMaxAttempts
+	}
+	if securityConfig.Totp.Lockout <= 0 {
+		securityConfig.Totp.Lockout = defaultTwoFactorLockout
+	}
+
+	return &twoFactorUsecase{
+		writeDB:        writeDB,
+		securityConfig: securityConfig,
+		userRepository: userRepository,
+		failureCounter: failureCounter,
+	}
+}
+
+// Enroll replaces the pending secret, 2FA stays disabled until a code of the secret is confirmed by Enable
+func (u *twoFactorUsecase) Enroll(ctx context.Context) (dto.TwoFactorEnrollResponse, error) {
+	tokenPayload := jwt.GetPayloadFromContext(ctx)
+
+	ctx, tx := gormpkg.InitTransactionToContext(ctx, u.writeDB)
+	defer tx.WithContext(ctx).Rollback()
+
+	user, err := u.userRepository.GetUserForUpdate(ctx, tokenPayload.UserID)
+	if err != nil {
+		return dto.TwoFactorEnrollResponse{}, serverError.ErrGeneralDatabaseError(err)
+	}
+
+	if user.TotpEnabled {
+		return dto.TwoFactorEnrollResponse{}, serverError.ErrInvalidRequest(model.ErrTwoFactorEnabled)
+	}
+
+	totpSecret, err := totp.NewSecret()
+	if err != nil {
+		return dto.TwoFactorEnrollResponse{}, serverError.ErrGeneralError(err)
+	}
+
+	user.TotpSecret, err = secret.Encrypt(u.securityConfig.Totp.EncryptionKey, totpSecret)
+	if err != nil {
+		return dto.TwoFactorEnrollResponse{}, serverError.ErrGeneralError(err)
+	}
+	user.TotpLastStep = 0
+
+	if err := u.userRepository.UpdateTotp(ctx, user); err != nil {
+		return dto.TwoFactorEnrollResponse{}, serverError.ErrGeneralDatabaseError(err)
+	}
+
+	if err := tx.WithContext(ctx).Commit().Error; err != nil {
+		return dto.TwoFactorEnrollResponse{}, serverError.ErrGeneralDatabaseError(err)
+	}
+
+	return dto.TwoFactorEnrollResponse{
+		Secret:          totpSecret,
+		ProvisioningURI: totp.ProvisioningURI(u.securityConfig.Totp.Issuer, user.Email, totpSecret),
+	}, nil
+}
+
+// Enable turns on 2FA once the user proves the authenticator holds the enrolled secret
+func (u *twoFactorUsecase) Enable(ctx context.Context, code string) (dto.TwoFactorEnableResponse, error) {
+	tokenPayload := jwt.GetPayloadFromContext(ctx)
+
+	ctx, tx := gormpkg.InitTransactionToContext(ctx, u.writeDB)
+	defer tx.WithContext(ctx).Rollback()
+
+	user, err := u.userRepository.GetUserForUpdate(ctx, tokenPayload.UserID)
+	if err != nil {
+		return dto.TwoFactorEnableResponse{}, serverError.ErrGeneralDatabaseError(err)
+	}
+
+	if user.TotpEnabled {
+		return dto.TwoFactorEnableResponse{}, serverError.ErrInvalidRequest(model.ErrTwoFactorEnabled)
+	}
+
+	if user.TotpSecret == "" {
+		return dto.TwoFactorEnableResponse{}, serverError.ErrInvalidRequest(model.ErrTwoFactorNotEnrolled)
+	}
+
+	if ok, err := u.verifyTotp(&user, code); err != nil {
+		return dto.TwoFactorEnableResponse{}, err
+	} else if !ok {
+		return dto.TwoFactorEnableResponse{}, serverError.ErrInvalidTwoFactorCode(nil)
+	}
+
+	user.TotpEnabled = true
+	if err := u.userRepository.UpdateTotp(ctx, user); err != nil {
+		return dto.TwoFactorEnableResponse{}, serverError.ErrGeneralDatabaseError(err)
+	}
+
+	recoveryCodes, codeHashes, err := newRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return dto.TwoFactorEnableResponse{}, serverError.ErrGeneralError(err)
+	}
+
+	if err := u.userRepository.ReplaceRecoveryCodes(ctx, user.ID, codeHashes); err != nil {
+		return dto.TwoFactorEnableResponse{}, serverError.ErrGeneralDatabaseError(err)
+	}
+
+	if err := tx.WithContext(ctx).Commit().Error; err != nil {
+		return dto.TwoFactorEnableResponse{}, serverError.ErrGeneralDatabaseError(err)
+	}
+
+	log.Context(ctx).Infof("user %v enabled two factor authentication", user.ID)
+	return dto.TwoFactorEnableResponse{RecoveryCodes: recoveryCodes}, nil
+}
+
+// Disable removes the secret and every recovery code, a fresh code is required so a stolen session can not do it
+func (u *twoFactorUsecase) Disable(ctx context.Context, code string) error {
+	tokenPayload := jwt.GetPayloadFromContext(ctx)
+
+	ctx, tx := gormpkg.InitTransactionToContext(ctx, u.writeDB)
+	defer tx.WithContext(ctx).Rollback()
+
+	user, err := u.userRepository.GetUserForUpdate(ctx, tokenPayload.UserID)
+	if err != nil {
+		return serverError.ErrGeneralDatabaseError(err)
+	}
+
+	if !user.TotpEnabled {
+		return serverError.ErrInvalidRequest(model.ErrTwoFactorNotEnrolled)
+	}
+
+	if err := u.verifyCode(ctx, &user, code); err != nil {
+		return err
+	}
+
+	user.TotpSecret = ""
+	user.TotpEnabled = false
+	user.TotpLastStep = 0
+	if err := u.userRepository.UpdateTotp(ctx, user); err != nil {
+		return serverError.ErrGeneralDatabaseError(err)
+	}
+
+	if err := u.userRepository.ReplaceRecoveryCodes(ctx, user.ID, nil); err != nil {
+		return serverError.ErrGeneralDatabaseError(err)
+	}
+
+	if err := tx.WithContext(ctx).Commit().Error; err != nil {
+		return serverError.ErrGeneralDatabaseError(err)
+	}
+
+	log.Context(ctx).Infof("user %v disabled two factor authentication", user.ID)
+	return nil
+}
+
+// Verify checks the code of a user with 2FA enabled, the accepted TOTP step or recovery code is consumed
+// so the same code can not be used twice. It must be called outside of a running transaction.
+func (u *twoFactorUsecase) Verify(ctx context.Context, userID int, code string) error {
+	ctx, tx := gormpkg.InitTransactionToContext(ctx, u.writeDB)
+	defer tx.WithContext(ctx).Rollback()
+
+	user, err := u.userRepository.GetUserForUpdate(ctx, userID)
+	if err != nil {
+		return serverError.ErrGeneralDatabaseError(err)
+	}
+
+	if !user.TotpEnabled {
+		return nil
+	}
+
+	if err := u.verifyCode(ctx, &user, code); err != nil {
+		return err
+	}
+
+	if err := tx.WithContext(ctx).Commit().Error; err != nil {
+		return serverError.ErrGeneralDatabaseError(err)
+	}
+
+	return nil
+}
+
+// verifyCode accepts a TOTP code or, when it is not one, an unused recovery code of the locked user.
+// Once the user reached the maximum invalid codes every code is refused until the lockout expired.
+func (u *twoFactorUsecase) verifyCode(ctx context.Context, user *model.User, code string) error {
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return serverError.ErrTwoFactorRequired(nil)
+	}
+
+	userID := strconv.Itoa(user.ID)
+	failures, err := u.failureCounter.Failures(ctx, userID)
+	if err != nil {
+		return serverError.ErrGeneralError(err)
+	}
+
+	if failures >= int64(u.securityConfig.Totp.MaxAttempts) {
+		log.Context(ctx).Warnf("user %v two factor locked after %v invalid code", user.ID, failures)
+		return serverError.ErrTwoFactorLocked(nil)
+	}
+
+	ok, err := u.verifyTotp(user, code)
+	if err != nil {
+		return err
+	}
+
+	if ok {
+		if err := u.userRepository.UpdateTotp(ctx, *user); err != nil {
+			return serverError.ErrGeneralDatabaseError(err)
+		}
+		return u.resetFailures(ctx, userID)
+	}
+
+	used, err := u.userRepository.UseRecoveryCode(ctx, user.ID, hashRecoveryCode(code))
+	if err != nil {
+		return serverError.ErrGeneralDatabaseError(err)
+	}
+
+	if !used {
+		if _, err := u.failureCounter.AddFailure(ctx, userID, u.securityConfig.Totp.Lockout); err != nil {
+			return serverError.ErrGeneralError(err)
+		}
+		return serverError.ErrInvalidTwoFactorCode(nil)
+	}
+
+	log.Context(ctx).Warnf("user %v passed two factor with a recovery code", user.ID)
+	return u.resetFailures(ctx, userID)
+}
+
+func (u *twoFactorUsecase) resetFailures(ctx context.Context, userID string) error {
+	if err := u.failureCounter.Reset(ctx, userID); err != nil {
+		return serverError.ErrGeneralError(err)
+	}
+
+	return nil
+}
+
+// verifyTotp sets the last step of the user when the code is valid and newer than the last accepted one
+func (u *twoFactorUsecase) verifyTotp(user *model.User, code string) (bool, error) {
+	totpSecret, err := secret.Decrypt(u.securityConfig.Totp.EncryptionKey, user.TotpSecret)
+	if err != nil {
+		return false, serverError.ErrGeneralError(err)
+	}
+
+	step, ok := totp.Validate(totpSecret, code, time.Now())
+	if !ok || step <= user.TotpLastStep {
+		return false, nil
+	}
+
+	user.TotpLastStep = step
+	return true, nil
+}
+
+// newRecoveryCodes returns the codes shown to the user and the hashes stored for them
+func newRecoveryCodes(count int) ([]string, []string, error) {
+	codes := make([]string, 0, count)
+	hashes := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		random := make([]byte, 5)
+		if _, err := rand.Read(random); err != nil {
+			return nil, nil, err
+		}
+
+		code := base32.StdEncoding.EncodeToString(random) // 8 characters, no padding for 5 bytes
+		code = code[:4] + "-" + code[4:]
+
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode ignores case and the separator so the code can be typed back loosely
+func hashRecoveryCode(code string) string {
+	code = strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+	hash := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(hash[:])
+}
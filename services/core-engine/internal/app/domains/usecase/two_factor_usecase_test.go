@@ -0,0 +1,138 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"core-engine/config"
+	"core-engine/internal/app/domains/model"
+	serverError "core-engine/pkg/error"
+	"core-engine/pkg/secret"
+	"core-engine/pkg/totp"
+)
+
+func TestNewRecoveryCodes(t *testing.T) {
+	codes, hashes, err := newRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(codes) != recoveryCodeCount || len(hashes) != recoveryCodeCount {
+		t.Fatalf("got %v codes and %v hashes, want %v", len(codes), len(hashes), recoveryCodeCount)
+	}
+
+	seen := make(map[string]bool)
+	for i, code := range codes {
+		if len(code) != 9 || code[4] != '-' {
+			t.Errorf("code %q is not formatted as XXXX-XXXX", code)
+		}
+
+		if seen[code] {
+			t.Errorf("code %q generated twice", code)
+		}
+		seen[code] = true
+
+		if hashes[i] != hashRecoveryCode(code) {
+			t.Errorf("hash of code %q does not match", code)
+		}
+	}
+}
+
+func TestHashRecoveryCodeNormalized(t *testing.T) {
+	want := hashRecoveryCode("ABCD-EFGH")
+
+	for _, typed := range []string{"abcd-efgh", "ABCDEFGH", "abcd efgh"} {
+		if got := hashRecoveryCode(typed); got != want {
+			t.Errorf("hashRecoveryCode(%q) does not match the issued code", typed)
+		}
+	}
+
+	if hashRecoveryCode(strings.Repeat("A", 8)) == want {
+		t.Error("different code has the same hash")
+	}
+}
+
+type fakeFailureCounter map[string]int64
+
+func (c fakeFailureCounter) Failures(ctx context.Context, id string) (int64, error) {
+	return c[id], nil
+}
+
+func (c fakeFailureCounter) AddFailure(ctx context.Context, id string, window time.Duration) (int64, error) {
+	c[id]++
+	return c[id], nil
+}
+
+func (c fakeFailureCounter) Reset(ctx context.Context, id string) error {
+	delete(c, id)
+	return nil
+}
+
+// Only the calls made by verifyCode, every recovery code is unknown
+type fakeTotpRepository struct {
+	model.UserRepository
+}
+
+func (fakeTotpRepository) UpdateTotp(ctx context.Context, user model.User) error {
+	return nil
+}
+
+func (fakeTotpRepository) UseRecoveryCode(ctx context.Context, userID int, codeHash string) (bool, error) {
+	return false, nil
+}
+
+func TestVerifyCodeLockout(t *testing.T) {
+	var securityConfig config.Security
+	securityConfig.Totp.EncryptionKey = "test"
+	securityConfig.Totp.MaxAttempts = 3
+
+	totpSecret, err := totp.NewSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user := model.User{ID: 1, TotpEnabled: true}
+	if user.TotpSecret, err = secret.Encrypt(securityConfig.Totp.EncryptionKey, totpSecret); err != nil {
+		t.Fatal(err)
+	}
+
+	failures := fakeFailureCounter{}
+	u := NewTwoFactorUsecase(nil, securityConfig, fakeTotpRepository{}, failures)
+
+	validCode, err := totp.Code(totpSecret, totp.Step(time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for attempt := 1; attempt <= securityConfig.Totp.MaxAttempts; attempt++ {
+		if err := u.verifyCode(context.Background(), &user, "ABCD-EFGH"); errorCode(err) != 905 {
+			t.Fatalf("attempt %v: expected invalid code, got %v", attempt, err)
+		}
+	}
+
+	// Locked, even the valid code is refused
+	if err := u.verifyCode(context.Background(), &user, validCode); errorCode(err) != 906 {
+		t.Fatalf("expected locked two factor, got %v", err)
+	}
+
+	// Below the limit, the valid code is accepted and the count starts again
+	failures["1"] = 2
+	if err := u.verifyCode(context.Background(), &user, validCode); err != nil {
+		t.Fatalf("valid code after lockout: %v", err)
+	}
+	if failures["1"] != 0 {
+		t.Fatalf("accepted code must reset the failures, got %v", failures["1"])
+	}
+}
+
+func errorCode(err error) int {
+	var serverErr serverError.ServerError
+	if errors.As(err, &serverErr) {
+		return serverErr.Code
+	}
+
+	return 0
+}
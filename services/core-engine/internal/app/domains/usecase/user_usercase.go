@@ -2,38 +2,57 @@ package usecase
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"time"
 
 	"github.com/gerins/log"
 	"github.com/go-playground/validator/v10"
-	"github.com/golang-jwt/jwt"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 
 	"core-engine/config"
 	"core-engine/internal/app/domains/dto"
 	"core-engine/internal/app/domains/model"
 	serverError "core-engine/pkg/error"
+	gormpkg "core-engine/pkg/gorm"
+	"core-engine/pkg/jwt"
 )
 
 type userUsecase struct {
+	writeDB          *gorm.DB
 	validator        *validator.Validate
 	securityConfig   config.Security
 	userRepository   model.UserRepository
 	walletRepository model.WalletRepository
+	ledgerRepository model.LedgerRepository
+	twoFactorUsecase model.TwoFactorUsecase
+	denyList         jwt.DenyList
 }
 
 // NewUserUsecase returns new user userUsecase.
 func NewUserUsecase(
+	writeDB *gorm.DB,
 	validator *validator.Validate,
 	securityConfig config.Security,
 	userRepository model.UserRepository,
 	walletRepository model.WalletRepository,
+	ledgerRepository model.LedgerRepository,
+	twoFactorUsecase model.TwoFactorUsecase,
+	denyList jwt.DenyList,
 ) *userUsecase {
 	return &userUsecase{
+		writeDB:          writeDB,
 		validator:        validator,
 		securityConfig:   securityConfig,
 		userRepository:   userRepository,
 		walletRepository: walletRepository,
+		ledgerRepository: ledgerRepository,
+		twoFactorUsecase: twoFactorUsecase,
+		denyList:         denyList,
 	}
 }
 
@@ -50,30 +69,232 @@ func (u *userUsecase) Login(ctx context.Context, loginReq dto.LoginRequest) (dto
 		return dto.LoginResponse{}, serverError.ErrInvalidUsernameOrPassword(err)
 	}
 
-	// Create a new token object
-	token := jwt.New(jwt.SigningMethodHS256)
+	// Check account status
+	if !userDetail.Status {
+		return dto.LoginResponse{}, serverError.ErrUserBlocked(nil) // User deactivated or frozen
+	}
+
+	// Only checked after the password, a wrong password never tells whether 2FA is enabled
+	if err := u.twoFactorUsecase.Verify(ctx, userDetail.ID, loginReq.TotpCode); err != nil {
+		return dto.LoginResponse{}, err
+	}
+
+	familyID, err := jwt.NewTokenID()
+	if err != nil {
+		return dto.LoginResponse{}, serverError.ErrGeneralError(err)
+	}
+
+	return u.issueTokens(ctx, userDetail, familyID)
+}
+
+// Refresh exchanges a refresh token for a new access and refresh token, the presented token can not be used again
+func (u *userUsecase) Refresh(ctx context.Context, refreshReq dto.RefreshRequest) (dto.LoginResponse, error) {
+	ctx, tx := gormpkg.InitTransactionToContext(ctx, u.writeDB)
+	defer tx.WithContext(ctx).Rollback()
+
+	refreshToken, err := u.userRepository.GetRefreshTokenForUpdate(ctx, hashRefreshToken(refreshReq.RefreshToken))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return dto.LoginResponse{}, serverError.ErrInvalidRefreshToken(err)
+	}
+	if err != nil {
+		return dto.LoginResponse{}, serverError.ErrGeneralDatabaseError(err)
+	}
+
+	// Already rotated, either the user or an attacker holds a stolen copy, end the whole login
+	if refreshToken.RevokedAt != nil {
+		log.Context(ctx).Warnf("refresh token reused, revoking family %v of user %v", refreshToken.FamilyID, refreshToken.UserID)
+		if err := u.revokeFamily(ctx, refreshToken.FamilyID); err != nil {
+			return dto.LoginResponse{}, err
+		}
+
+		if err := tx.WithContext(ctx).Commit().Error; err != nil {
+			return dto.LoginResponse{}, serverError.ErrGeneralDatabaseError(err)
+		}
+
+		return dto.LoginResponse{}, serverError.ErrInvalidRefreshToken(model.ErrRefreshTokenReused)
+	}
+
+	if time.Now().After(refreshToken.ExpiresAt) {
+		return dto.LoginResponse{}, serverError.ErrInvalidRefreshToken(model.ErrRefreshTokenExpired)
+	}
+
+	userDetail, err := u.userRepository.FindUserByID(ctx, refreshToken.UserID)
+	if err != nil {
+		return dto.LoginResponse{}, serverError.ErrGeneralDatabaseError(err)
+	}
+
+	if !userDetail.Status {
+		return dto.LoginResponse{}, serverError.ErrUserBlocked(nil)
+	}
+
+	now := time.Now()
+	refreshToken.RevokedAt = &now
+	if _, err := u.userRepository.SaveRefreshToken(ctx, refreshToken); err != nil {
+		return dto.LoginResponse{}, serverError.ErrGeneralDatabaseError(err)
+	}
+
+	loginResponse, err := u.issueTokens(ctx, userDetail, refreshToken.FamilyID)
+	if err != nil {
+		return dto.LoginResponse{}, err
+	}
+
+	if err := tx.WithContext(ctx).Commit().Error; err != nil {
+		return dto.LoginResponse{}, serverError.ErrGeneralDatabaseError(err)
+	}
+
+	return loginResponse, nil
+}
+
+// Logout revokes the access token of the context and every refresh token of the same login
+func (u *userUsecase) Logout(ctx context.Context) error {
+	tokenPayload := jwt.GetPayloadFromContext(ctx)
+
+	if err := u.denyList.Deny(ctx, tokenPayload.ID, time.Unix(tokenPayload.Exp, 0)); err != nil {
+		return serverError.ErrGeneralError(err)
+	}
+
+	refreshToken, err := u.userRepository.GetRefreshTokenByAccessTokenID(ctx, tokenPayload.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil // Access token already refreshed, its refresh token is revoked
+	}
+	if err != nil {
+		return serverError.ErrGeneralDatabaseError(err)
+	}
+
+	return u.revokeFamily(ctx, refreshToken.FamilyID)
+}
+
+// ChangePassword requires the old password and a fresh 2FA code, every session of the user is ended after it
+func (u *userUsecase) ChangePassword(ctx context.Context, passwordReq dto.ChangePasswordRequest) error {
+	tokenPayload := jwt.GetPayloadFromContext(ctx)
+
+	if passwordReq.NewPassword == "" {
+		return serverError.ErrInvalidRequest(nil)
+	}
+
+	userDetail, err := u.userRepository.FindUserByID(ctx, tokenPayload.UserID)
+	if err != nil {
+		return serverError.ErrGeneralDatabaseError(err)
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(userDetail.Password), []byte(passwordReq.OldPassword)); err != nil {
+		return serverError.ErrInvalidUsernameOrPassword(err)
+	}
+
+	if err := u.twoFactorUsecase.Verify(ctx, userDetail.ID, passwordReq.TotpCode); err != nil {
+		return err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(passwordReq.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return serverError.ErrGeneralError(err)
+	}
+
+	ctx, tx := gormpkg.InitTransactionToContext(ctx, u.writeDB)
+	defer tx.WithContext(ctx).Rollback()
+
+	if err := u.userRepository.UpdatePassword(ctx, userDetail.ID, string(hashedPassword)); err != nil {
+		return serverError.ErrGeneralDatabaseError(err)
+	}
+
+	revoked, err := u.userRepository.RevokeUserRefreshTokens(ctx, userDetail.ID)
+	if err != nil {
+		return serverError.ErrGeneralDatabaseError(err)
+	}
+
+	if err := denyAccessTokens(ctx, u.denyList, revoked, u.securityConfig.Jwt.Duration); err != nil {
+		return err
+	}
+
+	// The current access token may belong to a login already refreshed away, deny it as well
+	if err := u.denyList.Deny(ctx, tokenPayload.ID, time.Unix(tokenPayload.Exp, 0)); err != nil {
+		return serverError.ErrGeneralError(err)
+	}
 
-	// Set the claims for the token
-	claims := token.Claims.(jwt.MapClaims)
-	claims["userId"] = userDetail.ID
-	claims["email"] = userDetail.Email
-	claims["exp"] = time.Now().Add(u.securityConfig.Jwt.Duration).Unix()
+	if err := tx.WithContext(ctx).Commit().Error; err != nil {
+		return serverError.ErrGeneralDatabaseError(err)
+	}
 
-	// Generate the token string
-	tokenString, err := token.SignedString([]byte(u.securityConfig.Jwt.Key))
+	log.Context(ctx).Infof("user %v changed password", userDetail.ID)
+	return nil
+}
+
+// issueTokens signs a short lived access token and stores a new refresh token of the login family
+func (u *userUsecase) issueTokens(ctx context.Context, userDetail model.User, familyID string) (dto.LoginResponse, error) {
+	now := time.Now()
+
+	tokenString, accessToken, err := jwt.Generate(jwt.Payload{
+		UserID: userDetail.ID,
+		Email:  userDetail.Email,
+		Role:   userDetail.Role,
+		Exp:    now.Add(u.securityConfig.Jwt.Duration).Unix(),
+	}, []byte(u.securityConfig.Jwt.Key))
 	if err != nil {
 		log.Context(ctx).Errorf("failed generate token string, %v", err)
-		return dto.LoginResponse{}, serverError.ErrInvalidUsernameOrPassword(err)
+		return dto.LoginResponse{}, serverError.ErrGeneralError(err)
+	}
+
+	refreshToken, err := newRefreshToken()
+	if err != nil {
+		return dto.LoginResponse{}, serverError.ErrGeneralError(err)
+	}
+
+	_, err = u.userRepository.SaveRefreshToken(ctx, model.RefreshToken{
+		UserID:        userDetail.ID,
+		FamilyID:      familyID,
+		TokenHash:     hashRefreshToken(refreshToken),
+		AccessTokenID: accessToken.ID,
+		ExpiresAt:     now.Add(u.securityConfig.Jwt.RefreshDuration),
+	})
+	if err != nil {
+		return dto.LoginResponse{}, serverError.ErrGeneralDatabaseError(err)
 	}
 
 	loginResponse := dto.LoginResponse{
-		Email: userDetail.Email,
-		Token: tokenString,
+		Email:        userDetail.Email,
+		Token:        tokenString,
+		ExpiresAt:    accessToken.Exp,
+		RefreshToken: refreshToken,
 	}
 
 	return loginResponse, nil
 }
 
+// revokeFamily revokes every refresh token of a login and denies the access token issued with them
+func (u *userUsecase) revokeFamily(ctx context.Context, familyID string) error {
+	revoked, err := u.userRepository.RevokeRefreshTokenFamily(ctx, familyID)
+	if err != nil {
+		return serverError.ErrGeneralDatabaseError(err)
+	}
+
+	return denyAccessTokens(ctx, u.denyList, revoked, u.securityConfig.Jwt.Duration)
+}
+
+// denyAccessTokens denies the access token issued with each refresh token until it expires
+func denyAccessTokens(ctx context.Context, denyList jwt.DenyList, tokens []model.RefreshToken, accessDuration time.Duration) error {
+	for _, token := range tokens {
+		if err := denyList.Deny(ctx, token.AccessTokenID, token.CreatedAt.Add(accessDuration)); err != nil {
+			return serverError.ErrGeneralError(err)
+		}
+	}
+
+	return nil
+}
+
+func newRefreshToken() (string, error) {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(token), nil
+}
+
+func hashRefreshToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
 func (u *userUsecase) Register(ctx context.Context, registerReq dto.RegisterRequest) (model.User, error) {
 	// Hashing the password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(registerReq.Password), bcrypt.DefaultCost)
@@ -91,6 +312,9 @@ func (u *userUsecase) Register(ctx context.Context, registerReq dto.RegisterRequ
 		Status:      true, // Active
 	}
 
+	ctx, tx := gormpkg.InitTransactionToContext(ctx, u.writeDB)
+	defer tx.WithContext(ctx).Rollback()
+
 	newUser, err = u.userRepository.RegisterNewUser(ctx, newUser)
 	if err != nil {
 		return model.User{}, err
@@ -101,33 +325,38 @@ func (u *userUsecase) Register(ctx context.Context, registerReq dto.RegisterRequ
 		return model.User{}, err
 	}
 
+	if err := tx.WithContext(ctx).Commit().Error; err != nil {
+		log.Context(ctx).Error(err)
+		return model.User{}, serverError.ErrGeneralDatabaseError(err)
+	}
+
 	return newUser, nil
 }
 
+// Initial balance is deposited from outside of the exchange so the wallet stay a projection of the ledger
 func (u *userUsecase) injectInitialBalance(ctx context.Context, userID int) error {
+	const initialBalance = 1000000000
+
 	pair, err := u.walletRepository.GetPairDetail(ctx, "DOGEIDRT")
 	if err != nil {
 		return err
 	}
 
-	primaryWallet := model.Wallet{
-		UserID:   userID,
-		CryptoID: pair.PrimaryCryptoID,
-		Quantity: 1000000000,
-	}
+	journal := model.NewLedgerJournal(model.LedgerEntryDeposit, model.LedgerReferenceUser)
+	journal.ReferenceID = userID
+	ctx = model.SaveLedgerJournalToContext(ctx, journal)
 
-	secondaryWallet := model.Wallet{
-		UserID:   userID,
-		CryptoID: pair.SecondaryCryptoID,
-		Quantity: 1000000000,
-	}
+	for _, cryptoID := range []int{pair.PrimaryCryptoID, pair.SecondaryCryptoID} {
+		if err := u.walletRepository.Save(ctx, model.Wallet{UserID: userID, CryptoID: cryptoID}); err != nil {
+			return err
+		}
 
-	if err := u.walletRepository.Save(ctx, primaryWallet); err != nil {
-		return err
-	}
-	if err := u.walletRepository.Save(ctx, secondaryWallet); err != nil {
-		return err
+		if err := u.walletRepository.CreditBalance(ctx, userID, cryptoID, initialBalance); err != nil {
+			return err
+		}
+
+		journal.PostExternal(cryptoID, initialBalance)
 	}
 
-	return nil
+	return u.ledgerRepository.SaveJournal(ctx, journal)
 }
@@ -0,0 +1,133 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"core-engine/internal/app/domains/model"
+	serverError "core-engine/pkg/error"
+	"core-engine/pkg/jwt"
+)
+
+// Every holding is valued in this crypto
+const portfolioQuoteSymbol = "IDRT"
+
+type walletUsecase struct {
+	walletRepository model.WalletRepository
+}
+
+// NewWalletUsecase returns new wallet usecase.
+func NewWalletUsecase(walletRepository model.WalletRepository) *walletUsecase {
+	return &walletUsecase{
+		walletRepository: walletRepository,
+	}
+}
+
+// GetBalances returns the balance of the logged in user for every active crypto
+func (u *walletUsecase) GetBalances(ctx context.Context) ([]model.Balance, error) {
+	tokenPayload := jwt.GetPayloadFromContext(ctx)
+
+	balances, err := u.walletRepository.GetUserBalances(ctx, tokenPayload.UserID)
+	if err != nil {
+		return nil, serverError.ErrGeneralDatabaseError(err)
+	}
+
+	return balances, nil
+}
+
+// GetPortfolio values every holding of the logged in user with the last trade price against IDRT
+func (u *walletUsecase) GetPortfolio(ctx context.Context) (model.Portfolio, error) {
+	balances, err := u.GetBalances(ctx)
+	if err != nil {
+		return model.Portfolio{}, err
+	}
+
+	tickers, err := u.walletRepository.GetPairTickers(ctx, time.Now().Add(-24*time.Hour).Unix())
+	if err != nil {
+		return model.Portfolio{}, serverError.ErrGeneralDatabaseError(err)
+	}
+
+	return buildPortfolio(balances, tickers, portfolioQuoteSymbol), nil
+}
+
+// buildPortfolio values each balance with the pair traded against the quote crypto, crypto without
+// such pair is listed with zero value
+func buildPortfolio(balances []model.Balance, tickers []model.PairTicker, quoteSymbol string) model.Portfolio {
+	quoteCryptoID := 0
+	for _, balance := range balances {
+		if balance.Symbol == quoteSymbol {
+			quoteCryptoID = balance.CryptoID
+		}
+	}
+
+	tickerByCrypto := make(map[int]model.PairTicker)
+	for _, ticker := range tickers {
+		if ticker.SecondaryCryptoID == quoteCryptoID && ticker.LastPrice > 0 {
+			tickerByCrypto[ticker.PrimaryCryptoID] = ticker
+		}
+	}
+
+	portfolio := model.Portfolio{QuoteSymbol: quoteSymbol, Assets: make([]model.PortfolioAsset, 0, len(balances))}
+
+	var openValue float64
+	for _, balance := range balances {
+		asset := model.PortfolioAsset{
+			CryptoID: balance.CryptoID,
+			Symbol:   balance.Symbol,
+			Quantity: balance.Available + balance.Locked,
+		}
+
+		openPrice := 0.0
+		if balance.CryptoID == quoteCryptoID {
+			asset.Price, openPrice = 1, 1
+		} else if ticker, ok := tickerByCrypto[balance.CryptoID]; ok {
+			asset.Price, openPrice = ticker.LastPrice, ticker.OpenPrice
+		}
+
+		asset.Value = asset.Quantity * asset.Price
+		asset.Change24h = asset.Value - asset.Quantity*openPrice
+		if openPrice > 0 {
+			asset.ChangePercent24h = (asset.Price - openPrice) / openPrice * 100
+		}
+
+		portfolio.TotalValue += asset.Value
+		openValue += asset.Quantity * openPrice
+		portfolio.Assets = append(portfolio.Assets, asset)
+	}
+
+	portfolio.Change24h = portfolio.TotalValue - openValue
+	if openValue > 0 {
+		portfolio.ChangePercent24h = portfolio.Change24h / openValue * 100
+	}
+
+	return portfolio
+}
+
+// creditWallet add to the available balance, the wallet is created on the first credit of the crypto
+func creditWallet(ctx context.Context, walletRepository model.WalletRepository, userID, cryptoID int, amount float64) error {
+	return withWallet(ctx, walletRepository, userID, cryptoID, func() error {
+		return walletRepository.CreditBalance(ctx, userID, cryptoID, amount)
+	})
+}
+
+// applyBalanceChange add the net change of a batch, the wallet is created when the change only adds to it
+func applyBalanceChange(ctx context.Context, walletRepository model.WalletRepository, change model.BalanceChange) error {
+	return withWallet(ctx, walletRepository, change.UserID, change.CryptoID, func() error {
+		return walletRepository.ApplyBalanceChange(ctx, change)
+	})
+}
+
+// withWallet runs the balance update, when the user has no wallet of the crypto yet it is created and the update runs again
+func withWallet(ctx context.Context, walletRepository model.WalletRepository, userID, cryptoID int, update func() error) error {
+	err := update()
+	if !errors.Is(err, model.ErrWalletNotFound) {
+		return err
+	}
+
+	if err := walletRepository.Save(ctx, model.Wallet{UserID: userID, CryptoID: cryptoID}); err != nil {
+		return err
+	}
+
+	return update()
+}
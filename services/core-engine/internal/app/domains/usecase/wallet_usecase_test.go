@@ -0,0 +1,42 @@
+package usecase
+
+import (
+	"testing"
+
+	"core-engine/internal/app/domains/model"
+)
+
+func TestBuildPortfolio(t *testing.T) {
+	const idrt, btc, doge, ada = 1, 2, 6, 5
+
+	balances := []model.Balance{
+		{CryptoID: idrt, Symbol: "IDRT", Available: 1000, Locked: 500},
+		{CryptoID: btc, Symbol: "BTC", Available: 1, Locked: 1},
+		{CryptoID: ada, Symbol: "ADA", Available: 10}, // No pair against IDRT
+		{CryptoID: doge, Symbol: "DOGE", Available: 100},
+	}
+	tickers := []model.PairTicker{
+		{PairID: 1, PrimaryCryptoID: btc, SecondaryCryptoID: idrt, LastPrice: 1100, OpenPrice: 1000},
+		{PairID: 2, PrimaryCryptoID: doge, SecondaryCryptoID: idrt, LastPrice: 2, OpenPrice: 2},
+	}
+
+	got := buildPortfolio(balances, tickers, "IDRT")
+
+	want := []model.PortfolioAsset{
+		{CryptoID: idrt, Symbol: "IDRT", Quantity: 1500, Price: 1, Value: 1500},
+		{CryptoID: btc, Symbol: "BTC", Quantity: 2, Price: 1100, Value: 2200, Change24h: 200, ChangePercent24h: 10},
+		{CryptoID: ada, Symbol: "ADA", Quantity: 10},
+		{CryptoID: doge, Symbol: "DOGE", Quantity: 100, Price: 2, Value: 200},
+	}
+
+	for i := range want {
+		if got.Assets[i] != want[i] {
+			t.Fatalf("asset %v\n got %+v\nwant %+v", i, got.Assets[i], want[i])
+		}
+	}
+
+	// 3900 now, 3700 a day ago
+	if got.TotalValue != 3900 || got.Change24h != 200 || got.ChangePercent24h != 200.0/3700*100 {
+		t.Fatalf("unexpected total %+v", got)
+	}
+}
@@ -1,6 +1,8 @@
 package app
 
 import (
+	"context"
+
 	"github.com/gerins/log"
 	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
@@ -10,6 +12,7 @@ import (
 	"core-engine/internal/app/domains/handler"
 	"core-engine/internal/app/domains/repository"
 	"core-engine/internal/app/domains/usecase"
+	"core-engine/pkg/chain"
 	"core-engine/pkg/gorm"
 	"core-engine/pkg/kafka"
 	"core-engine/pkg/redis"
@@ -17,40 +20,83 @@ import (
 
 func Init(e *echo.Echo, g *grpc.Server, cfg *config.Config) chan bool {
 	var (
-		exitSignal         = make(chan bool)
-		validator          = validator.New()
-		apiTimeout         = cfg.App.HTTP.CtxTimeout
-		redisCache         = redis.Init(cfg.Dependencies.Cache)
-		redisLock          = redis.InitLock(redisCache)
-		readDatabase       = gorm.InitPostgres(cfg.Dependencies.Database.Read)
-		writeDatabase      = gorm.InitPostgres(cfg.Dependencies.Database.Write)
-		matchOrderConsumer = kafka.NewConsumer(cfg.Dependencies.MessageBroker, cfg.Dependencies.MessageBroker.Consumer.Topic.MatchOrder)
-		producer, writer   = kafka.NewProducer(cfg.Dependencies.MessageBroker.Brokers)
+		exitSignal          = make(chan bool)
+		validator           = validator.New()
+		apiTimeout          = cfg.App.HTTP.CtxTimeout
+		redisCache          = redis.Init(cfg.Dependencies.Cache)
+		redisLock           = redis.InitLock(redisCache)
+		denyList            = redis.NewTokenDenyList(redisCache)
+		twoFactorFailures   = redis.NewFailureCounter(redisCache, "totp")
+		readDatabase        = gorm.InitPostgres(cfg.Dependencies.Database.Read)
+		writeDatabase       = gorm.InitPostgres(cfg.Dependencies.Database.Write)
+		matchOrderConsumer  = kafka.NewConsumer(cfg.Dependencies.MessageBroker, cfg.Dependencies.MessageBroker.Consumer.Topic.MatchOrder)
+		orderUpdateConsumer = kafka.NewConsumer(cfg.Dependencies.MessageBroker, cfg.Dependencies.MessageBroker.Consumer.Topic.OrderUpdate)
+		producer, writer    = kafka.NewProducer(cfg.Dependencies.MessageBroker.Brokers)
+		retry               = kafka.NewRetryProcessor(writer, cfg.Dependencies.MessageBroker.Retry)
+		topicManager        = kafka.NewTopicManager(cfg.Dependencies.MessageBroker.Brokers)
+		chainAdapter        = initChainAdapter(cfg.Chain)
+		chainSyncCtx, stop  = context.WithCancel(context.Background())
 	)
 
+	// Matching engines replay the registry from the start, it must keep the last state of every pair
+	if err := topicManager.CreateCompactedTopic(context.Background(), cfg.Dependencies.MessageBroker.Producer.Topic.PairRegistry); err != nil {
+		log.Errorf("failed creating pair registry topic, %v", err)
+	}
+
 	// Repository
 	userRepository := repository.NewUserRepository(readDatabase, writeDatabase)
 	orderRepository := repository.NewOrderRepository(readDatabase, writeDatabase)
 	walletRepository := repository.NewWalletRepository(readDatabase, writeDatabase)
+	feeRepository := repository.NewFeeRepository(readDatabase, writeDatabase)
+	ledgerRepository := repository.NewLedgerRepository(readDatabase, writeDatabase)
+	fundingRepository := repository.NewFundingRepository(readDatabase, writeDatabase)
+	transferRepository := repository.NewTransferRepository(readDatabase, writeDatabase)
+	marketRepository := repository.NewMarketRepository(readDatabase, writeDatabase)
+	apiKeyRepository := repository.NewApiKeyRepository(readDatabase, writeDatabase)
 
 	// Usecase
-	userUsecase := usecase.NewUserUsecase(validator, cfg.Security, userRepository, walletRepository)
-	orderUsecase := usecase.NewOrderUsecase(writeDatabase, redisLock, producer, validator, orderRepository, userRepository, walletRepository)
+	twoFactorUsecase := usecase.NewTwoFactorUsecase(writeDatabase, cfg.Security, userRepository, twoFactorFailures)
+	userUsecase := usecase.NewUserUsecase(writeDatabase, validator, cfg.Security, userRepository, walletRepository, ledgerRepository, twoFactorUsecase, denyList)
+	orderUsecase := usecase.NewOrderUsecase(writeDatabase, redisLock, producer, validator, orderRepository, userRepository, walletRepository, feeRepository, ledgerRepository, cfg.Fee)
+	ledgerUsecase := usecase.NewLedgerUsecase(ledgerRepository)
+	tradeUsecase := usecase.NewTradeUsecase(orderRepository, walletRepository)
+	walletUsecase := usecase.NewWalletUsecase(walletRepository)
+	adminUsecase := usecase.NewAdminUsecase(writeDatabase, cfg.Security, userRepository, walletRepository, ledgerRepository, denyList)
+	transferUsecase := usecase.NewTransferUsecase(writeDatabase, redisLock, transferRepository, userRepository, walletRepository, ledgerRepository, twoFactorUsecase)
+	marketUsecase := usecase.NewMarketUsecase(writeDatabase, producer, topicManager, marketRepository, walletRepository, cfg.Dependencies.MessageBroker, cfg.Fee)
+	apiKeyUsecase := usecase.NewApiKeyUsecase(cfg.Security, apiKeyRepository, userRepository, twoFactorUsecase)
+	fundingUsecase := usecase.NewFundingUsecase(writeDatabase, chainAdapter, fundingRepository, userRepository, walletRepository, ledgerRepository, twoFactorUsecase, cfg.Chain)
 
 	// Handler
-	handler.NewUserHandler(userUsecase, apiTimeout).InitRoutes(e)
-	handler.NewOrderHTTPHandler(orderUsecase, apiTimeout, cfg.Security).InitRoutes(e)
-	handler.NewOrderQueueHandler(matchOrderConsumer, orderUsecase, apiTimeout).StartConsumer()
+	handler.NewUserHandler(userUsecase, apiTimeout, cfg.Security, denyList).InitRoutes(e)
+	handler.NewTwoFactorHTTPHandler(twoFactorUsecase, apiTimeout, cfg.Security, denyList).InitRoutes(e)
+	handler.NewOrderHTTPHandler(orderUsecase, apiTimeout, cfg.Security, denyList, apiKeyUsecase).InitRoutes(e)
+	handler.NewLedgerHTTPHandler(ledgerUsecase, apiTimeout, cfg.Security, denyList, apiKeyUsecase).InitRoutes(e)
+	handler.NewTradeHTTPHandler(tradeUsecase, apiTimeout, cfg.Security, denyList, apiKeyUsecase).InitRoutes(e)
+	handler.NewWalletHTTPHandler(walletUsecase, apiTimeout, cfg.Security, denyList, apiKeyUsecase).InitRoutes(e)
+	handler.NewAdminHTTPHandler(adminUsecase, apiTimeout, cfg.Security, denyList).InitRoutes(e)
+	handler.NewTransferHTTPHandler(transferUsecase, apiTimeout, cfg.Security, denyList, apiKeyUsecase).InitRoutes(e)
+	handler.NewMarketHTTPHandler(marketUsecase, apiTimeout, cfg.Security, denyList).InitRoutes(e)
+	handler.NewFundingHTTPHandler(fundingUsecase, apiTimeout, cfg.Security, denyList, apiKeyUsecase).InitRoutes(e)
+	handler.NewApiKeyHTTPHandler(apiKeyUsecase, apiTimeout, cfg.Security, denyList).InitRoutes(e)
+	handler.NewChainSyncHandler(fundingUsecase, cfg.Chain.PollInterval, apiTimeout).StartSync(chainSyncCtx)
+	handler.NewOrderQueueHandler(matchOrderConsumer, orderUpdateConsumer, retry, redisCache, orderUsecase, cfg.Dependencies.MessageBroker.Consumer.Batch, apiTimeout).StartConsumer()
 
 	// Graceful shutdown
 	go func() {
 		<-exitSignal // Receive exit signal
 		log.Info("disconnecting service dependencies")
 
+		stop() // Stop chain sync
+
 		if err := matchOrderConsumer.Close(); err != nil {
 			log.Error(err)
 		}
 
+		if err := orderUpdateConsumer.Close(); err != nil {
+			log.Error(err)
+		}
+
 		if err := writer.Close(); err != nil {
 			log.Error(err)
 		}
@@ -77,3 +123,13 @@ func Init(e *echo.Echo, g *grpc.Server, cfg *config.Config) chan bool {
 
 	return exitSignal
 }
+
+func initChainAdapter(cfg config.Chain) chain.Adapter {
+	switch cfg.Adapter {
+	case "fake":
+		return chain.NewFake()
+	default:
+		log.Fatalf("unknown chain adapter %q", cfg.Adapter)
+		return nil
+	}
+}
@@ -0,0 +1,94 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"io"
+
+	"github.com/gerins/log"
+	"github.com/labstack/echo/v4"
+	"github.com/spf13/cast"
+
+	"core-engine/pkg/apikey"
+	serverError "core-engine/pkg/error"
+	"core-engine/pkg/jwt"
+	"core-engine/pkg/response"
+)
+
+const (
+	HeaderApiKey       = "X-API-KEY"
+	HeaderApiTimestamp = "X-API-TIMESTAMP" // Unix millisecond
+	HeaderApiSignature = "X-API-SIGNATURE"
+)
+
+type ApiKeyAuthenticator interface {
+	Authenticate(ctx context.Context, request apikey.Request) (jwt.Payload, error)
+}
+
+// ValidateApiKey accepts a request signed with an API key and fills the same payload as ValidateJwtToken,
+// request without the API key header is passed to the fallback middleware
+func ValidateApiKey(authenticator ApiKeyAuthenticator, fallback echo.MiddlewareFunc) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		withFallback := fallback(next)
+
+		return func(c echo.Context) error {
+			keyID := c.Request().Header.Get(HeaderApiKey)
+			if keyID == "" {
+				return withFallback(c)
+			}
+
+			// Get parent context from Echo Locals
+			ctx, ok := c.Get("ctx").(context.Context)
+			if !ok {
+				ctx = context.Background()
+			}
+
+			// The body is signed, read it then put it back for the handler
+			body, err := io.ReadAll(c.Request().Body)
+			if err != nil {
+				return response.Failed(c, serverError.ErrInvalidRequest(err))
+			}
+			c.Request().Body = io.NopCloser(bytes.NewReader(body))
+
+			payload, err := authenticator.Authenticate(ctx, apikey.Request{
+				KeyID:     keyID,
+				Timestamp: cast.ToInt64(c.Request().Header.Get(HeaderApiTimestamp)),
+				Signature: c.Request().Header.Get(HeaderApiSignature),
+				Method:    c.Request().Method,
+				Path:      c.Request().URL.RequestURI(),
+				Body:      body,
+				ClientIP:  c.RealIP(),
+			})
+			if err != nil {
+				log.Context(ctx).Error(err)
+				return response.Failed(c, err)
+			}
+
+			ctx = jwt.SavePayloadToContext(ctx, payload)
+
+			c.Set("ctx", ctx)
+			return next(c)
+		}
+	}
+}
+
+// ValidateScope requires the scope from a request authenticated by API key, a login session has every scope
+func ValidateScope(scope string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			// Get parent context from Echo Locals
+			ctx, ok := c.Get("ctx").(context.Context)
+			if !ok {
+				ctx = context.Background()
+			}
+
+			payload := jwt.GetPayloadFromContext(ctx)
+			if payload.Scopes == "" || apikey.HasScope(payload.Scopes, scope) {
+				return next(c)
+			}
+
+			log.Context(ctx).Warn("api key scope not granted")
+			return response.Failed(c, serverError.ErrUnauthorized(nil))
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"core-engine/pkg/apikey"
+	"core-engine/pkg/jwt"
+)
+
+type authenticatorFunc func(ctx context.Context, request apikey.Request) (jwt.Payload, error)
+
+func (f authenticatorFunc) Authenticate(ctx context.Context, request apikey.Request) (jwt.Payload, error) {
+	return f(ctx, request)
+}
+
+func TestValidateApiKeyClientIP(t *testing.T) {
+	tests := []struct {
+		name           string
+		trustedProxies []string
+		remoteAddr     string
+		want           string
+	}{
+		{"spoofed header without proxy", nil, "203.0.113.7:4000", "203.0.113.7"},
+		{"spoofed header from untrusted peer", []string{"10.0.0.0/8"}, "203.0.113.7:4000", "203.0.113.7"},
+		{"header from trusted proxy", []string{"10.0.0.0/8"}, "10.1.2.3:4000", "198.51.100.1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			e := echo.New()
+
+			ipExtractor, err := ClientIPExtractor(test.trustedProxies)
+			if err != nil {
+				t.Fatal(err)
+			}
+			e.IPExtractor = ipExtractor
+
+			var clientIP string
+			authenticator := authenticatorFunc(func(ctx context.Context, request apikey.Request) (jwt.Payload, error) {
+				clientIP = request.ClientIP
+				return jwt.Payload{}, nil
+			})
+			fallback := func(next echo.HandlerFunc) echo.HandlerFunc { return next }
+
+			e.GET("/", func(c echo.Context) error { return c.NoContent(http.StatusOK) }, ValidateApiKey(authenticator, fallback))
+
+			request := httptest.NewRequest(http.MethodGet, "/", nil)
+			request.RemoteAddr = test.remoteAddr
+			request.Header.Set(HeaderApiKey, "key")
+			request.Header.Set(echo.HeaderXForwardedFor, "198.51.100.1")
+			request.Header.Set(echo.HeaderXRealIP, "198.51.100.1")
+
+			e.ServeHTTP(httptest.NewRecorder(), request)
+
+			if clientIP != test.want {
+				t.Fatalf("client IP %q, want %q", clientIP, test.want)
+			}
+		})
+	}
+
+	if _, err := ClientIPExtractor([]string{"10.0.0.0"}); err == nil {
+		t.Fatal("trusted proxy without prefix length must be rejected")
+	}
+}
@@ -12,7 +12,8 @@ import (
 	"core-engine/pkg/response"
 )
 
-func ValidateJwtToken(secretKey []byte) echo.MiddlewareFunc {
+// ValidateJwtToken accepts a signed, unexpired access token whose ID is not in the deny list
+func ValidateJwtToken(secretKey []byte, denyList jwt.DenyList) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			// Get parent context from Echo Locals
@@ -39,6 +40,18 @@ func ValidateJwtToken(secretKey []byte) echo.MiddlewareFunc {
 				return response.Failed(c, serverError.ErrUnauthorized(err))
 			}
 
+			// Fail closed, a revoked token must not pass while the deny list is unreachable
+			denied, err := denyList.IsDenied(ctx, payload.ID)
+			if err != nil {
+				log.Context(ctx).Error(err)
+				return response.Failed(c, serverError.ErrUnauthorized(err))
+			}
+
+			if denied {
+				log.Context(ctx).Warn("revoked token")
+				return response.Failed(c, serverError.ErrUnauthorized(jwt.ErrTokenRevoked))
+			}
+
 			ctx = jwt.SavePayloadToContext(ctx, payload)
 
 			c.Set("ctx", ctx)
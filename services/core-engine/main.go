@@ -34,6 +34,12 @@ func main() {
 		HideSensitiveData: cfg.App.Logging.HideSensitiveData,
 	})
 
+	// Admin command, replay the dead-letter messages then exit
+	if len(os.Args) > 1 && os.Args[1] == "dlq-replay" {
+		cmd.ReplayDeadLetter(cfg, os.Args[2:])
+		return
+	}
+
 	// Init app
 	appExitSignal := app.Init(http.Server, grpc.Server, cfg)
 
@@ -0,0 +1,72 @@
+package apikey
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Request is the signed part of an API request, the signature is HMAC-SHA256 of
+// timestamp, method, path with query and body, hex encoded
+type Request struct {
+	KeyID     string
+	Timestamp int64 // Unix millisecond
+	Signature string
+	Method    string
+	Path      string
+	Body      []byte
+	ClientIP  string
+}
+
+// Sign returns the signature of the request made with the secret
+func Sign(secret string, timestamp int64, method, path string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte(method))
+	mac.Write([]byte(path))
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify compares the request signature in constant time
+func (r Request) Verify(secret string) bool {
+	expected := Sign(secret, r.Timestamp, r.Method, r.Path, r.Body)
+	return hmac.Equal([]byte(expected), []byte(r.Signature))
+}
+
+// WithinWindow returns true when the request was signed no further than window from now, either way
+func (r Request) WithinWindow(now time.Time, window time.Duration) bool {
+	diff := now.Sub(time.UnixMilli(r.Timestamp))
+	return diff <= window && diff >= -window
+}
+
+// HasScope returns true when the comma separated scopes contain the scope
+func HasScope(scopes, scope string) bool {
+	for _, s := range strings.Split(scopes, ",") {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NewKey returns a random public key ID and secret
+func NewKey() (keyID, secret string, err error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", "", err
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", err
+	}
+
+	return hex.EncodeToString(id), hex.EncodeToString(raw), nil
+}
@@ -0,0 +1,39 @@
+package apikey
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	now := time.Now()
+	request := Request{
+		Timestamp: now.UnixMilli(),
+		Method:    "POST",
+		Path:      "/api/v1/order",
+		Body:      []byte(`{"pair_code":"DOGEIDRT"}`),
+	}
+	request.Signature = Sign("secret", request.Timestamp, request.Method, request.Path, request.Body)
+
+	if !request.Verify("secret") {
+		t.Fatal("valid signature rejected")
+	}
+
+	if request.Verify("other") {
+		t.Fatal("signature of another secret accepted")
+	}
+
+	tampered := request
+	tampered.Body = []byte(`{"pair_code":"BTCIDRT"}`)
+	if tampered.Verify("secret") {
+		t.Fatal("tampered body accepted")
+	}
+
+	if !request.WithinWindow(now.Add(4*time.Second), 5*time.Second) {
+		t.Fatal("request inside the window rejected")
+	}
+
+	if request.WithinWindow(now.Add(6*time.Second), 5*time.Second) || request.WithinWindow(now.Add(-6*time.Second), 5*time.Second) {
+		t.Fatal("request outside the window accepted")
+	}
+}
@@ -0,0 +1,34 @@
+package chain
+
+import (
+	"context"
+	"errors"
+)
+
+var ErrTransactionNotFound = errors.New("transaction not found")
+
+// Transfer is an outgoing payment, ID is unique per transfer so a repeated broadcast sends it only once
+type Transfer struct {
+	ID      string
+	Symbol  string
+	Address string
+	Amount  float64
+}
+
+// Transaction is the state of a transaction on the chain
+type Transaction struct {
+	Hash          string
+	Address       string  // Destination address
+	Amount        float64 // Amount received by the destination address
+	Confirmations int
+	Failed        bool // Rejected or dropped by the chain, it will never confirm
+}
+
+// Adapter connects the exchange to the chain of a crypto
+type Adapter interface {
+	// Broadcast send the transfer to the chain and returns its transaction hash
+	Broadcast(ctx context.Context, transfer Transfer) (string, error)
+
+	// Transaction returns the current state of a transaction
+	Transaction(ctx context.Context, symbol, hash string) (Transaction, error)
+}
@@ -0,0 +1,82 @@
+package chain
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Fake is an in-memory chain for local run and tests.
+// Every query of a pending transaction mines one block, an incoming transaction is added with Receive.
+type Fake struct {
+	lock         sync.Mutex
+	transactions map[string]*Transaction
+	broadcasts   map[string]string // Transfer ID to transaction hash
+}
+
+func NewFake() *Fake {
+	return &Fake{
+		transactions: make(map[string]*Transaction),
+		broadcasts:   make(map[string]string),
+	}
+}
+
+func (f *Fake) Broadcast(ctx context.Context, transfer Transfer) (string, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if hash, ok := f.broadcasts[transfer.ID]; ok {
+		return hash, nil
+	}
+
+	hash := fmt.Sprintf("fake-%v-%v", transfer.Symbol, len(f.broadcasts)+1)
+	f.broadcasts[transfer.ID] = hash
+	f.transactions[hash] = &Transaction{Hash: hash, Address: transfer.Address, Amount: transfer.Amount}
+
+	return hash, nil
+}
+
+func (f *Fake) Transaction(ctx context.Context, symbol, hash string) (Transaction, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	transaction, ok := f.transactions[hash]
+	if !ok {
+		return Transaction{}, ErrTransactionNotFound
+	}
+
+	if !transaction.Failed {
+		transaction.Confirmations++
+	}
+
+	return *transaction, nil
+}
+
+// Receive add an incoming transaction sending the amount to the address, it is not mined yet
+func (f *Fake) Receive(hash, address string, amount float64) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.transactions[hash] = &Transaction{Hash: hash, Address: address, Amount: amount}
+}
+
+// Confirm set the confirmations of a known transaction
+func (f *Fake) Confirm(hash string, confirmations int) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if transaction, ok := f.transactions[hash]; ok {
+		transaction.Confirmations = confirmations
+	}
+}
+
+// Fail mark a known transaction as rejected by the chain
+func (f *Fake) Fail(hash string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if transaction, ok := f.transactions[hash]; ok {
+		transaction.Confirmations = 0
+		transaction.Failed = true
+	}
+}
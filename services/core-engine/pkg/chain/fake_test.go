@@ -0,0 +1,42 @@
+package chain
+
+import (
+	"context"
+	"testing"
+)
+
+func TestFake(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFake()
+
+	// Broadcast is idempotent per transfer
+	hash, _ := fake.Broadcast(ctx, Transfer{ID: "withdrawal-1", Symbol: "BTC", Address: "addr", Amount: 1})
+	if again, _ := fake.Broadcast(ctx, Transfer{ID: "withdrawal-1", Symbol: "BTC", Address: "addr", Amount: 1}); again != hash {
+		t.Fatalf("repeated broadcast returns %v, want %v", again, hash)
+	}
+
+	// Every query mines one block
+	for want := 1; want <= 3; want++ {
+		if transaction, _ := fake.Transaction(ctx, "BTC", hash); transaction.Confirmations != want {
+			t.Fatalf("confirmations %v, want %v", transaction.Confirmations, want)
+		}
+	}
+
+	if transaction, _ := fake.Transaction(ctx, "BTC", hash); transaction.Address != "addr" || transaction.Amount != 1 {
+		t.Fatalf("broadcast transaction must keep its transfer, %+v", transaction)
+	}
+
+	// Incoming transaction is unknown until received
+	if _, err := fake.Transaction(ctx, "BTC", "deposit"); err != ErrTransactionNotFound {
+		t.Fatalf("expected transaction not found, got %v", err)
+	}
+	fake.Receive("deposit", "exchange-addr", 2.5)
+	if transaction, _ := fake.Transaction(ctx, "BTC", "deposit"); transaction.Confirmations != 1 || transaction.Address != "exchange-addr" || transaction.Amount != 2.5 {
+		t.Fatalf("unexpected incoming transaction %+v", transaction)
+	}
+
+	fake.Fail(hash)
+	if transaction, _ := fake.Transaction(ctx, "BTC", hash); !transaction.Failed || transaction.Confirmations != 0 {
+		t.Fatalf("failed transaction must not confirm, %+v", transaction)
+	}
+}
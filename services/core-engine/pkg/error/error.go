@@ -42,6 +42,18 @@ var (
 	ErrUserBlocked = func(err error) ServerError {
 		return ServerError{http.StatusUnauthorized, 902, "login failed user blocked", err}
 	}
+	ErrInvalidRefreshToken = func(err error) ServerError {
+		return ServerError{http.StatusUnauthorized, 903, "invalid refresh token", err}
+	}
+	ErrTwoFactorRequired = func(err error) ServerError {
+		return ServerError{http.StatusUnauthorized, 904, "two factor code required", err}
+	}
+	ErrInvalidTwoFactorCode = func(err error) ServerError {
+		return ServerError{http.StatusUnauthorized, 905, "invalid two factor code", err}
+	}
+	ErrTwoFactorLocked = func(err error) ServerError {
+		return ServerError{http.StatusTooManyRequests, 906, "too many invalid two factor code", err}
+	}
 	ErrGeneralDatabaseError = func(err error) ServerError {
 		return ServerError{http.StatusInternalServerError, 800, "internal dependencies error", err}
 	}
@@ -51,4 +63,16 @@ var (
 	ErrInsufficientBalance = func(err error) ServerError {
 		return ServerError{http.StatusBadRequest, 705, "insufficient balance", err}
 	}
+	ErrInvalidOrderRequest = func(err error) ServerError {
+		return ServerError{http.StatusBadRequest, 706, "invalid order request", err}
+	}
+	ErrInvalidRequest = func(err error) ServerError {
+		return ServerError{http.StatusBadRequest, 707, "invalid request", err}
+	}
+	ErrInvalidStateTransition = func(err error) ServerError {
+		return ServerError{http.StatusConflict, 708, "invalid state transition", err}
+	}
+	ErrTransferLimitExceeded = func(err error) ServerError {
+		return ServerError{http.StatusBadRequest, 709, "daily transfer limit exceeded", err}
+	}
 )
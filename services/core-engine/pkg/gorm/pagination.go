@@ -27,7 +27,13 @@ func CreatePaginationQuery(pageNumber, pageSize int, sortBy, sortDirection strin
 			sortDirection = "DESC"
 		}
 
+		// Rows sharing the same sort value need a stable order, otherwise they can move between pages
+		orderBy := fmt.Sprintf("%s %s", sortBy, sortDirection)
+		if sortBy != "id" {
+			orderBy = fmt.Sprintf("%s, id %s", orderBy, sortDirection)
+		}
+
 		offset := (pageNumber - 1) * pageSize
-		return db.Offset(offset).Limit(pageSize).Order(fmt.Sprintf("%s %s", sortBy, sortDirection))
+		return db.Offset(offset).Limit(pageSize).Order(orderBy)
 	}
 }
@@ -2,8 +2,11 @@ package jwt
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/golang-jwt/jwt"
 	"github.com/spf13/cast"
@@ -12,16 +15,25 @@ import (
 type contextKey struct{}
 
 type Payload struct {
+	ID     string `json:"jti"` // Unique per token, key of the deny list
 	UserID int    `json:"userId"`
 	Email  string `json:"email"`
 	Exp    int64  `json:"exp"`
 	Role   string `json:"role"`
+	Scopes string `json:"-"` // Comma separated scopes of an API key, empty for a login session
+}
+
+// DenyList keeps revoked token ID until the token expires
+type DenyList interface {
+	Deny(ctx context.Context, tokenID string, exp time.Time) error
+	IsDenied(ctx context.Context, tokenID string) (bool, error)
 }
 
 var (
 	ErrInvalidToken       = errors.New("invalid token")
 	ErrTokenNotFound      = errors.New("token not found")
 	ErrInvalidClaimFormat = errors.New("invalid claims format")
+	ErrTokenRevoked       = errors.New("token revoked")
 )
 
 func SavePayloadToContext(parent context.Context, payload Payload) context.Context {
@@ -37,6 +49,42 @@ func GetPayloadFromContext(ctx context.Context) Payload {
 	return Payload{}
 }
 
+// Generate signs a HS256 token of the payload, a new token ID is set when the payload has none
+func Generate(payload Payload, secretKey []byte) (string, Payload, error) {
+	if payload.ID == "" {
+		id, err := NewTokenID()
+		if err != nil {
+			return "", Payload{}, err
+		}
+		payload.ID = id
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"jti":    payload.ID,
+		"userId": payload.UserID,
+		"email":  payload.Email,
+		"role":   payload.Role,
+		"exp":    payload.Exp,
+	})
+
+	tokenString, err := token.SignedString(secretKey)
+	if err != nil {
+		return "", Payload{}, err
+	}
+
+	return tokenString, payload, nil
+}
+
+// NewTokenID returns a random 128 bit hex string
+func NewTokenID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(id), nil
+}
+
 func Validate(tokenString string, secretKey []byte) (Payload, error) {
 	if tokenString == "" {
 		return Payload{}, ErrTokenNotFound
@@ -70,9 +118,16 @@ func Validate(tokenString string, secretKey []byte) (Payload, error) {
 	}
 
 	payload := Payload{
+		ID:     cast.ToString(claims["jti"]),
 		UserID: cast.ToInt(claims["userId"]),
 		Email:  cast.ToString(claims["email"]),
 		Exp:    cast.ToInt64(claims["exp"]),
+		Role:   cast.ToString(claims["role"]),
+	}
+
+	// Token issued before the deny list can not be revoked
+	if payload.ID == "" {
+		return Payload{}, ErrInvalidToken
 	}
 
 	// Return the token payload
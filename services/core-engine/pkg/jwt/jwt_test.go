@@ -0,0 +1,52 @@
+package jwt
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+func TestGenerateAndValidate(t *testing.T) {
+	secretKey := []byte("secret")
+
+	tokenString, issued, err := Generate(Payload{UserID: 7, Email: "user@mail.com", Role: "USER", Exp: time.Now().Add(time.Minute).Unix()}, secretKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if issued.ID == "" {
+		t.Fatal("token ID is not set")
+	}
+
+	payload, err := Validate(tokenString, secretKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if payload != issued {
+		t.Fatalf("validated payload %+v, want %+v", payload, issued)
+	}
+
+	if _, err := Validate(tokenString, []byte("other")); err == nil {
+		t.Fatal("token signed with other key is accepted")
+	}
+}
+
+func TestValidateRejectTokenWithoutID(t *testing.T) {
+	secretKey := []byte("secret")
+
+	// Issued before the deny list, it can not be revoked
+	tokenString, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"userId": 7,
+		"exp":    time.Now().Add(time.Minute).Unix(),
+	}).SignedString(secretKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Validate(tokenString, secretKey); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("got %v, want %v", err, ErrInvalidToken)
+	}
+}
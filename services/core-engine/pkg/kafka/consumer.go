@@ -25,3 +25,20 @@ func NewConsumer(cfg config.MessageBroker, topic string) *kafka.Reader {
 	reader := kafka.NewReader(consumerConfig)
 	return reader
 }
+
+// NewDeadLetterConsumer returns reader for replaying a dead-letter topic, the replay progress is kept by its own group
+func NewDeadLetterConsumer(cfg config.MessageBroker, topic string) *kafka.Reader {
+	consumerConfig := kafka.ReaderConfig{
+		Brokers:        strings.Split(cfg.Brokers, ","),
+		GroupID:        cfg.Group + "-dlq-replay",
+		Topic:          DeadLetterTopic(topic),
+		MinBytes:       10e3,
+		MaxBytes:       10e6,
+		MaxWait:        10 * time.Millisecond,
+		CommitInterval: 0, // Commit synchronously, a replayed message must not be replayed twice
+		StartOffset:    kafka.FirstOffset,
+	}
+
+	reader := kafka.NewReader(consumerConfig)
+	return reader
+}
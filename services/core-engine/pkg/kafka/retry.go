@@ -0,0 +1,196 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gerins/log"
+	"github.com/segmentio/kafka-go"
+
+	"core-engine/config"
+)
+
+// Header keys written to every dead-letter message
+const (
+	HeaderOriginalTopic     = "x-original-topic"
+	HeaderOriginalPartition = "x-original-partition"
+	HeaderOriginalOffset    = "x-original-offset"
+	HeaderError             = "x-error"
+	HeaderAttempts          = "x-attempts"
+	HeaderFailedAt          = "x-failed-at"
+
+	deadLetterSuffix = ".dlq"
+)
+
+// DeadLetterTopic returns the dead-letter topic of a source topic
+func DeadLetterTopic(topic string) string {
+	return topic + deadLetterSuffix
+}
+
+// PermanentError mark an error that will never succeed on retry, e.g. malformed payload
+type PermanentError struct {
+	Err error
+}
+
+func (e PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// Permanent wrap err so the message is moved to the dead-letter topic without retrying
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return PermanentError{Err: err}
+}
+
+// IsPermanent returns true when err or any error it wraps is a PermanentError
+func IsPermanent(err error) bool {
+	var permanent PermanentError
+	return errors.As(err, &permanent)
+}
+
+type messageWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+}
+
+// RetryProcessor run a message handler with bounded retries, and move the message
+// to the dead-letter topic once the retries are exhausted or the error is permanent.
+type RetryProcessor struct {
+	deadLetter messageWriter
+	policy     config.Retry
+}
+
+// NewRetryProcessor returns new retry processor, deadLetter is usually the writer returned by NewProducer.
+func NewRetryProcessor(deadLetter messageWriter, policy config.Retry) *RetryProcessor {
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = 1
+	}
+
+	return &RetryProcessor{
+		deadLetter: deadLetter,
+		policy:     policy,
+	}
+}
+
+// Process call handle until it succeeds. It returns nil when the message is done, either handled or
+// dead-lettered, and the offset can be committed. A non-nil error means the message must not be committed,
+// which only happens when ctx is done or the dead-letter topic can not be written.
+func (p *RetryProcessor) Process(ctx context.Context, message kafka.Message, handle func() error) error {
+	var (
+		err     error
+		attempt int
+	)
+
+	for attempt = 1; ; attempt++ {
+		if err = handle(); err == nil {
+			return nil
+		}
+
+		if IsPermanent(err) || attempt >= p.policy.MaxAttempts {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(p.backoff(attempt)):
+		}
+	}
+
+	log.Errorf("moving message %v partition %v offset %v to dead-letter topic after %v attempt, %v",
+		message.Topic, message.Partition, message.Offset, attempt, err)
+
+	return p.deadLetter.WriteMessages(ctx, deadLetterMessage(message, err, attempt))
+}
+
+// Exponential backoff starting from the configured backoff, capped by max backoff
+func (p *RetryProcessor) backoff(attempt int) time.Duration {
+	backoff := p.policy.Backoff << (attempt - 1)
+	if p.policy.MaxBackoff > 0 && (backoff > p.policy.MaxBackoff || backoff <= 0) {
+		return p.policy.MaxBackoff
+	}
+
+	return backoff
+}
+
+func deadLetterMessage(message kafka.Message, err error, attempts int) kafka.Message {
+	headers := append(originalHeaders(message.Headers),
+		kafka.Header{Key: HeaderOriginalTopic, Value: []byte(message.Topic)},
+		kafka.Header{Key: HeaderOriginalPartition, Value: []byte(strconv.Itoa(message.Partition))},
+		kafka.Header{Key: HeaderOriginalOffset, Value: []byte(strconv.FormatInt(message.Offset, 10))},
+		kafka.Header{Key: HeaderError, Value: []byte(err.Error())},
+		kafka.Header{Key: HeaderAttempts, Value: []byte(strconv.Itoa(attempts))},
+		kafka.Header{Key: HeaderFailedAt, Value: []byte(time.Now().UTC().Format(time.RFC3339))},
+	)
+
+	return kafka.Message{
+		Topic:   DeadLetterTopic(message.Topic),
+		Key:     message.Key,
+		Value:   message.Value,
+		Headers: headers,
+	}
+}
+
+// Headers of the original message without the dead-letter metadata
+func originalHeaders(headers []kafka.Header) []kafka.Header {
+	result := make([]kafka.Header, 0, len(headers))
+	for _, header := range headers {
+		if !strings.HasPrefix(header.Key, "x-") {
+			result = append(result, header)
+		}
+	}
+
+	return result
+}
+
+// ReplayDeadLetter publish every message of a dead-letter topic back to its original topic.
+// It stops once no new message arrived within idle, and returns the number of replayed message.
+func ReplayDeadLetter(ctx context.Context, reader *kafka.Reader, writer messageWriter, idle time.Duration) (int, error) {
+	var replayed int
+
+	for {
+		fetchCtx, cancel := context.WithTimeout(ctx, idle)
+		message, err := reader.FetchMessage(fetchCtx)
+		cancel()
+		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+				return replayed, nil // Dead-letter topic is drained
+			}
+			return replayed, err
+		}
+
+		topic := strings.TrimSuffix(message.Topic, deadLetterSuffix)
+		for _, header := range message.Headers {
+			if header.Key == HeaderOriginalTopic {
+				topic = string(header.Value)
+			}
+		}
+
+		replay := kafka.Message{
+			Topic:   topic,
+			Key:     message.Key,
+			Value:   message.Value,
+			Headers: originalHeaders(message.Headers),
+		}
+
+		if err := writer.WriteMessages(ctx, replay); err != nil {
+			return replayed, fmt.Errorf("failed replaying offset %v to %v, %w", message.Offset, topic, err)
+		}
+
+		if err := reader.CommitMessages(ctx, message); err != nil {
+			return replayed, err
+		}
+
+		replayed++
+	}
+}
@@ -0,0 +1,156 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+
+	"core-engine/config"
+)
+
+type recordWriter struct {
+	messages []kafka.Message
+	err      error
+}
+
+func (w *recordWriter) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	if w.err != nil {
+		return w.err
+	}
+
+	w.messages = append(w.messages, msgs...)
+	return nil
+}
+
+func TestRetryProcessor(t *testing.T) {
+	message := kafka.Message{Topic: "match-order", Partition: 1, Offset: 42, Key: []byte("DOGEIDRT"), Value: []byte("{")}
+
+	tests := []struct {
+		name         string
+		failures     int
+		err          error
+		wantCalls    int
+		wantDeadLast bool
+	}{
+		{name: "success first attempt", failures: 0, err: errors.New("temporary"), wantCalls: 1},
+		{name: "success after retry", failures: 1, err: errors.New("database timeout"), wantCalls: 2},
+		{name: "retries exhausted", failures: 10, err: errors.New("database timeout"), wantCalls: 4, wantDeadLast: true},
+		{name: "permanent error", failures: 10, err: Permanent(errors.New("malformed trade")), wantCalls: 1, wantDeadLast: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writer := &recordWriter{}
+			processor := NewRetryProcessor(writer, config.Retry{MaxAttempts: 4})
+
+			calls := 0
+			err := processor.Process(context.Background(), message, func() error {
+				calls++
+				if calls <= tt.failures {
+					return tt.err
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			if calls != tt.wantCalls {
+				t.Errorf("handler called %v times, want %v", calls, tt.wantCalls)
+			}
+
+			if got := len(writer.messages) == 1; got != tt.wantDeadLast {
+				t.Fatalf("dead-lettered %v, want %v", got, tt.wantDeadLast)
+			}
+			if !tt.wantDeadLast {
+				return
+			}
+
+			dead := writer.messages[0]
+			headers := make(map[string]string)
+			for _, header := range dead.Headers {
+				headers[header.Key] = string(header.Value)
+			}
+
+			if dead.Topic != "match-order.dlq" || string(dead.Key) != "DOGEIDRT" || string(dead.Value) != "{" {
+				t.Errorf("unexpected dead-letter message %v %s %s", dead.Topic, dead.Key, dead.Value)
+			}
+			if headers[HeaderOriginalTopic] != "match-order" || headers[HeaderOriginalPartition] != "1" ||
+				headers[HeaderOriginalOffset] != "42" || headers[HeaderError] != tt.err.Error() {
+				t.Errorf("unexpected dead-letter headers %v", headers)
+			}
+		})
+	}
+}
+
+// The consumer must not commit a message it could neither handle nor dead-letter
+func TestRetryProcessorDeadLetterFailed(t *testing.T) {
+	writer := &recordWriter{err: errors.New("broker unavailable")}
+	processor := NewRetryProcessor(writer, config.Retry{})
+
+	err := processor.Process(context.Background(), kafka.Message{Topic: "order-update"}, func() error {
+		return Permanent(errors.New("malformed update"))
+	})
+	if err == nil {
+		t.Fatal("failed dead-letter write must be returned")
+	}
+}
+
+func TestRetryProcessorStopped(t *testing.T) {
+	writer := &recordWriter{}
+	processor := NewRetryProcessor(writer, config.Retry{MaxAttempts: 3, Backoff: time.Hour})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := processor.Process(ctx, kafka.Message{Topic: "match-order"}, func() error { return errors.New("temporary") })
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected canceled, got %v", err)
+	}
+	if len(writer.messages) != 0 {
+		t.Fatalf("message must not be dead-lettered while stopping")
+	}
+}
+
+func TestRetryProcessorBackoff(t *testing.T) {
+	processor := NewRetryProcessor(nil, config.Retry{Backoff: 100 * time.Millisecond, MaxBackoff: time.Second})
+
+	for attempt, want := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 4: 800 * time.Millisecond, 5: time.Second, 70: time.Second} {
+		if got := processor.backoff(attempt); got != want {
+			t.Errorf("backoff(%v) = %v, want %v", attempt, got, want)
+		}
+	}
+}
+
+// Replayed message failing again keeps only the metadata of its last failure
+func TestDeadLetterMessageHeaders(t *testing.T) {
+	message := kafka.Message{
+		Topic: "match-order",
+		Headers: []kafka.Header{
+			{Key: "trace-id", Value: []byte("abc")},
+			{Key: HeaderError, Value: []byte("previous failure")},
+		},
+	}
+
+	dead := deadLetterMessage(message, errors.New("new failure"), 2)
+
+	var traceID, errorHeaders int
+	for _, header := range dead.Headers {
+		switch header.Key {
+		case "trace-id":
+			traceID++
+		case HeaderError:
+			errorHeaders++
+			if string(header.Value) != "new failure" {
+				t.Errorf("error header %q, want the last failure", header.Value)
+			}
+		}
+	}
+
+	if traceID != 1 || errorHeaders != 1 {
+		t.Fatalf("unexpected headers %v", dead.Headers)
+	}
+}
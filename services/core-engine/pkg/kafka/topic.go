@@ -0,0 +1,84 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// TopicManager creates topic on the cluster instead of relying on the broker auto create
+//
+//counterfeiter:generate -o ./mock . TopicManager
+type TopicManager interface {
+	CreateTopic(ctx context.Context, topic string, partitions int) error // An existing topic is not an error
+	CreateCompactedTopic(ctx context.Context, topic string) error        // Keep the last message of every key forever
+}
+
+type topicManager struct {
+	brokers []string
+}
+
+func NewTopicManager(brokers string) TopicManager {
+	return &topicManager{brokers: strings.Split(brokers, ",")}
+}
+
+func (m *topicManager) CreateTopic(ctx context.Context, topic string, partitions int) error {
+	return m.createTopic(ctx, kafka.TopicConfig{
+		Topic:             topic,
+		NumPartitions:     partitions,
+		ReplicationFactor: -1, // Broker default
+	})
+}
+
+func (m *topicManager) CreateCompactedTopic(ctx context.Context, topic string) error {
+	return m.createTopic(ctx, kafka.TopicConfig{
+		Topic:             topic,
+		NumPartitions:     1,
+		ReplicationFactor: -1,
+		ConfigEntries:     []kafka.ConfigEntry{{ConfigName: "cleanup.policy", ConfigValue: "compact"}},
+	})
+}
+
+// createTopic sends the request to the controller broker, the only broker allowed to create topic
+func (m *topicManager) createTopic(ctx context.Context, topicConfig kafka.TopicConfig) error {
+	conn, err := m.dialAny(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	controller, err := conn.Controller()
+	if err != nil {
+		return err
+	}
+
+	controllerConn, err := kafka.DialContext(ctx, "tcp", net.JoinHostPort(controller.Host, strconv.Itoa(controller.Port)))
+	if err != nil {
+		return err
+	}
+	defer controllerConn.Close()
+
+	err = controllerConn.CreateTopics(topicConfig)
+	if err != nil && !errors.Is(err, kafka.TopicAlreadyExists) {
+		return err
+	}
+
+	return nil
+}
+
+func (m *topicManager) dialAny(ctx context.Context) (*kafka.Conn, error) {
+	var lastErr error
+	for _, broker := range m.brokers {
+		conn, err := kafka.DialContext(ctx, "tcp", broker)
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+
+	return nil, lastErr
+}
@@ -0,0 +1,44 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type tokenDenyList struct {
+	client *redis.Client
+}
+
+// NewTokenDenyList returns a deny list keeping each revoked token ID until the token expires
+func NewTokenDenyList(client *redis.Client) *tokenDenyList {
+	return &tokenDenyList{client: client}
+}
+
+func (d *tokenDenyList) Deny(ctx context.Context, tokenID string, exp time.Time) error {
+	ttl := time.Until(exp)
+	if ttl <= 0 {
+		return nil // Already expired, rejected by the signature check
+	}
+
+	return d.client.Set(ctx, denyListKey(tokenID), 1, ttl).Err()
+}
+
+func (d *tokenDenyList) IsDenied(ctx context.Context, tokenID string) (bool, error) {
+	err := d.client.Get(ctx, denyListKey(tokenID)).Err()
+	if errors.Is(err, redis.Nil) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func denyListKey(tokenID string) string {
+	return fmt.Sprintf("jwt#deny#%v", tokenID)
+}
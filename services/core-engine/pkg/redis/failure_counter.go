@@ -0,0 +1,51 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Count a failure, the window starts at the first failure so the counter is gone once it expired
+var failureScript = redis.NewScript(`
+local count = redis.call('INCR', KEYS[1])
+if count == 1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return count
+`)
+
+type failureCounter struct {
+	client *redis.Client
+	name   string
+}
+
+// NewFailureCounter returns a counter of failed attempts per ID, name separates the counters of each check
+func NewFailureCounter(client *redis.Client, name string) *failureCounter {
+	return &failureCounter{client: client, name: name}
+}
+
+func (c *failureCounter) Failures(ctx context.Context, id string) (int64, error) {
+	count, err := c.client.Get(ctx, c.key(id)).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+
+	return count, err
+}
+
+// AddFailure returns the failures including this one, the counter is kept for the window after the first failure
+func (c *failureCounter) AddFailure(ctx context.Context, id string, window time.Duration) (int64, error) {
+	return failureScript.Run(ctx, c.client, []string{c.key(id)}, window.Milliseconds()).Int64()
+}
+
+func (c *failureCounter) Reset(ctx context.Context, id string) error {
+	return c.client.Del(ctx, c.key(id)).Err()
+}
+
+func (c *failureCounter) key(id string) string {
+	return fmt.Sprintf("%v#failure#%v", c.name, id)
+}
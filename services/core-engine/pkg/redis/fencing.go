@@ -0,0 +1,35 @@
+package redis
+
+import (
+	"context"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Remember the first sequence of every leader term and reject a message of an older term published at or
+// after the start of a newer one. The older leader can only publish what it applied before the handover,
+// and the newer leader replayed the same journal, so anything before the newer term is still valid.
+var fencingScript = redis.NewScript(`
+local token = tonumber(ARGV[1])
+local sequence = tonumber(ARGV[3])
+redis.call('HSETNX', KEYS[1], ARGV[1], ARGV[2])
+local terms = redis.call('HGETALL', KEYS[1])
+for i = 1, #terms, 2 do
+	if tonumber(terms[i]) > token and sequence >= tonumber(terms[i + 1]) then
+		return 0
+	end
+end
+return 1
+`)
+
+// CheckFencingToken returns false when the message was published by a replaced leader after the newer
+// leader took over. Term start is the first trade sequence of the token, sequence is the trade sequence of
+// the message, or the last trade sequence of the pair for an order update.
+func CheckFencingToken(ctx context.Context, client *redis.Client, key string, token, termStart, sequence int64) (bool, error) {
+	valid, err := fencingScript.Run(ctx, client, []string{key}, token, termStart, sequence).Int()
+	if err != nil {
+		return false, err
+	}
+
+	return valid == 1, nil
+}
@@ -51,6 +51,25 @@ func SuccessList(c echo.Context, data any, page, limit, totalItem int) error {
 	return c.JSON(http.StatusOK, response)
 }
 
+type cursorMeta struct {
+	Limit      int    `json:"limit"`
+	NextCursor string `json:"nextCursor,omitempty"` // Empty on the last page
+}
+
+func SuccessCursor(c echo.Context, data any, limit int, nextCursor string) error {
+	response := DefaultResponse{
+		Code:    http.StatusOK,
+		Message: http.StatusText(http.StatusOK),
+		Data:    data,
+		Meta: cursorMeta{
+			Limit:      limit,
+			NextCursor: nextCursor,
+		},
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
 func Failed(c echo.Context, err error) error {
 	var (
 		generalError = serverError.ErrGeneralError(err)
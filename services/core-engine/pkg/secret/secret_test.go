@@ -0,0 +1,19 @@
+package secret
+
+import "testing"
+
+func TestEncryptAndDecrypt(t *testing.T) {
+	ciphertext, err := Encrypt("passphrase", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plaintext, err := Decrypt("passphrase", ciphertext)
+	if err != nil || plaintext != "secret" {
+		t.Fatalf("got %q %v, want secret", plaintext, err)
+	}
+
+	if _, err := Decrypt("other", ciphertext); err != ErrInvalidCiphertext {
+		t.Fatalf("decrypt with other passphrase: got %v, want %v", err, ErrInvalidCiphertext)
+	}
+}
@@ -0,0 +1,57 @@
+package totp
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+)
+
+// Test vectors of RFC 6238 appendix B for SHA1, truncated to 6 digits
+func TestCode(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, test := range tests {
+		got, err := Code(secret, Step(time.Unix(test.unix, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got != test.want {
+			t.Errorf("code at %v: got %v, want %v", test.unix, got, test.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := NewSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	code, _ := Code(secret, Step(now.Add(-Period)))
+
+	step, ok := Validate(secret, code, now)
+	if !ok || step != Step(now)-1 {
+		t.Fatalf("previous step code: got %v %v, want %v true", step, ok, Step(now)-1)
+	}
+
+	if _, ok := Validate(secret, code, now.Add(2*Period)); ok {
+		t.Fatal("code outside the skew accepted")
+	}
+
+	if _, ok := Validate(secret, "12345", now); ok {
+		t.Fatal("short code accepted")
+	}
+}
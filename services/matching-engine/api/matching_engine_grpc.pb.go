@@ -0,0 +1,322 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: matching_engine.proto
+
+package api
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	MatchingEngine_SubmitOrder_FullMethodName       = "/matchingengine.v1.MatchingEngine/SubmitOrder"
+	MatchingEngine_CancelOrder_FullMethodName       = "/matchingengine.v1.MatchingEngine/CancelOrder"
+	MatchingEngine_GetDepth_FullMethodName          = "/matchingengine.v1.MatchingEngine/GetDepth"
+	MatchingEngine_StreamBookUpdates_FullMethodName = "/matchingengine.v1.MatchingEngine/StreamBookUpdates"
+	MatchingEngine_StreamTrades_FullMethodName      = "/matchingengine.v1.MatchingEngine/StreamTrades"
+)
+
+// MatchingEngineClient is the client API for MatchingEngine service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type MatchingEngineClient interface {
+	// SubmitOrder matches the order against the book and returns the generated trades.
+	SubmitOrder(ctx context.Context, in *SubmitOrderRequest, opts ...grpc.CallOption) (*SubmitOrderResponse, error)
+	// CancelOrder removes a resting order from the book and returns its remaining quantity.
+	CancelOrder(ctx context.Context, in *CancelOrderRequest, opts ...grpc.CallOption) (*CancelOrderResponse, error)
+	// GetDepth returns the aggregated price levels of both sides of the book.
+	GetDepth(ctx context.Context, in *GetDepthRequest, opts ...grpc.CallOption) (*Depth, error)
+	// StreamBookUpdates streams every changed price level, a level with zero quantity has been removed.
+	StreamBookUpdates(ctx context.Context, in *StreamBookUpdatesRequest, opts ...grpc.CallOption) (MatchingEngine_StreamBookUpdatesClient, error)
+	// StreamTrades streams every trade generated by the book.
+	StreamTrades(ctx context.Context, in *StreamTradesRequest, opts ...grpc.CallOption) (MatchingEngine_StreamTradesClient, error)
+}
+
+type matchingEngineClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewMatchingEngineClient(cc grpc.ClientConnInterface) MatchingEngineClient {
+	return &matchingEngineClient{cc}
+}
+
+func (c *matchingEngineClient) SubmitOrder(ctx context.Context, in *SubmitOrderRequest, opts ...grpc.CallOption) (*SubmitOrderResponse, error) {
+	out := new(SubmitOrderResponse)
+	err := c.cc.Invoke(ctx, MatchingEngine_SubmitOrder_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *matchingEngineClient) CancelOrder(ctx context.Context, in *CancelOrderRequest, opts ...grpc.CallOption) (*CancelOrderResponse, error) {
+	out := new(CancelOrderResponse)
+	err := c.cc.Invoke(ctx, MatchingEngine_CancelOrder_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *matchingEngineClient) GetDepth(ctx context.Context, in *GetDepthRequest, opts ...grpc.CallOption) (*Depth, error) {
+	out := new(Depth)
+	err := c.cc.Invoke(ctx, MatchingEngine_GetDepth_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *matchingEngineClient) StreamBookUpdates(ctx context.Context, in *StreamBookUpdatesRequest, opts ...grpc.CallOption) (MatchingEngine_StreamBookUpdatesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &MatchingEngine_ServiceDesc.Streams[0], MatchingEngine_StreamBookUpdates_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &matchingEngineStreamBookUpdatesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type MatchingEngine_StreamBookUpdatesClient interface {
+	Recv() (*BookUpdate, error)
+	grpc.ClientStream
+}
+
+type matchingEngineStreamBookUpdatesClient struct {
+	grpc.ClientStream
+}
+
+func (x *matchingEngineStreamBookUpdatesClient) Recv() (*BookUpdate, error) {
+	m := new(BookUpdate)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *matchingEngineClient) StreamTrades(ctx context.Context, in *StreamTradesRequest, opts ...grpc.CallOption) (MatchingEngine_StreamTradesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &MatchingEngine_ServiceDesc.Streams[1], MatchingEngine_StreamTrades_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &matchingEngineStreamTradesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type MatchingEngine_StreamTradesClient interface {
+	Recv() (*Trade, error)
+	grpc.ClientStream
+}
+
+type matchingEngineStreamTradesClient struct {
+	grpc.ClientStream
+}
+
+func (x *matchingEngineStreamTradesClient) Recv() (*Trade, error) {
+	m := new(Trade)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// MatchingEngineServer is the server API for MatchingEngine service.
+// All implementations must embed UnimplementedMatchingEngineServer
+// for forward compatibility
+type MatchingEngineServer interface {
+	// SubmitOrder matches the order against the book and returns the generated trades.
+	SubmitOrder(context.Context, *SubmitOrderRequest) (*SubmitOrderResponse, error)
+	// CancelOrder removes a resting order from the book and returns its remaining quantity.
+	CancelOrder(context.Context, *CancelOrderRequest) (*CancelOrderResponse, error)
+	// GetDepth returns the aggregated price levels of both sides of the book.
+	GetDepth(context.Context, *GetDepthRequest) (*Depth, error)
+	// StreamBookUpdates streams every changed price level, a level with zero quantity has been removed.
+	StreamBookUpdates(*StreamBookUpdatesRequest, MatchingEngine_StreamBookUpdatesServer) error
+	// StreamTrades streams every trade generated by the book.
+	StreamTrades(*StreamTradesRequest, MatchingEngine_StreamTradesServer) error
+	mustEmbedUnimplementedMatchingEngineServer()
+}
+
+// UnimplementedMatchingEngineServer must be embedded to have forward compatible implementations.
+type UnimplementedMatchingEngineServer struct {
+}
+
+func (UnimplementedMatchingEngineServer) SubmitOrder(context.Context, *SubmitOrderRequest) (*SubmitOrderResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SubmitOrder not implemented")
+}
+func (UnimplementedMatchingEngineServer) CancelOrder(context.Context, *CancelOrderRequest) (*CancelOrderResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelOrder not implemented")
+}
+func (UnimplementedMatchingEngineServer) GetDepth(context.Context, *GetDepthRequest) (*Depth, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDepth not implemented")
+}
+func (UnimplementedMatchingEngineServer) StreamBookUpdates(*StreamBookUpdatesRequest, MatchingEngine_StreamBookUpdatesServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamBookUpdates not implemented")
+}
+func (UnimplementedMatchingEngineServer) StreamTrades(*StreamTradesRequest, MatchingEngine_StreamTradesServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamTrades not implemented")
+}
+func (UnimplementedMatchingEngineServer) mustEmbedUnimplementedMatchingEngineServer() {}
+
+// UnsafeMatchingEngineServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to MatchingEngineServer will
+// result in compilation errors.
+type UnsafeMatchingEngineServer interface {
+	mustEmbedUnimplementedMatchingEngineServer()
+}
+
+func RegisterMatchingEngineServer(s grpc.ServiceRegistrar, srv MatchingEngineServer) {
+	s.RegisterService(&MatchingEngine_ServiceDesc, srv)
+}
+
+func _MatchingEngine_SubmitOrder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SubmitOrderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MatchingEngineServer).SubmitOrder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MatchingEngine_SubmitOrder_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MatchingEngineServer).SubmitOrder(ctx, req.(*SubmitOrderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MatchingEngine_CancelOrder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelOrderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MatchingEngineServer).CancelOrder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MatchingEngine_CancelOrder_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MatchingEngineServer).CancelOrder(ctx, req.(*CancelOrderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MatchingEngine_GetDepth_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDepthRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MatchingEngineServer).GetDepth(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MatchingEngine_GetDepth_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MatchingEngineServer).GetDepth(ctx, req.(*GetDepthRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MatchingEngine_StreamBookUpdates_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamBookUpdatesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MatchingEngineServer).StreamBookUpdates(m, &matchingEngineStreamBookUpdatesServer{stream})
+}
+
+type MatchingEngine_StreamBookUpdatesServer interface {
+	Send(*BookUpdate) error
+	grpc.ServerStream
+}
+
+type matchingEngineStreamBookUpdatesServer struct {
+	grpc.ServerStream
+}
+
+func (x *matchingEngineStreamBookUpdatesServer) Send(m *BookUpdate) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _MatchingEngine_StreamTrades_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamTradesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MatchingEngineServer).StreamTrades(m, &matchingEngineStreamTradesServer{stream})
+}
+
+type MatchingEngine_StreamTradesServer interface {
+	Send(*Trade) error
+	grpc.ServerStream
+}
+
+type matchingEngineStreamTradesServer struct {
+	grpc.ServerStream
+}
+
+func (x *matchingEngineStreamTradesServer) Send(m *Trade) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// MatchingEngine_ServiceDesc is the grpc.ServiceDesc for MatchingEngine service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var MatchingEngine_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "matchingengine.v1.MatchingEngine",
+	HandlerType: (*MatchingEngineServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "SubmitOrder",
+			Handler:    _MatchingEngine_SubmitOrder_Handler,
+		},
+		{
+			MethodName: "CancelOrder",
+			Handler:    _MatchingEngine_CancelOrder_Handler,
+		},
+		{
+			MethodName: "GetDepth",
+			Handler:    _MatchingEngine_GetDepth_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamBookUpdates",
+			Handler:       _MatchingEngine_StreamBookUpdates_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "StreamTrades",
+			Handler:       _MatchingEngine_StreamTrades_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "matching_engine.proto",
+}
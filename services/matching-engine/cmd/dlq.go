@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"context"
+	"flag"
+	"time"
+
+	"github.com/gerins/log"
+
+	"matching-engine/config"
+	"matching-engine/pkg/kafka"
+)
+
+// ReplayDeadLetter publish the dead-letter messages back to their original topic,
+// run it after the cause of the failure is fixed.
+//
+//	matching-engine dlq-replay -topic DOGEIDRT -idle 10s
+func ReplayDeadLetter(cfg *config.Config, args []string) {
+	flags := flag.NewFlagSet("dlq-replay", flag.ExitOnError)
+	topic := flags.String("topic", cfg.Dependencies.MessageBroker.Consumer.Topic, "source topic of the dead-letter messages")
+	idle := flags.Duration("idle", 10*time.Second, "stop after no new message within this duration")
+	_ = flags.Parse(args)
+
+	reader := kafka.NewDeadLetterConsumer(cfg.Dependencies.MessageBroker, *topic)
+	_, writer := kafka.NewProducer(cfg.Dependencies.MessageBroker.Brokers)
+	defer func() { _ = reader.Close(); _ = writer.Close() }()
+
+	replayed, err := kafka.ReplayDeadLetter(context.Background(), reader, writer, *idle)
+	if err != nil {
+		log.Fatalf("failed replaying %v after %v message, %v", kafka.DeadLetterTopic(*topic), replayed, err)
+	}
+
+	log.Infof("replayed %v message from %v", replayed, kafka.DeadLetterTopic(*topic))
+}
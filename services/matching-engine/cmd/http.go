@@ -3,6 +3,7 @@ package cmd
 import (
 	"context"
 	"errors"
+	"expvar"
 	"fmt"
 	"net/http"
 
@@ -38,6 +39,7 @@ func (s *HttpServer) Run() chan bool {
 	s.Server.GET("/ping", func(c echo.Context) error {
 		return c.String(http.StatusOK, "OK")
 	})
+	s.Server.GET("/debug/vars", echo.WrapHandler(expvar.Handler())) // Metrics
 
 	// Start server
 	go func() {
@@ -3,6 +3,7 @@ package config
 import (
 	"log"
 	"os"
+	"path"
 	"time"
 
 	"github.com/spf13/viper"
@@ -15,6 +16,8 @@ const (
 type Config struct {
 	App          App
 	GRPC         GRPC
+	Engine       Engine
+	Replication  Replication
 	Dependencies Dependencies
 }
 
@@ -31,6 +34,55 @@ type GRPC struct {
 	Port string
 }
 
+type Engine struct {
+	QueueSize        int           // Maximum pending command for each pair before the caller is blocked
+	DedupeWindow     int           // Number of last processed order ID remembered to drop redelivered order
+	SnapshotInterval time.Duration // How often the book state is saved to redis
+	Registry         bool          // Start a book for the active pair registered by the core engine
+	RegistryPairs    []string      // Code pattern of the registered pair assigned to this instance, e.g. "*IDRT"
+	Pairs            []Pair
+}
+
+type Pair struct {
+	Code     string
+	Matching Matching
+}
+
+type Matching struct {
+	Algorithm     string  // FIFO, PRO_RATA or FIFO_TOP_PRO_RATA
+	LotSize       float64 // Pro-rata share is rounded down to this size, 0 means no rounding
+	MinAllocation float64 // Pro-rata share below this quantity is given to the oldest order instead
+}
+
+// Assigned tells whether the registered pair is served by this instance. Without replication a pair must be
+// assigned to a single instance, two books consuming the same pair would match its order twice.
+func (e Engine) Assigned(code string) bool {
+	for _, pattern := range e.RegistryPairs {
+		if matched, err := path.Match(pattern, code); err == nil && matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Pair returns the configuration of a pair, default FIFO when the pair is not configured
+func (e Engine) Pair(code string) Pair {
+	for _, pair := range e.Pairs {
+		if pair.Code == code {
+			return pair
+		}
+	}
+
+	return Pair{Code: code}
+}
+
+type Replication struct {
+	Enabled    bool
+	InstanceID string        // Unique name of this instance, default to hostname
+	LeaseTTL   time.Duration // Leader lease duration, the standby takes over after it expired
+}
+
 type Dependencies struct {
 	Cache         Cache
 	MessageBroker MessageBroker
@@ -45,14 +97,23 @@ type Cache struct {
 type MessageBroker struct {
 	Brokers  string
 	Group    string
+	Retry    Retry
 	Consumer struct {
-		Topic string
+		Topic         string // Pair started on boot, the topic is named by the pair code
+		RegistryTopic string // Pair created or updated by the core engine
 	}
 	Producer struct {
-		Topic string
+		Topic            string
+		OrderUpdateTopic string
 	}
 }
 
+type Retry struct {
+	MaxAttempts int           // Attempt before the message is moved to the dead-letter topic
+	Backoff     time.Duration // Wait before the first retry, doubled on every next retry
+	MaxBackoff  time.Duration
+}
+
 // ParseConfigFile is used for parsing config file into struct
 func ParseConfigFile(configName string) *Config {
 	viperConfig := viper.New()
@@ -76,6 +137,12 @@ func ParseConfigFile(configName string) *Config {
 	if env := os.Getenv("KAFKA_ADDRESS"); env != "" {
 		config.Dependencies.MessageBroker.Brokers = env
 	}
+	if env := os.Getenv("REPLICATION_INSTANCE_ID"); env != "" {
+		config.Replication.InstanceID = env
+	}
+	if config.Replication.InstanceID == "" {
+		config.Replication.InstanceID, _ = os.Hostname()
+	}
 
 	return config
 }
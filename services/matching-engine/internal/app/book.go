@@ -0,0 +1,242 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/gerins/log"
+	"github.com/go-playground/validator/v10"
+	goredis "github.com/go-redis/redis/v8"
+
+	"matching-engine/config"
+	"matching-engine/internal/app/controller"
+	"matching-engine/internal/app/model"
+	"matching-engine/internal/app/usecase"
+	"matching-engine/pkg/kafka"
+	"matching-engine/pkg/redis"
+)
+
+var errBooksClosed = errors.New("engine is shutting down")
+
+// books starts and stops the order book of every pair served by this instance
+type books struct {
+	cfg       *config.Config
+	cache     *goredis.Client
+	producer  kafka.Producer
+	retry     *kafka.RetryProcessor
+	validator *validator.Validate
+	registry  *usecase.Registry
+
+	mu      sync.Mutex
+	running []*book
+	closed  bool
+}
+
+// book is the order book of one pair with its sequencer, consumer, snapshot and leader lease
+type book struct {
+	pairCode      string
+	sequencer     *usecase.Sequencer
+	orderConsumer chan io.Closer
+	stopSnapshot  context.CancelFunc
+	stopLease     context.CancelFunc
+}
+
+func newBooks(cfg *config.Config, cache *goredis.Client, producer kafka.Producer, retry *kafka.RetryProcessor, validator *validator.Validate) *books {
+	return &books{
+		cfg:       cfg,
+		cache:     cache,
+		producer:  producer,
+		retry:     retry,
+		validator: validator,
+		registry:  usecase.NewRegistry(),
+	}
+}
+
+// Start restores the book of the pair and starts consuming its order, or replaying its journal when running as standby
+func (b *books) Start(pair config.Pair) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return errBooksClosed
+	}
+
+	if _, err := b.registry.Engine(pair.Code); err == nil {
+		return fmt.Errorf("book for pair %v is already running", pair.Code)
+	}
+
+	var (
+		pairCode      = pair.Code
+		leadership    = usecase.Standalone
+		journal       model.Journal
+		lease         *redis.Lease
+		orderConsumer = make(chan io.Closer, 1)
+	)
+
+	allocator, err := usecase.NewAllocator(pair.Matching)
+	if err != nil {
+		return fmt.Errorf("failed init allocator for pair %v, %w", pairCode, err)
+	}
+
+	if b.cfg.Replication.Enabled {
+		lease = redis.NewLease(b.cache, fmt.Sprintf("matching-engine#leader#%v", pairCode), b.cfg.Replication.InstanceID, b.cfg.Replication.LeaseTTL)
+		leadership = lease
+		journal = usecase.NewKafkaJournal(pairCode, b.cfg.Dependencies.MessageBroker.Brokers, b.producer)
+	}
+
+	orderBookUsecase := usecase.NewOrderBook(
+		pairCode,
+		b.cfg.Dependencies.MessageBroker.Producer.Topic,
+		b.cfg.Dependencies.MessageBroker.Producer.OrderUpdateTopic,
+		b.cache,
+		b.producer,
+		b.validator,
+		allocator,
+		pair.Matching.LotSize,
+		leadership,
+		b.cfg.Engine.DedupeWindow,
+	)
+	if err := orderBookUsecase.LoadSnapshot(context.Background()); err != nil {
+		return fmt.Errorf("failed restoring snapshot for pair %v, %w", pairCode, err)
+	}
+
+	sequencer := usecase.NewSequencer(orderBookUsecase, b.cfg.Engine.QueueSize, leadership, journal)
+
+	// The order consumer is only started by the leader, the standby replays the journal until it takes over
+	startOrderConsumer := func(snapshotter controller.Snapshotter) controller.Snapshotter {
+		queueHandler := controller.NewQueueHandler(kafka.NewConsumer(b.cfg.Dependencies.MessageBroker, pairCode), b.retry, sequencer, snapshotter, b.cfg.App.CtxTimeout)
+		queueHandler.StartConsumer()
+		orderConsumer <- queueHandler
+		return queueHandler
+	}
+
+	// Without journal the order offset is only committed once a snapshot covers it, otherwise the order
+	// applied after the last snapshot is lost on restart
+	var snapshotter controller.Snapshotter = sequencer
+	if lease == nil {
+		snapshotter = startOrderConsumer(sequencer)
+	}
+
+	// Periodic snapshot, a redelivered order older than the snapshot is dropped by the dedupe index
+	snapshotCtx, stopSnapshot := context.WithCancel(context.Background())
+	go func() {
+		if b.cfg.Engine.SnapshotInterval <= 0 {
+			return
+		}
+
+		ticker := time.NewTicker(b.cfg.Engine.SnapshotInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-snapshotCtx.Done():
+				return
+			case <-ticker.C:
+				if err := snapshotter.SaveSnapshot(snapshotCtx); err != nil {
+					log.Errorf("failed saving snapshot for pair %v, %v", pairCode, err)
+				}
+			}
+		}
+	}()
+
+	leaseCtx, stopLease := context.WithCancel(context.Background())
+	if lease != nil {
+		// Entries before the snapshot offset are already in the book, replaying them again would apply
+		// orders the dedupe window has forgotten
+		journalConsumer, err := kafka.NewJournalConsumer(b.cfg.Dependencies.MessageBroker.Brokers, usecase.JournalTopic(pairCode), orderBookUsecase.JournalOffset())
+		if err != nil {
+			stopSnapshot()
+			stopLease()
+			sequencer.Close()
+			return fmt.Errorf("failed starting journal consumer for pair %v, %w", pairCode, err)
+		}
+
+		journalHandler := controller.NewJournalHandler(journalConsumer, sequencer, b.cfg.App.CtxTimeout)
+		journalHandler.StartConsumer()
+		log.Infof("running as standby for pair %v from journal offset %v", pairCode, orderBookUsecase.JournalOffset())
+
+		go lease.Run(leaseCtx, func(leader bool, token int64) {
+			if !leader {
+				// Fail-stop, the next leader already owns a higher fencing token
+				log.Fatalf("lost leader lease for pair %v, stopping to avoid two active publishers", pairCode)
+			}
+
+			if err := journalHandler.CatchUpAndStop(leaseCtx); err != nil {
+				log.Fatalf("failed catching up journal for pair %v, %v", pairCode, err)
+			}
+
+			log.Infof("promoted to leader for pair %v with fencing token %v", pairCode, token)
+			startOrderConsumer(nil)
+		})
+	}
+
+	b.registry.Register(sequencer)
+	b.running = append(b.running, &book{
+		pairCode:      pairCode,
+		sequencer:     sequencer,
+		orderConsumer: orderConsumer,
+		stopSnapshot:  stopSnapshot,
+		stopLease:     stopLease,
+	})
+
+	log.Infof("started book for pair %v", pairCode)
+	return nil
+}
+
+// OnPairRegistered starts the book of an active pair registered by the core engine and assigned to this
+// instance. The rules of a running book are fixed, a changed rule or status only applies after the instance
+// is restarted.
+func (b *books) OnPairRegistered(pair model.Pair) {
+	if _, err := b.registry.Engine(pair.Code); err == nil {
+		log.Infof("pair %v updated, the running book keeps its rules until restarted", pair.Code)
+		return
+	}
+
+	if !pair.Status {
+		return
+	}
+
+	if !b.cfg.Engine.Assigned(pair.Code) {
+		log.Infof("pair %v registered, it is not assigned to this instance", pair.Code)
+		return
+	}
+
+	err := b.Start(config.Pair{
+		Code: pair.Code,
+		Matching: config.Matching{
+			Algorithm:     pair.MatchingAlgorithm,
+			LotSize:       pair.LotSize,
+			MinAllocation: pair.MinAllocation,
+		},
+	})
+	if err != nil {
+		log.Errorf("failed starting registered pair %v, %v", pair.Code, err)
+	}
+}
+
+// Close stops every book, the consumer first so no command is rejected by a closed sequencer
+func (b *books) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for _, book := range b.running {
+		book.stopLease()
+		book.stopSnapshot()
+		select {
+		case consumer := <-book.orderConsumer:
+			if err := consumer.Close(); err != nil {
+				log.Error(err)
+			}
+		default:
+		}
+		if err := book.sequencer.SaveSnapshot(context.Background()); err != nil {
+			log.Errorf("failed saving snapshot for pair %v, %v", book.pairCode, err)
+		}
+		book.sequencer.Close() // Finish the running command before closing the producer
+	}
+}
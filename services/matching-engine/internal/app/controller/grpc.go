@@ -0,0 +1,261 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gerins/log"
+	"github.com/go-playground/validator/v10"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"matching-engine/api"
+	"matching-engine/internal/app/model"
+)
+
+type grpcHandler struct {
+	api.UnimplementedMatchingEngineServer
+	engines model.EngineRegistry
+	timeout time.Duration
+}
+
+func NewGRPCHandler(engines model.EngineRegistry, timeout time.Duration) interface{ Register(g *grpc.Server) } {
+	return &grpcHandler{
+		engines: engines,
+		timeout: timeout,
+	}
+}
+
+func (h *grpcHandler) Register(g *grpc.Server) {
+	api.RegisterMatchingEngineServer(g, h)
+}
+
+func (h *grpcHandler) SubmitOrder(ctx context.Context, req *api.SubmitOrderRequest) (*api.SubmitOrderResponse, error) {
+	engine, err := h.engine(req.GetPairCode())
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	ctx = log.NewRequest().SaveToContext(ctx)
+	defer log.Context(ctx).Save()
+
+	order := orderFromProto(req.GetOrder())
+	log.Context(ctx).ReqBody = order
+
+	trades, err := engine.Execute(ctx, order)
+	if err != nil {
+		log.Context(ctx).Error(err)
+		return nil, statusFromError(err)
+	}
+
+	resp := &api.SubmitOrderResponse{Trades: make([]*api.Trade, 0, len(trades))}
+	for _, trade := range trades {
+		resp.Trades = append(resp.Trades, tradeToProto(trade))
+	}
+
+	return resp, nil
+}
+
+func (h *grpcHandler) CancelOrder(ctx context.Context, req *api.CancelOrderRequest) (*api.CancelOrderResponse, error) {
+	engine, err := h.engine(req.GetPairCode())
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	order, err := engine.Cancel(ctx, int(req.GetOrderId()))
+	if err != nil {
+		return nil, statusFromError(err)
+	}
+
+	return &api.CancelOrderResponse{Order: orderToProto(order)}, nil
+}
+
+func (h *grpcHandler) GetDepth(ctx context.Context, req *api.GetDepthRequest) (*api.Depth, error) {
+	engine, err := h.engine(req.GetPairCode())
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	depth, err := engine.Depth(ctx, int(req.GetLimit()))
+	if err != nil {
+		return nil, statusFromError(err)
+	}
+
+	return &api.Depth{
+		PairCode: depth.PairCode,
+		Bids:     levelsToProto(depth.Bids),
+		Asks:     levelsToProto(depth.Asks),
+		Checksum: depth.Checksum,
+	}, nil
+}
+
+func (h *grpcHandler) StreamBookUpdates(req *api.StreamBookUpdatesRequest, stream api.MatchingEngine_StreamBookUpdatesServer) error {
+	engine, err := h.engine(req.GetPairCode())
+	if err != nil {
+		return err
+	}
+
+	updates, unsubscribe := engine.SubscribeBookUpdates()
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+
+		case update, ok := <-updates:
+			if !ok {
+				return status.Error(codes.ResourceExhausted, "subscriber too slow, resubscribe")
+			}
+
+			if err := stream.Send(&api.BookUpdate{
+				PairCode: update.PairCode,
+				Bids:     levelsToProto(update.Bids),
+				Asks:     levelsToProto(update.Asks),
+				Checksum: update.Checksum,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (h *grpcHandler) StreamTrades(req *api.StreamTradesRequest, stream api.MatchingEngine_StreamTradesServer) error {
+	engine, err := h.engine(req.GetPairCode())
+	if err != nil {
+		return err
+	}
+
+	trades, unsubscribe := engine.SubscribeTrades()
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+
+		case trade, ok := <-trades:
+			if !ok {
+				return status.Error(codes.ResourceExhausted, "subscriber too slow, resubscribe")
+			}
+
+			if err := stream.Send(tradeToProto(trade)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (h *grpcHandler) engine(pairCode string) (model.Engine, error) {
+	engine, err := h.engines.Engine(pairCode)
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound, "pair %v is not served by this engine", pairCode)
+	}
+
+	return engine, nil
+}
+
+func statusFromError(err error) error {
+	switch {
+	case errors.Is(err, model.ErrOrderNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, model.ErrEngineBusy):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, model.ErrEngineStopped), errors.Is(err, model.ErrNotLeader):
+		return status.Error(codes.Unavailable, err.Error())
+	case errors.Is(err, model.ErrInvalidQuoteOrder):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+
+	var validationErr validator.ValidationErrors
+	if errors.As(err, &validationErr) {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return status.Error(codes.Internal, err.Error())
+}
+
+var (
+	sideFromProto = map[api.Side]model.Side{
+		api.Side_SIDE_BUY:  model.OrderSideBuy,
+		api.Side_SIDE_SELL: model.OrderSideSell,
+	}
+	sideToProto = map[model.Side]api.Side{
+		model.OrderSideBuy:  api.Side_SIDE_BUY,
+		model.OrderSideSell: api.Side_SIDE_SELL,
+	}
+	typeFromProto = map[api.OrderType]model.Type{
+		api.OrderType_ORDER_TYPE_MARKET: model.OrderTypeMarket,
+		api.OrderType_ORDER_TYPE_LIMIT:  model.OrderTypeLimit,
+	}
+	typeToProto = map[model.Type]api.OrderType{
+		model.OrderTypeMarket: api.OrderType_ORDER_TYPE_MARKET,
+		model.OrderTypeLimit:  api.OrderType_ORDER_TYPE_LIMIT,
+	}
+)
+
+func orderFromProto(order *api.Order) model.Order {
+	return model.Order{
+		ID:              int(order.GetId()),
+		UserID:          int(order.GetUserId()),
+		PairID:          int(order.GetPairId()),
+		Quantity:        order.GetQuantity(),
+		Price:           order.GetPrice(),
+		Type:            typeFromProto[order.GetType()],
+		Side:            sideFromProto[order.GetSide()],
+		QuoteQuantity:   order.GetQuoteQuantity(),
+		TransactionTime: order.GetTransactionTime(),
+	}
+}
+
+func orderToProto(order model.Order) *api.Order {
+	return &api.Order{
+		Id:              int64(order.ID),
+		UserId:          int64(order.UserID),
+		PairId:          int64(order.PairID),
+		Quantity:        order.Quantity,
+		Price:           order.Price,
+		Type:            typeToProto[order.Type],
+		Side:            sideToProto[order.Side],
+		QuoteQuantity:   order.QuoteQuantity,
+		TransactionTime: order.TransactionTime,
+	}
+}
+
+func tradeToProto(trade model.Trade) *api.Trade {
+	return &api.Trade{
+		PairId:        int64(trade.PairID),
+		PairCode:      trade.PairCode,
+		TakerUserId:   int64(trade.TakerUserID),
+		TakerOrderId:  int64(trade.TakerOrderID),
+		MakerUserId:   int64(trade.MakerUserID),
+		MakerOrderId:  int64(trade.MakerOrderID),
+		Quantity:      trade.Quantity,
+		Price:         trade.Price,
+		Side:          sideToProto[trade.Side],
+		TradeTime:     trade.TradeTime,
+		TradeSequence: trade.Sequence,
+	}
+}
+
+func levelsToProto(levels []model.PriceLevel) []*api.PriceLevel {
+	result := make([]*api.PriceLevel, 0, len(levels))
+	for _, level := range levels {
+		result = append(result, &api.PriceLevel{Price: level.Price, Quantity: level.Quantity})
+	}
+
+	return result
+}
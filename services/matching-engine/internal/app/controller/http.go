@@ -2,6 +2,7 @@ package controller
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"time"
 
@@ -12,14 +13,16 @@ import (
 )
 
 type httpHandler struct {
-	engine  model.Engine
-	timeout time.Duration
+	engines     model.EngineRegistry
+	defaultPair string // Pair of the order without the pair query parameter
+	timeout     time.Duration
 }
 
-func NewHTTPHandler(processor model.Engine, timeout time.Duration) interface{ InitRoutes(e *echo.Echo) } {
+func NewHTTPHandler(engines model.EngineRegistry, defaultPair string, timeout time.Duration) interface{ InitRoutes(e *echo.Echo) } {
 	return &httpHandler{
-		engine:  processor,
-		timeout: timeout,
+		engines:     engines,
+		defaultPair: defaultPair,
+		timeout:     timeout,
 	}
 }
 
@@ -39,7 +42,23 @@ func (h *httpHandler) OrderHandler(c echo.Context) error {
 		return response.ResponseFailed(c, err, http.StatusBadRequest)
 	}
 
-	orderResult := h.engine.Execute(ctx, requestPayload)
+	pairCode := c.QueryParam("pair")
+	if pairCode == "" {
+		pairCode = h.defaultPair
+	}
+
+	engine, err := h.engines.Engine(pairCode)
+	if err != nil {
+		return response.ResponseFailed(c, err, http.StatusNotFound)
+	}
+
+	trades, err := engine.Execute(ctx, requestPayload)
+	if err != nil {
+		if errors.Is(err, model.ErrEngineBusy) || errors.Is(err, model.ErrEngineStopped) || errors.Is(err, model.ErrNotLeader) {
+			return response.ResponseFailed(c, err, http.StatusServiceUnavailable)
+		}
+		return response.ResponseFailed(c, err, http.StatusBadRequest)
+	}
 
-	return response.ResponseSuccess(c, orderResult)
+	return response.ResponseSuccess(c, trades)
 }
@@ -0,0 +1,131 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gerins/log"
+	"github.com/go-playground/validator/v10"
+	"github.com/segmentio/kafka-go"
+
+	"matching-engine/internal/app/model"
+)
+
+// Wait before fetching or replaying again after a failure
+const journalBackoff = 100 * time.Millisecond
+
+type replayer interface {
+	Replay(ctx context.Context, entry model.JournalEntry) error
+}
+
+type journalHandler struct {
+	journalConsumer *kafka.Reader
+	engine          replayer
+	timeout         time.Duration
+	cancel          context.CancelFunc
+	stopped         chan struct{}
+}
+
+func NewJournalHandler(journalConsumer *kafka.Reader, engine replayer, timeout time.Duration) *journalHandler {
+	return &journalHandler{
+		journalConsumer: journalConsumer,
+		engine:          engine,
+		timeout:         timeout,
+		stopped:         make(chan struct{}),
+	}
+}
+
+// StartConsumer replay the leader journal into the local book while running as standby
+func (h *journalHandler) StartConsumer() {
+	var ctx context.Context
+	ctx, h.cancel = context.WithCancel(context.Background())
+
+	go func() {
+		defer close(h.stopped)
+
+		for {
+			kafkaMessage, err := h.journalConsumer.FetchMessage(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					return // Stopped after promoted to leader
+				}
+				log.Errorf("failed fetching journal entry, %v", err)
+				sleepContext(ctx, journalBackoff)
+				continue
+			}
+
+			// Skipping an entry would leave a book different from the leader, and this book can be promoted
+			var entry model.JournalEntry
+			if err := entry.FromJSON(kafkaMessage.Value); err != nil {
+				log.Fatalf("invalid journal entry at offset %v, %v", kafkaMessage.Offset, err)
+			}
+
+			if err := h.replay(ctx, entry, kafkaMessage.Offset); err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				log.Fatalf("failed replaying journal entry at offset %v, %v", kafkaMessage.Offset, err)
+			}
+		}
+	}()
+}
+
+// replay applies the entry, the entry not accepted by the busy sequencer is applied again. Rejection by the
+// book is expected, the leader rejected the same command.
+func (h *journalHandler) replay(ctx context.Context, entry model.JournalEntry, offset int64) error {
+	for {
+		replayCtx, cancel := context.WithTimeout(ctx, h.timeout)
+		err := h.engine.Replay(replayCtx, entry)
+		cancel()
+
+		switch {
+		case err == nil:
+			return nil
+		case rejectedCommand(err):
+			log.Infof("journal entry at offset %v rejected, %v", offset, err)
+			return nil
+		case errors.Is(err, model.ErrEngineBusy) && ctx.Err() == nil:
+			log.Warnf("journal entry at offset %v not accepted, retrying, %v", offset, err)
+			sleepContext(ctx, journalBackoff)
+		default:
+			return err
+		}
+	}
+}
+
+// rejectedCommand returns true when the book itself refused the command, the same command is refused everywhere
+func rejectedCommand(err error) bool {
+	var validationErr validator.ValidationErrors
+	return errors.As(err, &validationErr) || errors.Is(err, model.ErrInvalidQuoteOrder) || errors.Is(err, model.ErrOrderNotFound)
+}
+
+func sleepContext(ctx context.Context, duration time.Duration) {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
+
+// CatchUpAndStop replay every entry already written by the previous leader then stop the consumer
+func (h *journalHandler) CatchUpAndStop(ctx context.Context) error {
+	for {
+		lag, err := h.journalConsumer.ReadLag(ctx)
+		if err != nil {
+			return err
+		}
+		if lag == 0 {
+			break
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	h.cancel()
+	<-h.stopped
+
+	return h.journalConsumer.Close()
+}
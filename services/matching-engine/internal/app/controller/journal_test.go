@@ -0,0 +1,48 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"matching-engine/internal/app/model"
+)
+
+type replayerFunc func(ctx context.Context, entry model.JournalEntry) error
+
+func (f replayerFunc) Replay(ctx context.Context, entry model.JournalEntry) error {
+	return f(ctx, entry)
+}
+
+func TestJournalReplay(t *testing.T) {
+	entry := model.JournalEntry{Command: model.JournalCommandCancel, OrderID: 1}
+
+	// Busy sequencer did not accept the entry, the same entry is applied again
+	attempts := 0
+	h := NewJournalHandler(nil, replayerFunc(func(ctx context.Context, entry model.JournalEntry) error {
+		if attempts++; attempts < 3 {
+			return model.ErrEngineBusy
+		}
+		return nil
+	}), time.Second)
+	if err := h.replay(context.Background(), entry, 10); err != nil || attempts != 3 {
+		t.Fatalf("busy entry must be retried until applied, got %v after %v attempt", err, attempts)
+	}
+
+	// Command rejected by the book is expected
+	h.engine = replayerFunc(func(ctx context.Context, entry model.JournalEntry) error {
+		return model.ErrOrderNotFound
+	})
+	if err := h.replay(context.Background(), entry, 11); err != nil {
+		t.Fatalf("rejected entry must be skipped, got %v", err)
+	}
+
+	// Anything else would make the book diverge
+	h.engine = replayerFunc(func(ctx context.Context, entry model.JournalEntry) error {
+		return context.DeadlineExceeded
+	})
+	if err := h.replay(context.Background(), entry, 12); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("failed entry must stop the replay, got %v", err)
+	}
+}
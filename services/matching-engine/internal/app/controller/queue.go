@@ -3,37 +3,74 @@ package controller
 import (
 	"context"
 	"encoding/json"
+	"sync"
 	"time"
 
 	"github.com/gerins/log"
 	"github.com/segmentio/kafka-go"
 
 	"matching-engine/internal/app/model"
+	kafkapkg "matching-engine/pkg/kafka"
 )
 
+// orderReader is the part of the kafka reader used by the queue handler
+type orderReader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// Snapshotter saves the current state of the book
+type Snapshotter interface {
+	SaveSnapshot(ctx context.Context) error
+}
+
 type queueHandler struct {
-	kafkaConsumer *kafka.Reader
+	kafkaConsumer orderReader
+	retry         *kafkapkg.RetryProcessor
 	engine        model.Engine
+	snapshotter   Snapshotter
 	timeout       time.Duration
+	cancel        context.CancelFunc
+	stopped       chan struct{}
+
+	mu        sync.Mutex
+	processed map[int]kafka.Message // Last processed message of every partition, not committed yet
 }
 
-func NewQueueHandler(kafkaConsumer *kafka.Reader, processor model.Engine, timeout time.Duration) *queueHandler {
+// NewQueueHandler returns handler of the order topic. Without snapshotter every message is committed once
+// processed, with snapshotter the message is only committed after a snapshot containing it is saved,
+// used when there is no journal the book could be rebuilt from after a restart.
+func NewQueueHandler(kafkaConsumer orderReader, retry *kafkapkg.RetryProcessor, processor model.Engine, snapshotter Snapshotter, timeout time.Duration) *queueHandler {
 	return &queueHandler{
 		kafkaConsumer: kafkaConsumer,
+		retry:         retry,
 		engine:        processor,
+		snapshotter:   snapshotter,
 		timeout:       timeout,
+		stopped:       make(chan struct{}),
+		processed:     make(map[int]kafka.Message),
 	}
 }
 
 func (h *queueHandler) StartConsumer() {
+	var consumerCtx context.Context
+	consumerCtx, h.cancel = context.WithCancel(context.Background())
+
 	go func() {
+		defer close(h.stopped)
+
 		for {
-			kafkaMessage, err := h.kafkaConsumer.FetchMessage(context.Background())
+			kafkaMessage, err := h.kafkaConsumer.FetchMessage(consumerCtx)
 			if err != nil {
+				if consumerCtx.Err() != nil {
+					return
+				}
 				continue
 			}
 
-			func() {
+			// Failed message is retried then moved to the dead-letter topic, so it never blocks the pair
+			err = h.retry.Process(consumerCtx, kafkaMessage, func() error {
 				ctx, cancel := context.WithTimeout(context.Background(), h.timeout)
 				defer func() {
 					log.Context(ctx).Save()
@@ -44,29 +81,92 @@ func (h *queueHandler) StartConsumer() {
 
 				if err := h.OrderHandler(ctx, kafkaMessage.Value); err != nil {
 					log.Context(ctx).Error(err)
-					return
+					return err
 				}
 
-				// Commit message
-				if err := h.kafkaConsumer.CommitMessages(ctx, kafkaMessage); err != nil {
-					log.Context(ctx).Error(err)
-					return
-				}
-			}()
+				return nil
+			})
+			if err != nil {
+				log.Error(err)
+				continue // Dont commit message, it will be redelivered
+			}
+
+			if h.snapshotter != nil {
+				h.mu.Lock()
+				h.processed[kafkaMessage.Partition] = kafkaMessage
+				h.mu.Unlock()
+				continue
+			}
+
+			// Commit message
+			if err := h.kafkaConsumer.CommitMessages(consumerCtx, kafkaMessage); err != nil {
+				log.Error(err)
+			}
 		}
 	}()
 }
 
+// SaveSnapshot saves the book then commits every message processed before the snapshot was taken, a message
+// processed after it stays uncommitted and is redelivered on restart instead of being lost with the book.
+func (h *queueHandler) SaveSnapshot(ctx context.Context) error {
+	// Collected before the snapshot, every message here has already been applied to the book
+	h.mu.Lock()
+	covered := make([]kafka.Message, 0, len(h.processed))
+	for _, msg := range h.processed {
+		covered = append(covered, msg)
+	}
+	h.mu.Unlock()
+
+	if err := h.snapshotter.SaveSnapshot(ctx); err != nil {
+		return err
+	}
+
+	if len(covered) == 0 {
+		return nil
+	}
+
+	if err := h.kafkaConsumer.CommitMessages(ctx, covered...); err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	for _, msg := range covered {
+		if h.processed[msg.Partition].Offset == msg.Offset {
+			delete(h.processed, msg.Partition)
+		}
+	}
+	h.mu.Unlock()
+
+	return nil
+}
+
+// Close stop fetching new message and close the consumer, the processed message is committed with a last snapshot
+func (h *queueHandler) Close() error {
+	h.cancel()
+	<-h.stopped
+
+	if h.snapshotter != nil {
+		if err := h.SaveSnapshot(context.Background()); err != nil {
+			log.Error(err)
+		}
+	}
+
+	return h.kafkaConsumer.Close()
+}
+
 func (h *queueHandler) OrderHandler(ctx context.Context, msg []byte) error {
 	var payload model.Order
 	if err := json.Unmarshal(msg, &payload); err != nil {
 		log.Context(ctx).Error(err)
-		return err
+		return kafkapkg.Permanent(err)
 	}
 
 	log.Context(ctx).ReqBody = payload
 
-	if err := h.engine.Execute(ctx, payload); err != nil {
+	if _, err := h.engine.Execute(ctx, payload); err != nil {
+		if rejectedCommand(err) {
+			return kafkapkg.Permanent(err)
+		}
 		return err
 	}
 
@@ -0,0 +1,159 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+
+	"matching-engine/config"
+	"matching-engine/internal/app/model"
+	kafkapkg "matching-engine/pkg/kafka"
+)
+
+// fakeTopic is a single partition order topic with the committed offset of the consumer group
+type fakeTopic struct {
+	mu        sync.Mutex
+	messages  []kafka.Message
+	committed int64
+}
+
+func (topic *fakeTopic) produce(t *testing.T, order model.Order) {
+	payload, err := json.Marshal(order)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	topic.mu.Lock()
+	defer topic.mu.Unlock()
+	topic.messages = append(topic.messages, kafka.Message{Offset: int64(len(topic.messages)), Value: payload})
+}
+
+// fakeReader reads the topic from the committed offset, like a consumer group member after a restart
+type fakeReader struct {
+	topic *fakeTopic
+	next  int64
+}
+
+func newFakeReader(topic *fakeTopic) *fakeReader {
+	topic.mu.Lock()
+	defer topic.mu.Unlock()
+	return &fakeReader{topic: topic, next: topic.committed}
+}
+
+func (r *fakeReader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	for {
+		r.topic.mu.Lock()
+		if r.next < int64(len(r.topic.messages)) {
+			msg := r.topic.messages[r.next]
+			r.next++
+			r.topic.mu.Unlock()
+			return msg, nil
+		}
+		r.topic.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return kafka.Message{}, ctx.Err()
+		case <-time.After(time.Millisecond):
+		}
+	}
+}
+
+func (r *fakeReader) CommitMessages(ctx context.Context, msgs ...kafka.Message) error {
+	r.topic.mu.Lock()
+	defer r.topic.mu.Unlock()
+	for _, msg := range msgs {
+		if msg.Offset+1 > r.topic.committed {
+			r.topic.committed = msg.Offset + 1
+		}
+	}
+	return nil
+}
+
+func (r *fakeReader) Close() error { return nil }
+
+// fakeBook records the applied order, the snapshot is what survives a restart
+type fakeBook struct {
+	model.Engine
+
+	mu       sync.Mutex
+	applied  []int
+	snapshot []int
+}
+
+func (b *fakeBook) Execute(ctx context.Context, order model.Order) ([]model.Trade, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.applied = append(b.applied, order.ID)
+	return nil, nil
+}
+
+func (b *fakeBook) SaveSnapshot(ctx context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.snapshot = append([]int{}, b.applied...)
+	return nil
+}
+
+func (b *fakeBook) waitApplied(t *testing.T, count int) []int {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		b.mu.Lock()
+		applied := append([]int{}, b.applied...)
+		b.mu.Unlock()
+		if len(applied) >= count {
+			return applied
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	t.Fatalf("book did not apply %v order in time", count)
+	return nil
+}
+
+func TestQueueHandlerRestartFromSnapshot(t *testing.T) {
+	var (
+		topic = new(fakeTopic)
+		book  = new(fakeBook)
+		retry = kafkapkg.NewRetryProcessor(nil, config.Retry{MaxAttempts: 1})
+	)
+
+	h := NewQueueHandler(newFakeReader(topic), retry, book, book, time.Second)
+	h.StartConsumer()
+
+	topic.produce(t, model.Order{ID: 1})
+	topic.produce(t, model.Order{ID: 2})
+	book.waitApplied(t, 2)
+	if err := h.SaveSnapshot(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if topic.committed != 2 {
+		t.Fatalf("order in the snapshot must be committed, committed offset %v", topic.committed)
+	}
+
+	// Crash after applying an order the last snapshot does not contain
+	topic.produce(t, model.Order{ID: 3})
+	book.waitApplied(t, 3)
+	h.cancel()
+	<-h.stopped
+	if topic.committed != 2 {
+		t.Fatalf("order after the snapshot must not be committed, committed offset %v", topic.committed)
+	}
+
+	// Restarted book is restored from the snapshot, the order after it is redelivered
+	restored := &fakeBook{applied: book.snapshot}
+	h = NewQueueHandler(newFakeReader(topic), retry, restored, restored, time.Second)
+	h.StartConsumer()
+	defer h.Close()
+
+	if applied := restored.waitApplied(t, 3); !reflect.DeepEqual(applied, []int{1, 2, 3}) {
+		t.Fatalf("restarted book lost or repeated order, applied %v", applied)
+	}
+}
@@ -0,0 +1,60 @@
+package controller
+
+import (
+	"context"
+
+	"github.com/gerins/log"
+	"github.com/segmentio/kafka-go"
+
+	"matching-engine/internal/app/model"
+)
+
+type registryHandler struct {
+	registryConsumer *kafka.Reader
+	onPair           func(pair model.Pair)
+	cancel           context.CancelFunc
+	stopped          chan struct{}
+}
+
+// NewRegistryHandler calls onPair for every pair registration, in the order they were published
+func NewRegistryHandler(registryConsumer *kafka.Reader, onPair func(pair model.Pair)) *registryHandler {
+	return &registryHandler{
+		registryConsumer: registryConsumer,
+		onPair:           onPair,
+		stopped:          make(chan struct{}),
+	}
+}
+
+func (h *registryHandler) StartConsumer() {
+	var ctx context.Context
+	ctx, h.cancel = context.WithCancel(context.Background())
+
+	go func() {
+		defer close(h.stopped)
+
+		for {
+			kafkaMessage, err := h.registryConsumer.FetchMessage(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				continue
+			}
+
+			var pair model.Pair
+			if err := pair.FromJSON(kafkaMessage.Value); err != nil {
+				log.Errorf("invalid pair registration at offset %v, %v", kafkaMessage.Offset, err)
+				continue
+			}
+
+			h.onPair(pair)
+		}
+	}()
+}
+
+func (h *registryHandler) Close() error {
+	h.cancel()
+	<-h.stopped
+
+	return h.registryConsumer.Close()
+}
@@ -8,7 +8,6 @@ import (
 
 	"matching-engine/config"
 	"matching-engine/internal/app/controller"
-	"matching-engine/internal/app/usecase"
 	"matching-engine/pkg/kafka"
 	"matching-engine/pkg/redis"
 )
@@ -18,20 +17,40 @@ func Init(e *echo.Echo, g *grpc.Server, cfg *config.Config) chan bool {
 		exitSignal            = make(chan bool)
 		validator             = validator.New()
 		cache                 = redis.Init(cfg.Dependencies.Cache)
-		kafkaConsumer         = kafka.NewConsumer(cfg.Dependencies.MessageBroker)
 		kafkaProducer, writer = kafka.NewProducer(cfg.Dependencies.MessageBroker.Brokers)
+		pairCode              = cfg.Dependencies.MessageBroker.Consumer.Topic
+		retry                 = kafka.NewRetryProcessor(writer, cfg.Dependencies.MessageBroker.Retry)
+		orderBooks            = newBooks(cfg, cache, kafkaProducer, retry, validator)
 	)
 
-	// Init http router
-	orderBookUsecase := usecase.NewOrderBook(cfg.Dependencies.MessageBroker.Consumer.Topic, cfg.Dependencies.MessageBroker.Producer.Topic, cache, kafkaProducer, validator)
-	controller.NewHTTPHandler(orderBookUsecase, cfg.App.CtxTimeout).InitRoutes(e)
-	controller.NewQueueHandler(kafkaConsumer, orderBookUsecase, cfg.App.CtxTimeout).StartConsumer()
+	// Init http router, grpc service and the book of the configured pair
+	controller.NewHTTPHandler(orderBooks.registry, pairCode, cfg.App.CtxTimeout).InitRoutes(e)
+	controller.NewGRPCHandler(orderBooks.registry, cfg.App.CtxTimeout).Register(g)
+
+	if err := orderBooks.Start(cfg.Engine.Pair(pairCode)); err != nil {
+		log.Fatal(err)
+	}
+
+	// Pair listed by the admin is started without restarting the instance
+	var registryHandler interface{ Close() error }
+	if cfg.Engine.Registry {
+		handler := controller.NewRegistryHandler(kafka.NewRegistryConsumer(cfg.Dependencies.MessageBroker.Brokers, cfg.Dependencies.MessageBroker.Consumer.RegistryTopic), orderBooks.OnPairRegistered)
+		handler.StartConsumer()
+		registryHandler = handler
+	}
 
 	// Gracefull shutdown
 	go func() {
 		<-exitSignal // Receive exit signal
 		log.Info("disconnecting service dependencies")
 
+		if registryHandler != nil {
+			if err := registryHandler.Close(); err != nil {
+				log.Error(err)
+			}
+		}
+		orderBooks.Close()
+
 		if err := writer.Close(); err != nil {
 			log.Error(err)
 		}
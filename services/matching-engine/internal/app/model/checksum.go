@@ -0,0 +1,35 @@
+package model
+
+import (
+	"hash/crc32"
+	"strconv"
+	"strings"
+)
+
+// ChecksumLevels is the number of best levels of each side covered by the checksum
+const ChecksumLevels = 10
+
+// Checksum returns the CRC32 (IEEE) checksum of the top ChecksumLevels levels of the book.
+//
+// Format: levels are taken best price first and interleaved bid then ask, bid[0], ask[0], bid[1], ask[1], ...
+// A side with fewer levels is simply skipped once it runs out. Every level is written as "price:quantity"
+// using the shortest decimal representation without exponent (Go strconv 'f' format with precision -1),
+// and the levels are joined with ":". For example a book with bids 100x2, 99x1.5 and ask 101x3 gives
+// "100:2:101:3:99:1.5". An empty book gives the checksum of an empty string, which is 0.
+func Checksum(bids, asks []PriceLevel) uint32 {
+	var parts []string
+	for i := 0; i < ChecksumLevels; i++ {
+		if i < len(bids) {
+			parts = append(parts, formatLevel(bids[i]))
+		}
+		if i < len(asks) {
+			parts = append(parts, formatLevel(asks[i]))
+		}
+	}
+
+	return crc32.ChecksumIEEE([]byte(strings.Join(parts, ":")))
+}
+
+func formatLevel(level PriceLevel) string {
+	return strconv.FormatFloat(level.Price, 'f', -1, 64) + ":" + strconv.FormatFloat(level.Quantity, 'f', -1, 64)
+}
@@ -19,8 +19,9 @@ const (
 )
 
 const (
-	OrderStatusComplete Status = "COMPLETE"
-	OrderStatusFailed   Status = "FAILED"
-	OrderStatusProgress Status = "PROGRESS"
-	OrderStatusPartial  Status = "PARTIAL"
+	OrderStatusComplete  Status = "COMPLETE"
+	OrderStatusFailed    Status = "FAILED"
+	OrderStatusProgress  Status = "PROGRESS"
+	OrderStatusPartial   Status = "PARTIAL"
+	OrderStatusCancelled Status = "CANCELLED"
 )
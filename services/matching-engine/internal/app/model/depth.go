@@ -0,0 +1,22 @@
+package model
+
+type PriceLevel struct {
+	Price    float64 `json:"price"`
+	Quantity float64 `json:"quantity"`
+}
+
+// Depth is the aggregated price levels of the book, best price first
+type Depth struct {
+	PairCode string       `json:"pair_code"`
+	Bids     []PriceLevel `json:"bids"`
+	Asks     []PriceLevel `json:"asks"`
+	Checksum uint32       `json:"checksum"` // Checksum of the whole book after the change, see Checksum
+}
+
+// BookUpdate contains only the changed price levels, a level with zero quantity has been removed
+type BookUpdate struct {
+	PairCode string       `json:"pair_code"`
+	Bids     []PriceLevel `json:"bids"`
+	Asks     []PriceLevel `json:"asks"`
+	Checksum uint32       `json:"checksum"` // Checksum of the whole book after the change, see Checksum
+}
@@ -1,7 +1,42 @@
 package model
 
-import "context"
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrOrderNotFound = errors.New("order not found")
+	ErrEngineBusy    = errors.New("engine queue is full")
+	ErrEngineStopped = errors.New("engine stopped")
+	ErrNotLeader     = errors.New("engine is running as standby")
+	ErrPairNotServed = errors.New("pair is not served by this engine")
+
+	ErrInvalidQuoteOrder = errors.New("quote quantity is only allowed for market buy order without quantity")
+)
+
+// Leadership tells whether this instance is allowed to publish,
+// the token is the fencing token attached to every published trade.
+type Leadership interface {
+	Token() (token int64, leader bool)
+}
+
+// Journal record every command accepted by the leader so the standby can replay it
+type Journal interface {
+	Append(ctx context.Context, entry JournalEntry) error
+	EndOffset(ctx context.Context) (int64, error) // Offset of the next entry to be appended
+}
 
 type Engine interface {
-	Execute(ctx context.Context, order Order) error
+	PairCode() string
+	Execute(ctx context.Context, order Order) ([]Trade, error)
+	Cancel(ctx context.Context, orderID int) (Order, error)
+	Depth(ctx context.Context, limit int) (Depth, error)
+	SubscribeBookUpdates() (updates <-chan BookUpdate, unsubscribe func())
+	SubscribeTrades() (trades <-chan Trade, unsubscribe func())
+}
+
+// EngineRegistry finds the engine of a pair among the books running in this instance
+type EngineRegistry interface {
+	Engine(pairCode string) (Engine, error)
 }
@@ -0,0 +1,26 @@
+package model
+
+import "encoding/json"
+
+type JournalCommand string
+
+const (
+	JournalCommandExecute JournalCommand = "EXECUTE"
+	JournalCommandCancel  JournalCommand = "CANCEL"
+)
+
+// JournalEntry is a command accepted by the leader, replayed by the standby in the same order
+type JournalEntry struct {
+	Command JournalCommand `json:"command"`
+	Order   Order          `json:"order,omitempty"`
+	OrderID int            `json:"order_id,omitempty"`
+}
+
+func (entry *JournalEntry) FromJSON(msg []byte) error {
+	return json.Unmarshal(msg, entry)
+}
+
+func (entry *JournalEntry) ToJSON() []byte {
+	str, _ := json.Marshal(entry)
+	return str
+}
@@ -6,10 +6,11 @@ type Order struct {
 	ID              int     `json:"id"`
 	UserID          int     `json:"user_id"`
 	PairID          int     `json:"pair_id"`
-	Quantity        float64 `json:"quantity"`
-	Price           float64 `json:"price"`
+	Quantity        float64 `json:"quantity" validate:"required_without=QuoteQuantity,gte=0"`
+	Price           float64 `json:"price" validate:"required_without=QuoteQuantity,gte=0"`
+	QuoteQuantity   float64 `json:"quote_quantity,omitempty" validate:"gte=0"` // Market buy budget in quote asset, replace quantity and price
 	Type            Type    `json:"type"`
-	Side            Side    `json:"side"`
+	Side            Side    `json:"side" validate:"oneof=BUY SELL"`
 	Status          Status  `json:"status"`
 	TransactionTime int64   `json:"transaction_time"`
 }
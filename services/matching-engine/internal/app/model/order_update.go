@@ -0,0 +1,40 @@
+package model
+
+import "encoding/json"
+
+// OrderUpdate is published when an order is finished by the engine without resting in the book,
+// rejected before reaching the book, or removed from the book by a cancel, so the remaining reserved
+// balance can be refunded.
+type OrderUpdate struct {
+	OrderID             int     `json:"order_id"`
+	UserID              int     `json:"user_id"`
+	PairID              int     `json:"pair_id"`
+	PairCode            string  `json:"pair_code"`
+	Status              Status  `json:"status"`
+	FilledQuantity      float64 `json:"filled_quantity"`
+	FilledQuoteQuantity float64 `json:"filled_quote_quantity"` // Quote asset spent by every trade of the order
+	RemainingQuantity   float64 `json:"remaining_quantity"`    // Quantity still resting in the book when cancelled, or the whole quantity when rejected
+	UpdateTime          int64   `json:"update_time"`
+	TradeSequence       int64   `json:"trade_sequence"` // Sequence of the last trade of the pair when the update was made
+	FencingToken        int64   `json:"fencing_token,omitempty"`
+	TermStart           int64   `json:"term_start_sequence,omitempty"`
+}
+
+// Order returns the order removed by a cancel update
+func (update OrderUpdate) Order() Order {
+	return Order{
+		ID:       update.OrderID,
+		UserID:   update.UserID,
+		PairID:   update.PairID,
+		Quantity: update.RemainingQuantity,
+	}
+}
+
+func (update *OrderUpdate) FromJSON(msg []byte) error {
+	return json.Unmarshal(msg, update)
+}
+
+func (update *OrderUpdate) ToJSON() []byte {
+	str, _ := json.Marshal(update)
+	return str
+}
@@ -0,0 +1,17 @@
+package model
+
+import "encoding/json"
+
+// Pair is the registration published by the core engine when a pair is created or updated
+type Pair struct {
+	ID                int     `json:"id"`
+	Code              string  `json:"code"`
+	MatchingAlgorithm string  `json:"matching_algorithm"`
+	LotSize           float64 `json:"lot_size"`
+	MinAllocation     float64 `json:"min_allocation"`
+	Status            bool    `json:"status"`
+}
+
+func (pair *Pair) FromJSON(msg []byte) error {
+	return json.Unmarshal(msg, pair)
+}
@@ -0,0 +1,24 @@
+package model
+
+import "encoding/json"
+
+// Snapshot is the full state of an order book, used to restore the book after restart
+type Snapshot struct {
+	PairCode          string              `json:"pair_code"`
+	BuyOrders         []Order             `json:"buy_orders"`
+	SellOrders        []Order             `json:"sell_orders"`
+	ProcessedOrderIDs []int               `json:"processed_order_ids"`   // Dedupe index, oldest first
+	Unpublished       map[int]Unpublished `json:"unpublished,omitempty"` // Keyed by order ID
+	TradeSequence     int64               `json:"trade_sequence"`        // Sequence of the last trade
+	JournalOffset     int64               `json:"journal_offset"`        // First journal entry not applied to the book, the standby resumes from it
+	CreatedAt         int64               `json:"created_at"`
+}
+
+func (snapshot *Snapshot) FromJSON(msg []byte) error {
+	return json.Unmarshal(msg, snapshot)
+}
+
+func (snapshot *Snapshot) ToJSON() []byte {
+	str, _ := json.Marshal(snapshot)
+	return str
+}
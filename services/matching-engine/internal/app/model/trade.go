@@ -13,6 +13,9 @@ type Trade struct {
 	Price        float64 `json:"price"`
 	Side         Side    `json:"side"`
 	TradeTime    int64   `json:"trade_time"`
+	Sequence     int64   `json:"trade_sequence"`                // Increase by one for every trade of the pair, unique key of the trade
+	FencingToken int64   `json:"fencing_token,omitempty"`       // Leader lease token of the publishing engine
+	TermStart    int64   `json:"term_start_sequence,omitempty"` // First trade sequence published with the fencing token
 }
 
 func (trade *Trade) FromJSON(msg []byte) error {
@@ -0,0 +1,8 @@
+package model
+
+// Unpublished is what the leader produced for an order and failed to publish, the order is already
+// applied to the book so the messages are sent again when the same command is redelivered
+type Unpublished struct {
+	Trades []Trade      `json:"trades,omitempty"`
+	Update *OrderUpdate `json:"update,omitempty"`
+}
@@ -0,0 +1,114 @@
+package usecase
+
+import (
+	"fmt"
+	"math"
+
+	"matching-engine/config"
+	"matching-engine/internal/app/model"
+)
+
+const (
+	AllocationFIFO           = "FIFO"
+	AllocationProRata        = "PRO_RATA"
+	AllocationFIFOTopProRata = "FIFO_TOP_PRO_RATA"
+)
+
+// Allocator split the taker quantity between the resting orders of a single price level.
+// The level is given in time priority, oldest order first, and the returned fills are in the same order.
+type Allocator interface {
+	Allocate(quantity float64, level []model.Order) []float64
+}
+
+// NewAllocator returns the allocator configured for a pair, FIFO when nothing is configured
+func NewAllocator(cfg config.Matching) (Allocator, error) {
+	switch cfg.Algorithm {
+	case "", AllocationFIFO:
+		return fifoAllocator{}, nil
+	case AllocationProRata:
+		return proRataAllocator{lotSize: cfg.LotSize, minAllocation: cfg.MinAllocation}, nil
+	case AllocationFIFOTopProRata:
+		return proRataAllocator{lotSize: cfg.LotSize, minAllocation: cfg.MinAllocation, topOrder: true}, nil
+	}
+
+	return nil, fmt.Errorf("unknown allocation algorithm %v", cfg.Algorithm)
+}
+
+// fifoAllocator fill the oldest order first
+type fifoAllocator struct{}
+
+func (fifoAllocator) Allocate(quantity float64, level []model.Order) []float64 {
+	fills := make([]float64, len(level))
+	allocateFIFO(quantity, level, fills)
+	return fills
+}
+
+// proRataAllocator split the quantity proportionally to the size of each resting order.
+//
+// Rounding rule: every share is rounded down to a multiple of lotSize, a share below minAllocation
+// is dropped, and whatever left after rounding is given to the oldest orders first.
+// When topOrder is set the oldest order of the level is filled first before the pro-rata split.
+type proRataAllocator struct {
+	lotSize       float64
+	minAllocation float64
+	topOrder      bool
+}
+
+func (a proRataAllocator) Allocate(quantity float64, level []model.Order) []float64 {
+	fills := make([]float64, len(level))
+	if len(level) == 0 {
+		return fills
+	}
+
+	if a.topOrder {
+		fills[0] = math.Min(quantity, level[0].Quantity)
+		quantity -= fills[0]
+	}
+
+	var totalQuantity float64
+	for i, order := range level {
+		totalQuantity += order.Quantity - fills[i]
+	}
+
+	// Enough quantity to fill the whole level, no need to split
+	if quantity >= totalQuantity {
+		for i, order := range level {
+			fills[i] = order.Quantity
+		}
+		return fills
+	}
+
+	var allocated float64
+	for i, order := range level {
+		share := quantity * (order.Quantity - fills[i]) / totalQuantity
+		if a.lotSize > 0 {
+			share = math.Floor(share/a.lotSize) * a.lotSize
+		}
+		if share < a.minAllocation {
+			continue
+		}
+
+		fills[i] += share
+		allocated += share
+	}
+
+	allocateFIFO(quantity-allocated, level, fills)
+	return fills
+}
+
+// allocateFIFO give the quantity to the oldest order with remaining capacity first
+func allocateFIFO(quantity float64, level []model.Order, fills []float64) {
+	for i, order := range level {
+		if quantity <= 0 {
+			return
+		}
+
+		fill := math.Min(quantity, order.Quantity-fills[i])
+		if fill <= 0 {
+			continue
+		}
+
+		fills[i] += fill
+		quantity -= fill
+	}
+}
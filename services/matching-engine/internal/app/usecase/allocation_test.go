@@ -0,0 +1,51 @@
+package usecase
+
+import (
+	"reflect"
+	"testing"
+
+	"matching-engine/config"
+	"matching-engine/internal/app/model"
+)
+
+func TestAllocators(t *testing.T) {
+	var (
+		smallestFirst = []model.Order{{ID: 1, Quantity: 10}, {ID: 2, Quantity: 30}, {ID: 3, Quantity: 60}}
+		largestFirst  = []model.Order{{ID: 1, Quantity: 60}, {ID: 2, Quantity: 30}, {ID: 3, Quantity: 10}}
+	)
+
+	testCases := []struct {
+		name      string
+		matching  config.Matching
+		level     []model.Order
+		quantity  float64
+		wantFills []float64
+	}{
+		{"fifo partial", config.Matching{Algorithm: AllocationFIFO}, smallestFirst, 25, []float64{10, 15, 0}},
+		{"fifo whole level", config.Matching{}, smallestFirst, 150, []float64{10, 30, 60}},
+		{"pro rata exact", config.Matching{Algorithm: AllocationProRata, LotSize: 1}, smallestFirst, 50, []float64{5, 15, 30}},
+		{"pro rata rounding goes to oldest", config.Matching{Algorithm: AllocationProRata, LotSize: 1}, smallestFirst, 7, []float64{1, 2, 4}},
+		{"pro rata proportional", config.Matching{Algorithm: AllocationProRata, LotSize: 1}, largestFirst, 10, []float64{6, 3, 1}},
+		{"pro rata minimum allocation", config.Matching{Algorithm: AllocationProRata, LotSize: 1, MinAllocation: 3}, largestFirst, 10, []float64{7, 3, 0}},
+		{"pro rata whole level", config.Matching{Algorithm: AllocationProRata, LotSize: 1}, smallestFirst, 100, []float64{10, 30, 60}},
+		{"fifo top then pro rata", config.Matching{Algorithm: AllocationFIFOTopProRata, LotSize: 1}, smallestFirst, 28, []float64{10, 6, 12}},
+		{"fifo top only", config.Matching{Algorithm: AllocationFIFOTopProRata, LotSize: 1}, smallestFirst, 8, []float64{8, 0, 0}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			allocator, err := NewAllocator(tc.matching)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if fills := allocator.Allocate(tc.quantity, tc.level); !reflect.DeepEqual(fills, tc.wantFills) {
+				t.Fatalf("expected fills %v, got %v", tc.wantFills, fills)
+			}
+		})
+	}
+
+	if _, err := NewAllocator(config.Matching{Algorithm: "LIFO"}); err == nil {
+		t.Fatal("expected error for unknown algorithm")
+	}
+}
@@ -0,0 +1,52 @@
+package usecase
+
+import "expvar"
+
+const defaultDedupeWindow = 100000
+
+// Number of duplicate order dropped for each pair, exposed at /debug/vars
+var duplicateOrders = expvar.NewMap("matching_engine_duplicate_orders")
+
+// dedupeIndex remember the last processed order IDs, the oldest ID is forgotten once the window is full
+type dedupeIndex struct {
+	ids    map[int]struct{}
+	window []int // Ring buffer of the remembered IDs in processing order
+	next   int
+}
+
+func newDedupeIndex(size int) *dedupeIndex {
+	if size <= 0 {
+		size = defaultDedupeWindow
+	}
+
+	return &dedupeIndex{
+		ids:    make(map[int]struct{}, size),
+		window: make([]int, 0, size),
+	}
+}
+
+func (d *dedupeIndex) contains(id int) bool {
+	_, ok := d.ids[id]
+	return ok
+}
+
+func (d *dedupeIndex) add(id int) {
+	if d.contains(id) {
+		return
+	}
+
+	if len(d.window) < cap(d.window) {
+		d.window = append(d.window, id)
+	} else {
+		delete(d.ids, d.window[d.next])
+		d.window[d.next] = id
+		d.next = (d.next + 1) % len(d.window)
+	}
+
+	d.ids[id] = struct{}{}
+}
+
+// list returns the remembered IDs, oldest first
+func (d *dedupeIndex) list() []int {
+	return append(append(make([]int, 0, len(d.window)), d.window[d.next:]...), d.window[:d.next]...)
+}
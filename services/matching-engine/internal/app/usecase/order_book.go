@@ -2,7 +2,9 @@ package usecase
 
 import (
 	"context"
+	"math"
 	"sort"
+	"sync"
 	"time"
 
 	"github.com/gerins/log"
@@ -14,187 +16,431 @@ import (
 	"matching-engine/pkg/kafka"
 )
 
-// orderBook is used for processing data orderBook
+// quoteDust is the float residue of a quote budget, below it the budget is treated as spent
+const quoteDust = 1e-9
+
+// OrderBook is used for processing data orderBook.
+// It is not safe for concurrent use, every access must go through the Sequencer.
 type OrderBook struct {
-	pairCode        string
-	matchOrderTopic string
-	cache           *redis.Client
-	kafkaProducer   kafka.Producer
-	validator       *validator.Validate
-	BuyOrders       []model.Order
-	SellOrders      []model.Order
+	pairCode         string
+	matchOrderTopic  string
+	orderUpdateTopic string
+	cache            *redis.Client
+	kafkaProducer    kafka.Producer
+	validator        *validator.Validate
+	allocator        Allocator
+	lotSize          float64
+	leadership       model.Leadership
+	processed        *dedupeIndex
+	unpublished      map[int]model.Unpublished // Messages of a processed order the leader failed to publish
+	tradeSequence    int64                     // Sequence of the last trade, replay of the journal produces the same sequence
+	termToken        int64                     // Fencing token of the current leader term
+	termStart        int64                     // First trade sequence of the current leader term
+	journalOffset    int64                     // Journal offset of the restored snapshot
+	BuyOrders        []model.Order
+	SellOrders       []model.Order
+
+	subscriberLock   sync.Mutex
+	bookSubscribers  map[chan model.BookUpdate]struct{}
+	tradeSubscribers map[chan model.Trade]struct{}
 }
 
 // NewOrderBook returns new order book usecase.
 func NewOrderBook(
 	pairCode string,
 	matchOrderTopic string,
+	orderUpdateTopic string,
 	cache *redis.Client,
 	kafkaProducer kafka.Producer,
 	validator *validator.Validate,
+	allocator Allocator,
+	lotSize float64,
+	leadership model.Leadership,
+	dedupeWindow int,
 ) *OrderBook {
 	return &OrderBook{
-		pairCode:        pairCode,
-		matchOrderTopic: matchOrderTopic,
-		cache:           cache,
-		kafkaProducer:   kafkaProducer,
-		validator:       validator,
-		BuyOrders:       []model.Order{},
-		SellOrders:      []model.Order{},
+		pairCode:         pairCode,
+		matchOrderTopic:  matchOrderTopic,
+		orderUpdateTopic: orderUpdateTopic,
+		cache:            cache,
+		kafkaProducer:    kafkaProducer,
+		validator:        validator,
+		allocator:        allocator,
+		lotSize:          lotSize,
+		leadership:       leadership,
+		processed:        newDedupeIndex(dedupeWindow),
+		unpublished:      make(map[int]model.Unpublished),
+		BuyOrders:        []model.Order{},
+		SellOrders:       []model.Order{},
+		bookSubscribers:  make(map[chan model.BookUpdate]struct{}),
+		tradeSubscribers: make(map[chan model.Trade]struct{}),
 	}
 }
 
+// PairCode returns the pair code served by this order book
+func (book *OrderBook) PairCode() string {
+	return book.pairCode
+}
+
 // Process an order and return the trades generated before adding the remaining amount to the market
-func (book *OrderBook) Execute(ctx context.Context, order model.Order) error {
+func (book *OrderBook) Execute(ctx context.Context, order model.Order) ([]model.Trade, error) {
 	var trades []model.Trade
 
-	switch order.Side {
-	case model.OrderSideBuy:
+	// Reject order that can never rest in the book
+	if err := book.validator.Struct(order); err != nil {
+		return nil, book.reject(ctx, order, err)
+	}
+
+	if order.QuoteQuantity > 0 && (order.Side != model.OrderSideBuy || order.Type != model.OrderTypeMarket || order.Quantity != 0) {
+		return nil, book.reject(ctx, order, model.ErrInvalidQuoteOrder)
+	}
+
+	// Redelivered order is acknowledged without touching the book, what it produced and failed to be
+	// published is sent now so the trades are never lost
+	if order.ID != 0 {
+		if book.processed.contains(order.ID) {
+			if pending, ok := book.unpublished[order.ID]; ok {
+				log.Context(ctx).Infof("publishing %v pending trades of redelivered order %v", len(pending.Trades), order.ID)
+				if err := book.publish(ctx, order.ID, pending); err != nil {
+					return nil, err
+				}
+				return pending.Trades, nil
+			}
+
+			duplicateOrders.Add(book.pairCode, 1)
+			log.Context(ctx).Infof("dropped duplicate order %v", order.ID)
+			return []model.Trade{}, nil
+		}
+		book.processed.add(order.ID)
+	}
+
+	token, termStart, leader := book.fence()
+
+	switch {
+	case order.QuoteQuantity > 0:
+		trades = book.processMarketQuoteBuy(order)
+
+	case order.Side == model.OrderSideBuy:
 		trades = book.processLimitBuy(order)
 
-	case model.OrderSideSell:
+	case order.Side == model.OrderSideSell:
 		trades = book.processLimitSell(order)
 	}
 
-	if len(trades) == 0 {
-		return nil
+	var filledQuantity, filledQuoteQuantity float64
+	for _, trade := range trades {
+		filledQuantity += trade.Quantity
+		filledQuoteQuantity += trade.Quantity * trade.Price
 	}
 
-	log.Context(ctx).RespBody = trades
+	// Quote order never rest in the book, only the opposite side is changed
+	book.publishBookUpdate(order, order.QuoteQuantity == 0 && filledQuantity < order.Quantity, trades)
 
-	// Publish to Kafka
-	for _, trade := range trades {
-		if err := book.kafkaProducer.Send(ctx, book.matchOrderTopic, cast.ToString(order.ID), trade); err != nil {
-			return err // TODO Run reversal mechanism
+	if len(trades) != 0 {
+		log.Context(ctx).RespBody = trades
+	}
+
+	for i := range trades {
+		trades[i].FencingToken = token
+		trades[i].TermStart = termStart
+		book.publishTrade(trades[i])
+	}
+
+	// Only the leader publish to Kafka, the standby keeps the book without publishing
+	if !leader {
+		return trades, nil
+	}
+
+	pending := model.Unpublished{Trades: append([]model.Trade{}, trades...)}
+
+	// Quote order is finished right away, the unspent quote is refunded by the core engine
+	if order.QuoteQuantity > 0 {
+		update := model.OrderUpdate{
+			OrderID:             order.ID,
+			UserID:              order.UserID,
+			PairID:              order.PairID,
+			PairCode:            book.pairCode,
+			Status:              model.OrderStatusComplete,
+			FilledQuantity:      filledQuantity,
+			FilledQuoteQuantity: filledQuoteQuantity,
+			UpdateTime:          time.Now().Unix(),
+			TradeSequence:       book.tradeSequence,
+			FencingToken:        token,
+			TermStart:           termStart,
 		}
+		if len(trades) == 0 {
+			update.Status = model.OrderStatusFailed // Nothing to buy
+		}
+		pending.Update = &update
 	}
 
-	return nil
+	if err := book.publish(ctx, order.ID, pending); err != nil {
+		return nil, err
+	}
+
+	return trades, nil
 }
 
-// Process a limit buy order
-func (book *OrderBook) processLimitBuy(reqOrder model.Order) []model.Trade {
-	trades := make([]model.Trade, 0, 1)
-	n := len(book.SellOrders)
-
-	// check if we have at least one matching order
-	if n != 0 && book.SellOrders[n-1].Price <= reqOrder.Price {
-		// traverse all orders that match
-		for i := n - 1; i >= 0; i-- {
-			if book.SellOrders[i].Price > reqOrder.Price {
-				break
+// Cancel remove a resting order from the order book and return its remaining quantity
+func (book *OrderBook) Cancel(ctx context.Context, orderID int) (model.Order, error) {
+	order, found := book.removeOrder(orderID)
+	if !found {
+		// Cancelled before but its order update was not published
+		if pending, ok := book.unpublished[orderID]; ok && pending.Update != nil && pending.Update.Status == model.OrderStatusCancelled {
+			if err := book.publish(ctx, orderID, pending); err != nil {
+				return model.Order{}, err
 			}
+			return pending.Update.Order(), nil
+		}
+		return model.Order{}, model.ErrOrderNotFound
+	}
 
-			// fill the entire order
-			if book.SellOrders[i].Quantity >= reqOrder.Quantity {
-				tradeTime := time.Now().Unix()
-				trades = append(trades, model.Trade{
-					PairID:       reqOrder.PairID,
-					PairCode:     book.pairCode,
-					TakerUserID:  reqOrder.UserID,
-					TakerOrderID: reqOrder.ID,
-					MakerUserID:  book.SellOrders[i].UserID,
-					MakerOrderID: book.SellOrders[i].ID,
-					Quantity:     reqOrder.Quantity,
-					Price:        book.SellOrders[i].Price,
-					Side:         model.OrderSideBuy,
-					TradeTime:    tradeTime,
-				})
-
-				book.SellOrders[i].Quantity -= reqOrder.Quantity
-				if book.SellOrders[i].Quantity == 0 {
-					book.removeSellOrder(i)
-				}
-				return trades
-			}
+	book.publishBookUpdate(order, true, nil)
 
-			// fill a partial order and continue
-			if book.SellOrders[i].Quantity < reqOrder.Quantity {
-				tradeTime := time.Now().Unix()
-				trades = append(trades, model.Trade{
-					PairID:       reqOrder.PairID,
-					PairCode:     book.pairCode,
-					TakerUserID:  reqOrder.UserID,
-					TakerOrderID: reqOrder.ID,
-					MakerUserID:  book.SellOrders[i].UserID,
-					MakerOrderID: book.SellOrders[i].ID,
-					Quantity:     book.SellOrders[i].Quantity,
-					Price:        book.SellOrders[i].Price,
-					Side:         model.OrderSideBuy,
-					TradeTime:    tradeTime,
-				})
-
-				reqOrder.Quantity -= book.SellOrders[i].Quantity
-				book.removeSellOrder(i)
-				continue
-			}
+	// The core engine unlocks the reserved balance of the remaining quantity
+	token, termStart, leader := book.fence()
+	if leader {
+		update := model.OrderUpdate{
+			OrderID:           order.ID,
+			UserID:            order.UserID,
+			PairID:            order.PairID,
+			PairCode:          book.pairCode,
+			Status:            model.OrderStatusCancelled,
+			RemainingQuantity: order.Quantity,
+			UpdateTime:        time.Now().Unix(),
+			TradeSequence:     book.tradeSequence,
+			FencingToken:      token,
+			TermStart:         termStart,
+		}
+
+		if err := book.publish(ctx, order.ID, model.Unpublished{Update: &update}); err != nil {
+			return model.Order{}, err
+		}
+	}
+
+	return order, nil
+}
+
+// reject publishes a FAILED order update for an order that never reached the book, so the core engine
+// unlocks its whole reserved balance before the order is moved to the dead-letter topic
+func (book *OrderBook) reject(ctx context.Context, order model.Order, cause error) error {
+	token, termStart, leader := book.fence()
+	if order.ID == 0 || !leader {
+		return cause
+	}
+
+	update := model.OrderUpdate{
+		OrderID:           order.ID,
+		UserID:            order.UserID,
+		PairID:            order.PairID,
+		PairCode:          book.pairCode,
+		Status:            model.OrderStatusFailed,
+		RemainingQuantity: order.Quantity,
+		UpdateTime:        time.Now().Unix(),
+		TradeSequence:     book.tradeSequence,
+		FencingToken:      token,
+		TermStart:         termStart,
+	}
+
+	if err := book.kafkaProducer.Send(ctx, book.orderUpdateTopic, cast.ToString(order.ID), update); err != nil {
+		log.Context(ctx).Error(err)
+		return err // Retried, the order is dead-lettered only after its update is published
+	}
+
+	return cause
+}
+
+// publish sends the trades then the order update of an order, what is not sent is kept until the same
+// command is received again
+func (book *OrderBook) publish(ctx context.Context, orderID int, pending model.Unpublished) error {
+	for len(pending.Trades) != 0 {
+		if err := book.kafkaProducer.Send(ctx, book.matchOrderTopic, cast.ToString(orderID), pending.Trades[0]); err != nil {
+			book.unpublished[orderID] = pending
+			return err
+		}
+		pending.Trades = pending.Trades[1:]
+	}
+
+	if pending.Update != nil {
+		if err := book.kafkaProducer.Send(ctx, book.orderUpdateTopic, cast.ToString(orderID), *pending.Update); err != nil {
+			book.unpublished[orderID] = pending
+			return err
+		}
+	}
+
+	delete(book.unpublished, orderID)
+	return nil
+}
+
+// fence returns the fencing token of this instance and the first trade sequence of its leader term.
+// A message of an older term is still settled when its sequence is before the start of the newer term,
+// the new leader replayed the journal so its book already includes it.
+func (book *OrderBook) fence() (token, termStart int64, leader bool) {
+	token, leader = book.leadership.Token()
+	if leader && token != book.termToken {
+		book.termToken = token
+		book.termStart = book.tradeSequence + 1
+	}
+
+	return token, book.termStart, leader
+}
+
+// removeOrder remove a resting order of either side
+func (book *OrderBook) removeOrder(orderID int) (model.Order, bool) {
+	for i, order := range book.BuyOrders {
+		if order.ID == orderID {
+			book.removeBuyOrder(i)
+			return order, true
 		}
 	}
 
+	for i, order := range book.SellOrders {
+		if order.ID == orderID {
+			book.removeSellOrder(i)
+			return order, true
+		}
+	}
+
+	return model.Order{}, false
+}
+
+// Depth return the aggregated price levels of both side, limit 0 means all levels
+func (book *OrderBook) Depth(ctx context.Context, limit int) (model.Depth, error) {
+	depth := model.Depth{
+		PairCode: book.pairCode,
+		Bids:     aggregateLevels(book.BuyOrders, limit),
+		Asks:     aggregateLevels(book.SellOrders, limit),
+		Checksum: book.checksum(),
+	}
+
+	return depth, nil
+}
+
+// Process a limit buy order
+func (book *OrderBook) processLimitBuy(reqOrder model.Order) []model.Trade {
+	trades := book.match(&reqOrder, &book.SellOrders, func(price float64) bool {
+		return price <= reqOrder.Price
+	})
+
 	// finally add the remaining order to the list
-	book.addBuyOrder(reqOrder)
+	if reqOrder.Quantity > 0 {
+		book.addBuyOrder(reqOrder)
+	}
+
 	return trades
 }
 
 // Process a limit sell order
 func (book *OrderBook) processLimitSell(reqOrder model.Order) []model.Trade {
+	trades := book.match(&reqOrder, &book.BuyOrders, func(price float64) bool {
+		return price >= reqOrder.Price
+	})
+
+	// finally add the remaining order to the list
+	if reqOrder.Quantity > 0 {
+		book.addSellOrder(reqOrder)
+	}
+
+	return trades
+}
+
+// Process a market buy order with quote quantity, sweep the asks until the quote budget runs out.
+// The quantity bought at each level is rounded down to the lot size of the pair.
+func (book *OrderBook) processMarketQuoteBuy(reqOrder model.Order) []model.Trade {
+	var (
+		trades = make([]model.Trade, 0, 1)
+		budget = reqOrder.QuoteQuantity
+	)
+
+	for len(book.SellOrders) != 0 && budget > quoteDust {
+		bestPrice := book.SellOrders[len(book.SellOrders)-1].Price
+
+		quantity := budget / bestPrice
+		if book.lotSize > 0 {
+			quantity = math.Floor(quantity/book.lotSize) * book.lotSize
+		}
+		if quantity <= quoteDust {
+			break // Budget is not enough for a single lot, or only the float residue is left
+		}
+
+		// Take only the best level, the next level is priced with the remaining budget
+		reqOrder.Quantity = quantity
+		levelTrades := book.match(&reqOrder, &book.SellOrders, func(price float64) bool {
+			return price == bestPrice
+		})
+		if len(levelTrades) == 0 {
+			break
+		}
+
+		for _, trade := range levelTrades {
+			budget -= trade.Quantity * trade.Price
+		}
+		trades = append(trades, levelTrades...)
+	}
+
+	return trades
+}
+
+// Match the taker against the best price level of the opposite side until the price no longer crosses.
+// The quantity of each level is split between its resting orders by the allocator of this pair.
+func (book *OrderBook) match(taker *model.Order, makers *[]model.Order, crosses func(price float64) bool) []model.Trade {
 	trades := make([]model.Trade, 0, 1)
-	n := len(book.BuyOrders)
-	// check if we have at least one matching order
-	if n != 0 && book.BuyOrders[n-1].Price >= reqOrder.Price {
-		// traverse all orders that match
-		for i := n - 1; i >= 0; i-- {
-			if book.BuyOrders[i].Price < reqOrder.Price {
-				break
-			}
 
-			// fill the entire order
-			if book.BuyOrders[i].Quantity >= reqOrder.Quantity {
-				tradeTime := time.Now().Unix()
-				trades = append(trades, model.Trade{
-					PairID:       reqOrder.PairID,
-					PairCode:     book.pairCode,
-					TakerUserID:  reqOrder.UserID,
-					TakerOrderID: reqOrder.ID,
-					MakerUserID:  book.BuyOrders[i].UserID,
-					MakerOrderID: book.BuyOrders[i].ID,
-					Quantity:     reqOrder.Quantity,
-					Price:        book.BuyOrders[i].Price,
-					Side:         model.OrderSideSell,
-					TradeTime:    tradeTime,
-				})
-
-				book.BuyOrders[i].Quantity -= reqOrder.Quantity
-				if book.BuyOrders[i].Quantity == 0 {
-					book.removeBuyOrder(i)
-				}
-				return trades
-			}
+	for taker.Quantity > 0 && len(*makers) != 0 {
+		orders := *makers
+		bestPrice := orders[len(orders)-1].Price
+		if !crosses(bestPrice) {
+			break
+		}
+
+		// The best level sits at the end of the slice, the oldest order is the last one
+		start := len(orders) - 1
+		for start > 0 && orders[start-1].Price == bestPrice {
+			start--
+		}
 
-			// fill a partial order and continue
-			if book.BuyOrders[i].Quantity < reqOrder.Quantity {
-				tradeTime := time.Now().Unix()
-				trades = append(trades, model.Trade{
-					PairID:       reqOrder.PairID,
-					PairCode:     book.pairCode,
-					TakerUserID:  reqOrder.UserID,
-					TakerOrderID: reqOrder.ID,
-					MakerUserID:  book.BuyOrders[i].UserID,
-					MakerOrderID: book.BuyOrders[i].ID,
-					Quantity:     book.BuyOrders[i].Quantity,
-					Price:        book.BuyOrders[i].Price,
-					Side:         model.OrderSideSell,
-					TradeTime:    tradeTime,
-				})
-				reqOrder.Quantity -= book.BuyOrders[i].Quantity
-				book.removeBuyOrder(i)
+		level := make([]model.Order, 0, len(orders)-start)
+		for i := len(orders) - 1; i >= start; i-- {
+			level = append(level, orders[i])
+		}
+
+		tradeTime := time.Now().Unix()
+		for i, fill := range book.allocator.Allocate(taker.Quantity, level) {
+			fill = math.Min(fill, math.Min(taker.Quantity, level[i].Quantity))
+			if fill <= 0 {
 				continue
 			}
+
+			book.tradeSequence++
+			trades = append(trades, model.Trade{
+				PairID:       taker.PairID,
+				PairCode:     book.pairCode,
+				TakerUserID:  taker.UserID,
+				TakerOrderID: taker.ID,
+				MakerUserID:  level[i].UserID,
+				MakerOrderID: level[i].ID,
+				Quantity:     fill,
+				Price:        bestPrice,
+				Side:         taker.Side,
+				TradeTime:    tradeTime,
+				Sequence:     book.tradeSequence,
+			})
+
+			taker.Quantity -= fill
+			orders[len(orders)-1-i].Quantity -= fill
+		}
+
+		// Remove every filled order of the level while keeping the time priority of the rest
+		remaining := orders[:start]
+		for i := start; i < len(orders); i++ {
+			if orders[i].Quantity > 0 {
+				remaining = append(remaining, orders[i])
+			}
 		}
+
+		*makers = remaining
 	}
 
-	// finally add the remaining order to the list
-	book.addSellOrder(reqOrder)
 	return trades
 }
 
@@ -229,3 +475,39 @@ func (book *OrderBook) removeBuyOrder(index int) {
 func (book *OrderBook) removeSellOrder(index int) {
 	book.SellOrders = append(book.SellOrders[:index], book.SellOrders[index+1:]...)
 }
+
+// Aggregate orders into price levels starting from the best price at the end of the slice
+func aggregateLevels(orders []model.Order, limit int) []model.PriceLevel {
+	levels := make([]model.PriceLevel, 0)
+	for i := len(orders) - 1; i >= 0; i-- {
+		if n := len(levels); n != 0 && levels[n-1].Price == orders[i].Price {
+			levels[n-1].Quantity += orders[i].Quantity
+			continue
+		}
+
+		if limit > 0 && len(levels) == limit {
+			break
+		}
+
+		levels = append(levels, model.PriceLevel{Price: orders[i].Price, Quantity: orders[i].Quantity})
+	}
+
+	return levels
+}
+
+// Checksum of the top levels of the current book
+func (book *OrderBook) checksum() uint32 {
+	return model.Checksum(aggregateLevels(book.BuyOrders, model.ChecksumLevels), aggregateLevels(book.SellOrders, model.ChecksumLevels))
+}
+
+// Sum the remaining quantity of a single price level
+func levelQuantity(orders []model.Order, price float64) float64 {
+	var quantity float64
+	for _, order := range orders {
+		if order.Price == price {
+			quantity += order.Quantity
+		}
+	}
+
+	return quantity
+}
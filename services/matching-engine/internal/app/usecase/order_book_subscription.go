@@ -0,0 +1,100 @@
+package usecase
+
+import (
+	"matching-engine/internal/app/model"
+)
+
+// Buffer for each subscriber, slow subscriber will be disconnected when the buffer is full
+const subscriberBufferSize = 1024
+
+// SubscribeBookUpdates returns a channel receiving every changed price level
+func (book *OrderBook) SubscribeBookUpdates() (<-chan model.BookUpdate, func()) {
+	book.subscriberLock.Lock()
+	defer book.subscriberLock.Unlock()
+
+	updates := make(chan model.BookUpdate, subscriberBufferSize)
+	book.bookSubscribers[updates] = struct{}{}
+
+	return updates, func() {
+		book.subscriberLock.Lock()
+		defer book.subscriberLock.Unlock()
+
+		if _, found := book.bookSubscribers[updates]; found {
+			delete(book.bookSubscribers, updates)
+			close(updates)
+		}
+	}
+}
+
+// SubscribeTrades returns a channel receiving every trade generated by the order book
+func (book *OrderBook) SubscribeTrades() (<-chan model.Trade, func()) {
+	book.subscriberLock.Lock()
+	defer book.subscriberLock.Unlock()
+
+	trades := make(chan model.Trade, subscriberBufferSize)
+	book.tradeSubscribers[trades] = struct{}{}
+
+	return trades, func() {
+		book.subscriberLock.Lock()
+		defer book.subscriberLock.Unlock()
+
+		if _, found := book.tradeSubscribers[trades]; found {
+			delete(book.tradeSubscribers, trades)
+			close(trades)
+		}
+	}
+}
+
+// Publish the price levels touched by the order, every traded price and the order price itself when it rests or leaves the book
+func (book *OrderBook) publishBookUpdate(order model.Order, ownLevelChanged bool, trades []model.Trade) {
+	book.subscriberLock.Lock()
+	defer book.subscriberLock.Unlock()
+
+	if len(book.bookSubscribers) == 0 {
+		return
+	}
+
+	sameSide, oppositeSide := book.BuyOrders, book.SellOrders
+	if order.Side == model.OrderSideSell {
+		sameSide, oppositeSide = book.SellOrders, book.BuyOrders
+	}
+
+	var sameLevels, oppositeLevels []model.PriceLevel
+	for i, trade := range trades {
+		if i == 0 || trades[i-1].Price != trade.Price {
+			oppositeLevels = append(oppositeLevels, model.PriceLevel{Price: trade.Price, Quantity: levelQuantity(oppositeSide, trade.Price)})
+		}
+	}
+
+	if ownLevelChanged {
+		sameLevels = append(sameLevels, model.PriceLevel{Price: order.Price, Quantity: levelQuantity(sameSide, order.Price)})
+	}
+
+	update := model.BookUpdate{PairCode: book.pairCode, Bids: sameLevels, Asks: oppositeLevels, Checksum: book.checksum()}
+	if order.Side == model.OrderSideSell {
+		update.Bids, update.Asks = oppositeLevels, sameLevels
+	}
+
+	for subscriber := range book.bookSubscribers {
+		select {
+		case subscriber <- update:
+		default: // Subscriber too slow, force it to resubscribe
+			delete(book.bookSubscribers, subscriber)
+			close(subscriber)
+		}
+	}
+}
+
+func (book *OrderBook) publishTrade(trade model.Trade) {
+	book.subscriberLock.Lock()
+	defer book.subscriberLock.Unlock()
+
+	for subscriber := range book.tradeSubscribers {
+		select {
+		case subscriber <- trade:
+		default: // Subscriber too slow, force it to resubscribe
+			delete(book.tradeSubscribers, subscriber)
+			close(subscriber)
+		}
+	}
+}
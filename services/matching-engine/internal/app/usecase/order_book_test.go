@@ -0,0 +1,551 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"hash/crc32"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+
+	"matching-engine/internal/app/model"
+	"matching-engine/pkg/kafka/mock"
+)
+
+// bookHarness drives an OrderBook with a stream of orders and checks the book invariants after every step.
+type bookHarness struct {
+	t        testing.TB
+	book     *OrderBook
+	trades   []model.Trade // Trades published by the last Execute call
+	updates  []model.OrderUpdate
+	accepted float64 // Total quantity of every accepted order
+	traded   float64 // Total quantity of every published trade
+	sequence int64   // Sequence of the last published trade
+	fifo     bool    // Time priority within a price level is only checked for FIFO allocation
+}
+
+func newBookHarness(t testing.TB) *bookHarness {
+	return newBookHarnessWithAllocator(t, fifoAllocator{})
+}
+
+func newBookHarnessWithAllocator(t testing.TB, allocator Allocator) *bookHarness {
+	h := &bookHarness{t: t, fifo: allocator == fifoAllocator{}}
+
+	producer := new(mock.FakeProducer)
+	producer.SendStub = func(ctx context.Context, topic, key string, payload interface{}) error {
+		switch payload := payload.(type) {
+		case model.Trade:
+			h.trades = append(h.trades, payload)
+		case model.OrderUpdate:
+			h.updates = append(h.updates, payload)
+		}
+		return nil
+	}
+
+	h.book = NewOrderBook("DOGEIDRT", "match-order", "order-update", nil, producer, validator.New(), allocator, 0, Standalone, 0)
+	return h
+}
+
+// step executes one order and verifies the state of the book afterward.
+func (h *bookHarness) step(order model.Order) {
+	h.t.Helper()
+
+	var (
+		buyBefore  = append([]model.Order{}, h.book.BuyOrders...)
+		sellBefore = append([]model.Order{}, h.book.SellOrders...)
+	)
+
+	h.trades = nil
+	trades, err := h.book.Execute(context.Background(), order)
+	if err != nil {
+		if order.Quantity > 0 && order.Price > 0 {
+			h.t.Fatalf("valid order %+v rejected, %v", order, err)
+		}
+		if len(h.trades) != 0 || len(h.book.BuyOrders) != len(buyBefore) || len(h.book.SellOrders) != len(sellBefore) {
+			h.t.Fatalf("rejected order %+v changed the book", order)
+		}
+		return
+	}
+
+	if order.Quantity <= 0 || order.Price <= 0 {
+		h.t.Fatalf("invalid order %+v accepted", order)
+	}
+
+	if len(trades) != len(h.trades) {
+		h.t.Fatalf("returned %v trades but published %v", len(trades), len(h.trades))
+	}
+
+	h.accepted += order.Quantity
+	for _, trade := range h.trades {
+		h.traded += trade.Quantity
+
+		if trade.Sequence != h.sequence+1 {
+			h.t.Fatalf("trade sequence %v does not follow %v", trade.Sequence, h.sequence)
+		}
+		h.sequence = trade.Sequence
+	}
+
+	makers := sellBefore
+	if order.Side == model.OrderSideSell {
+		makers = buyBefore
+	}
+
+	if h.fifo {
+		h.checkPriority(order, makers)
+	} else {
+		h.checkPricePriority(order, makers)
+	}
+	h.checkNotCrossed()
+	h.checkRestingQuantity()
+	h.checkConservation()
+	h.checkSorted(h.book.BuyOrders, func(a, b float64) bool { return a > b })
+	h.checkSorted(h.book.SellOrders, func(a, b float64) bool { return a < b })
+}
+
+// checkPriority verifies that trades consume the opposite side strictly from the best price and oldest order first.
+func (h *bookHarness) checkPriority(taker model.Order, makers []model.Order) {
+	h.t.Helper()
+
+	remaining := taker.Quantity
+	for i, trade := range h.trades {
+		if i >= len(makers) {
+			h.t.Fatalf("trade %+v has no maker left in the book", trade)
+		}
+
+		maker := makers[len(makers)-1-i]
+		if trade.MakerOrderID != maker.ID || trade.Price != maker.Price {
+			h.t.Fatalf("trade %+v skipped maker %+v with higher priority", trade, maker)
+		}
+		if trade.TakerOrderID != taker.ID || trade.Side != taker.Side {
+			h.t.Fatalf("trade %+v does not belong to taker %+v", trade, taker)
+		}
+		if trade.Quantity <= 0 || trade.Quantity > maker.Quantity || trade.Quantity > remaining {
+			h.t.Fatalf("trade %+v has invalid quantity", trade)
+		}
+		if i < len(h.trades)-1 && trade.Quantity != maker.Quantity {
+			h.t.Fatalf("maker %+v partially filled before a worse maker", maker)
+		}
+
+		remaining -= trade.Quantity
+	}
+}
+
+// checkPricePriority verifies that trades consume the opposite side level by level from the best price,
+// a worse level is only touched after the better one has been exhausted.
+func (h *bookHarness) checkPricePriority(taker model.Order, makers []model.Order) {
+	h.t.Helper()
+
+	var (
+		levels   = aggregateLevels(makers, 0)
+		filled   = make(map[float64]float64)
+		position = 0
+	)
+
+	for _, trade := range h.trades {
+		if trade.TakerOrderID != taker.ID || trade.Side != taker.Side || trade.Quantity <= 0 {
+			h.t.Fatalf("trade %+v does not belong to taker %+v", trade, taker)
+		}
+
+		index := levelIndex(levels, trade.Price)
+		if index != position {
+			if index != position+1 || filled[levels[position].Price] != levels[position].Quantity {
+				h.t.Fatalf("trade %+v skipped a better price level", trade)
+			}
+			position = index
+		}
+
+		filled[trade.Price] += trade.Quantity
+		if filled[trade.Price] > levels[index].Quantity {
+			h.t.Fatalf("trade %+v overfilled price level %+v", trade, levels[index])
+		}
+	}
+}
+
+func levelIndex(levels []model.PriceLevel, price float64) int {
+	for i, level := range levels {
+		if level.Price == price {
+			return i
+		}
+	}
+
+	return len(levels)
+}
+
+func (h *bookHarness) checkNotCrossed() {
+	h.t.Helper()
+
+	nBuy, nSell := len(h.book.BuyOrders), len(h.book.SellOrders)
+	if nBuy == 0 || nSell == 0 {
+		return
+	}
+
+	if bestBid, bestAsk := h.book.BuyOrders[nBuy-1].Price, h.book.SellOrders[nSell-1].Price; bestBid >= bestAsk {
+		h.t.Fatalf("book crossed, best bid %v best ask %v", bestBid, bestAsk)
+	}
+}
+
+func (h *bookHarness) checkRestingQuantity() {
+	h.t.Helper()
+
+	for _, orders := range [][]model.Order{h.book.BuyOrders, h.book.SellOrders} {
+		for _, order := range orders {
+			if order.Quantity <= 0 {
+				h.t.Fatalf("resting order %+v has non positive quantity", order)
+			}
+		}
+	}
+}
+
+// checkConservation verifies every accepted quantity either rests in the book or was traded on both sides.
+func (h *bookHarness) checkConservation() {
+	h.t.Helper()
+
+	var resting float64
+	for _, orders := range [][]model.Order{h.book.BuyOrders, h.book.SellOrders} {
+		for _, order := range orders {
+			resting += order.Quantity
+		}
+	}
+
+	if h.accepted != resting+2*h.traded {
+		h.t.Fatalf("quantity not conserved, accepted %v resting %v traded %v", h.accepted, resting, h.traded)
+	}
+}
+
+// checkSorted verifies the best price sits at the end of the slice and, within a price level,
+// the oldest order sits closest to the end.
+func (h *bookHarness) checkSorted(orders []model.Order, better func(a, b float64) bool) {
+	h.t.Helper()
+
+	for i := 1; i < len(orders); i++ {
+		prev, curr := orders[i-1], orders[i]
+		if better(prev.Price, curr.Price) {
+			h.t.Fatalf("order %+v placed behind worse order %+v", prev, curr)
+		}
+		if prev.Price == curr.Price && prev.ID < curr.ID {
+			h.t.Fatalf("older order %+v placed behind newer order %+v", prev, curr)
+		}
+	}
+}
+
+// decodeOrders turns arbitrary bytes into an order stream, three bytes per order.
+// Prices and quantities are kept to small integers so float arithmetic stays exact.
+func decodeOrders(data []byte) []model.Order {
+	orders := make([]model.Order, 0, len(data)/3)
+	for i := 0; i+2 < len(data); i += 3 {
+		side := model.OrderSideBuy
+		if data[i]&1 == 1 {
+			side = model.OrderSideSell
+		}
+
+		orders = append(orders, model.Order{
+			ID:              len(orders) + 1,
+			UserID:          int(data[i]>>1) % 8,
+			PairID:          1,
+			Price:           float64(data[i+1] % 16),
+			Quantity:        float64(data[i+2] % 32),
+			Type:            model.OrderTypeLimit,
+			Side:            side,
+			TransactionTime: int64(len(orders) + 1),
+		})
+	}
+
+	return orders
+}
+
+func TestOrderBookInvariants(t *testing.T) {
+	allocators := map[string]Allocator{
+		AllocationFIFO:           fifoAllocator{},
+		AllocationProRata:        proRataAllocator{lotSize: 1, minAllocation: 2},
+		AllocationFIFOTopProRata: proRataAllocator{lotSize: 1, minAllocation: 2, topOrder: true},
+	}
+
+	for name, allocator := range allocators {
+		t.Run(name, func(t *testing.T) {
+			for seed := int64(1); seed <= 50; seed++ {
+				var (
+					random = rand.New(rand.NewSource(seed))
+					data   = make([]byte, 3*500)
+				)
+
+				random.Read(data)
+
+				h := newBookHarnessWithAllocator(t, allocator)
+				for _, order := range decodeOrders(data) {
+					h.step(order)
+				}
+			}
+		})
+	}
+}
+
+// FuzzOrderBook feeds random order streams to the book. New failing inputs are written to
+// testdata/fuzz/FuzzOrderBook by the go tool, commit them to keep them as regression cases.
+func FuzzOrderBook(f *testing.F) {
+	f.Add([]byte{0, 10, 5, 1, 10, 5})                  // Exact match
+	f.Add([]byte{0, 10, 5, 2, 10, 3, 1, 9, 20})        // Sweep two makers and rest the remaining
+	f.Add([]byte{1, 5, 1, 3, 5, 1, 5, 5, 1, 0, 15, 2}) // Same price level, oldest first
+	f.Add([]byte{0, 10, 0, 1, 0, 5})                   // Zero quantity and zero price
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		h := newBookHarness(t)
+		for _, order := range decodeOrders(data) {
+			h.step(order)
+		}
+	})
+}
+
+func TestOrderBookDropDuplicateOrder(t *testing.T) {
+	h := newBookHarness(t)
+	h.book.processed = newDedupeIndex(2)
+
+	sell := model.Order{ID: 1, UserID: 1, PairID: 1, Price: 10, Quantity: 5, Type: model.OrderTypeLimit, Side: model.OrderSideSell}
+	buy := model.Order{ID: 2, UserID: 2, PairID: 1, Price: 10, Quantity: 2, Type: model.OrderTypeLimit, Side: model.OrderSideBuy}
+	h.step(sell)
+	h.step(buy)
+
+	before := h.book.Snapshot()
+	for _, order := range []model.Order{sell, buy} {
+		trades, err := h.book.Execute(context.Background(), order)
+		if err != nil || len(trades) != 0 {
+			t.Fatalf("duplicate order %v must be acknowledged without trades, got %v %v", order.ID, trades, err)
+		}
+	}
+
+	if !reflect.DeepEqual(before.SellOrders, h.book.SellOrders) || len(h.book.BuyOrders) != 0 {
+		t.Fatalf("duplicate order changed the book, %+v", h.book.SellOrders)
+	}
+
+	// Restored book keeps the dedupe index, and the window forget the oldest ID
+	restored := newBookHarness(t)
+	restored.book.processed = newDedupeIndex(2)
+	restored.book.Restore(before)
+	if _, err := restored.book.Execute(context.Background(), model.Order{ID: 3, UserID: 3, PairID: 1, Price: 20, Quantity: 1, Type: model.OrderTypeLimit, Side: model.OrderSideSell}); err != nil {
+		t.Fatal(err)
+	}
+
+	if restored.book.processed.contains(1) || !restored.book.processed.contains(2) || !restored.book.processed.contains(3) {
+		t.Fatalf("unexpected dedupe window %v", restored.book.processed.list())
+	}
+
+	// Restored book continues the trade sequence
+	trades, err := restored.book.Execute(context.Background(), model.Order{ID: 4, UserID: 4, PairID: 1, Price: 10, Quantity: 1, Type: model.OrderTypeLimit, Side: model.OrderSideBuy})
+	if err != nil || len(trades) != 1 || trades[0].Sequence != before.TradeSequence+1 {
+		t.Fatalf("restored book must continue trade sequence %v, got %+v %v", before.TradeSequence, trades, err)
+	}
+}
+
+func TestOrderBookRepublishOnRedelivery(t *testing.T) {
+	h := newBookHarness(t)
+
+	sell1 := model.Order{ID: 1, UserID: 1, PairID: 1, Price: 10, Quantity: 1, Type: model.OrderTypeLimit, Side: model.OrderSideSell}
+	sell2 := model.Order{ID: 2, UserID: 1, PairID: 1, Price: 11, Quantity: 1, Type: model.OrderTypeLimit, Side: model.OrderSideSell}
+	h.step(sell1)
+	h.step(sell2)
+
+	// Producer fails on the second trade of the buy order
+	producer := h.book.kafkaProducer.(*mock.FakeProducer)
+	send := producer.SendStub
+	producer.SendStub = func(ctx context.Context, topic, key string, payload interface{}) error {
+		if len(h.trades) == 1 {
+			return errors.New("broker unavailable")
+		}
+		return send(ctx, topic, key, payload)
+	}
+
+	buy := model.Order{ID: 3, UserID: 2, PairID: 1, Price: 11, Quantity: 2, Type: model.OrderTypeLimit, Side: model.OrderSideBuy}
+	h.trades = nil
+	if _, err := h.book.Execute(context.Background(), buy); err == nil {
+		t.Fatal("failed publish must be returned so the order is redelivered")
+	}
+	if len(h.trades) != 1 || len(h.book.SellOrders) != 0 {
+		t.Fatalf("expected the book matched with one published trade, got %+v", h.trades)
+	}
+
+	// Snapshot keeps the unpublished trade
+	snapshot := h.book.Snapshot()
+	if pending := snapshot.Unpublished[buy.ID]; len(pending.Trades) != 1 || pending.Trades[0].MakerOrderID != sell2.ID {
+		t.Fatalf("snapshot must keep the unpublished trade, got %+v", snapshot.Unpublished)
+	}
+
+	// Redelivered order publish the remaining trade without matching again
+	producer.SendStub = send
+	trades, err := h.book.Execute(context.Background(), buy)
+	if err != nil || len(trades) != 1 || len(h.trades) != 2 {
+		t.Fatalf("redelivered order must publish the pending trade, got %+v %v", trades, err)
+	}
+	if h.trades[1].MakerOrderID != sell2.ID || h.trades[1].Sequence != h.trades[0].Sequence+1 {
+		t.Fatalf("unexpected republished trade %+v", h.trades[1])
+	}
+	if len(h.book.BuyOrders) != 0 || len(h.book.unpublished) != 0 {
+		t.Fatalf("redelivered order changed the book, %+v", h.book.BuyOrders)
+	}
+
+	// Once published it is a plain duplicate
+	trades, err = h.book.Execute(context.Background(), buy)
+	if err != nil || len(trades) != 0 || len(h.trades) != 2 {
+		t.Fatalf("published order must be dropped as duplicate, got %+v %v", trades, err)
+	}
+}
+
+func TestOrderBookQuoteMarketBuy(t *testing.T) {
+	h := newBookHarness(t)
+	h.book.lotSize = 1
+
+	h.step(model.Order{ID: 1, UserID: 1, PairID: 1, Price: 100, Quantity: 10, Type: model.OrderTypeLimit, Side: model.OrderSideSell})
+	h.step(model.Order{ID: 2, UserID: 2, PairID: 1, Price: 110, Quantity: 10, Type: model.OrderTypeLimit, Side: model.OrderSideSell})
+
+	trades, err := h.book.Execute(context.Background(), model.Order{ID: 3, UserID: 3, PairID: 1, QuoteQuantity: 1600, Type: model.OrderTypeMarket, Side: model.OrderSideBuy})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 10 at 100 spend 1000, the remaining 600 buys 5 whole lots at 110
+	if len(trades) != 2 || trades[0].Quantity != 10 || trades[1].Quantity != 5 || trades[1].Price != 110 {
+		t.Fatalf("unexpected trades %+v", trades)
+	}
+	if len(h.book.BuyOrders) != 0 || len(h.book.SellOrders) != 1 || h.book.SellOrders[0].Quantity != 5 {
+		t.Fatalf("quote order must not rest in the book, buy %+v sell %+v", h.book.BuyOrders, h.book.SellOrders)
+	}
+	if len(h.updates) != 1 || h.updates[0].Status != model.OrderStatusComplete || h.updates[0].FilledQuoteQuantity != 1550 {
+		t.Fatalf("unexpected order update %+v", h.updates)
+	}
+
+	// Empty ask side, the whole budget is refunded
+	h.book.SellOrders = nil
+	h.updates = nil
+	if _, err := h.book.Execute(context.Background(), model.Order{ID: 4, UserID: 3, PairID: 1, QuoteQuantity: 100, Type: model.OrderTypeMarket, Side: model.OrderSideBuy}); err != nil {
+		t.Fatal(err)
+	}
+	if len(h.updates) != 1 || h.updates[0].Status != model.OrderStatusFailed || h.updates[0].FilledQuoteQuantity != 0 {
+		t.Fatalf("unexpected order update %+v", h.updates)
+	}
+
+	// Quote quantity is only for market buy, the rejected order is failed so its balance is unlocked
+	h.updates = nil
+	if _, err := h.book.Execute(context.Background(), model.Order{ID: 5, UserID: 3, PairID: 1, QuoteQuantity: 100, Type: model.OrderTypeMarket, Side: model.OrderSideSell}); err != model.ErrInvalidQuoteOrder {
+		t.Fatalf("expected invalid quote order, got %v", err)
+	}
+	if len(h.updates) != 1 || h.updates[0].OrderID != 5 || h.updates[0].Status != model.OrderStatusFailed {
+		t.Fatalf("rejected order must publish a failed update, got %+v", h.updates)
+	}
+
+	// Without lot size the float residue of the spent budget must not buy a dust quantity
+	h = newBookHarness(t)
+	h.step(model.Order{ID: 6, UserID: 1, PairID: 1, Price: 0.7, Quantity: 1, Type: model.OrderTypeLimit, Side: model.OrderSideSell})
+	h.step(model.Order{ID: 7, UserID: 1, PairID: 1, Price: 0.7, Quantity: 1, Type: model.OrderTypeLimit, Side: model.OrderSideSell})
+	h.step(model.Order{ID: 8, UserID: 1, PairID: 1, Price: 0.7, Quantity: 20, Type: model.OrderTypeLimit, Side: model.OrderSideSell})
+	trades, err = h.book.Execute(context.Background(), model.Order{ID: 9, UserID: 3, PairID: 1, QuoteQuantity: 10, Type: model.OrderTypeMarket, Side: model.OrderSideBuy})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trades) != 3 {
+		t.Fatalf("expected one fill per maker without dust, got %+v", trades)
+	}
+}
+
+func TestOrderBookChecksum(t *testing.T) {
+	h := newBookHarness(t)
+	updates, unsubscribe := h.book.SubscribeBookUpdates()
+	defer unsubscribe()
+
+	if depth, _ := h.book.Depth(context.Background(), 0); depth.Checksum != 0 {
+		t.Fatalf("empty book checksum must be 0, got %v", depth.Checksum)
+	}
+
+	for i, order := range []model.Order{
+		{Price: 100, Quantity: 2, Side: model.OrderSideBuy},
+		{Price: 99, Quantity: 1.5, Side: model.OrderSideBuy},
+		{Price: 101, Quantity: 3, Side: model.OrderSideSell},
+	} {
+		order.ID, order.Type = i+1, model.OrderTypeLimit
+		h.step(order)
+	}
+
+	// Documented example of the checksum format
+	want := crc32.ChecksumIEEE([]byte("100:2:101:3:99:1.5"))
+	depth, _ := h.book.Depth(context.Background(), 1)
+	if depth.Checksum != want {
+		t.Fatalf("depth checksum %v, want %v", depth.Checksum, want)
+	}
+
+	var last model.BookUpdate
+	for len(updates) != 0 {
+		last = <-updates
+	}
+	if last.Checksum != want {
+		t.Fatalf("book update checksum %v, want %v", last.Checksum, want)
+	}
+}
+
+func TestOrderBookCancelPublishOrderUpdate(t *testing.T) {
+	h := newBookHarness(t)
+
+	h.step(model.Order{ID: 1, UserID: 1, PairID: 1, Price: 10, Quantity: 5, Type: model.OrderTypeLimit, Side: model.OrderSideSell})
+	h.step(model.Order{ID: 2, UserID: 2, PairID: 1, Price: 10, Quantity: 2, Type: model.OrderTypeLimit, Side: model.OrderSideBuy})
+
+	order, err := h.book.Cancel(context.Background(), 1)
+	if err != nil || order.Quantity != 3 {
+		t.Fatalf("expected remaining quantity 3, got %+v %v", order, err)
+	}
+
+	if len(h.book.SellOrders) != 0 {
+		t.Fatalf("cancelled order still in the book, %+v", h.book.SellOrders)
+	}
+
+	if len(h.updates) != 1 || h.updates[0].Status != model.OrderStatusCancelled || h.updates[0].OrderID != 1 || h.updates[0].RemainingQuantity != 3 {
+		t.Fatalf("unexpected order update %+v", h.updates)
+	}
+
+	if _, err := h.book.Cancel(context.Background(), 1); err != model.ErrOrderNotFound {
+		t.Fatalf("expected order not found, got %v", err)
+	}
+}
+
+type fakeLeadership struct {
+	token  int64
+	leader bool
+}
+
+func (l *fakeLeadership) Token() (int64, bool) {
+	return l.token, l.leader
+}
+
+func TestOrderBookFencingTermStart(t *testing.T) {
+	h := newBookHarness(t)
+	leadership := &fakeLeadership{token: 5, leader: true}
+	h.book.leadership = leadership
+
+	h.step(model.Order{ID: 1, UserID: 1, PairID: 1, Price: 10, Quantity: 5, Type: model.OrderTypeLimit, Side: model.OrderSideSell})
+	h.step(model.Order{ID: 2, UserID: 2, PairID: 1, Price: 10, Quantity: 1, Type: model.OrderTypeLimit, Side: model.OrderSideBuy})
+	if len(h.trades) != 1 || h.trades[0].FencingToken != 5 || h.trades[0].TermStart != 1 {
+		t.Fatalf("unexpected first term trade %+v", h.trades)
+	}
+
+	// Standby keeps the book without publishing
+	leadership.leader = false
+	h.trades = nil
+	trades, err := h.book.Execute(context.Background(), model.Order{ID: 3, UserID: 2, PairID: 1, Price: 10, Quantity: 1, Type: model.OrderTypeLimit, Side: model.OrderSideBuy})
+	if err != nil || len(trades) != 1 || len(h.trades) != 0 {
+		t.Fatalf("standby must match without publishing, matched %+v published %+v %v", trades, h.trades, err)
+	}
+	h.accepted += trades[0].Quantity // Keep the harness in step with the fill matched by the standby
+	h.traded += trades[0].Quantity
+	h.sequence = trades[0].Sequence
+
+	// Promoted with a new token, the term starts after the last trade of the replayed book
+	leadership.token, leadership.leader = 6, true
+	h.step(model.Order{ID: 4, UserID: 2, PairID: 1, Price: 10, Quantity: 1, Type: model.OrderTypeLimit, Side: model.OrderSideBuy})
+	if len(h.trades) != 1 || h.trades[0].FencingToken != 6 || h.trades[0].Sequence != 3 || h.trades[0].TermStart != 3 {
+		t.Fatalf("unexpected second term trade %+v", h.trades)
+	}
+
+	if _, err := h.book.Cancel(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	if last := h.updates[len(h.updates)-1]; last.FencingToken != 6 || last.TermStart != 3 || last.TradeSequence != 3 {
+		t.Fatalf("unexpected cancel update %+v", last)
+	}
+}
@@ -0,0 +1,42 @@
+package usecase
+
+import (
+	"sync"
+
+	"matching-engine/internal/app/model"
+)
+
+// Registry holds the engine of every pair running in this instance, pairs are added while serving
+type Registry struct {
+	mu      sync.RWMutex
+	engines map[string]model.Engine
+}
+
+func NewRegistry() *Registry {
+	return &Registry{engines: make(map[string]model.Engine)}
+}
+
+// Register adds the engine of a pair, false when the pair already has one
+func (r *Registry) Register(engine model.Engine) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exist := r.engines[engine.PairCode()]; exist {
+		return false
+	}
+
+	r.engines[engine.PairCode()] = engine
+	return true
+}
+
+func (r *Registry) Engine(pairCode string) (model.Engine, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	engine, exist := r.engines[pairCode]
+	if !exist {
+		return nil, model.ErrPairNotServed
+	}
+
+	return engine, nil
+}
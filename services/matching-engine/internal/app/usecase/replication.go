@@ -0,0 +1,47 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"matching-engine/internal/app/model"
+	"matching-engine/pkg/kafka"
+)
+
+// Standalone is the leadership of an engine running without replication, it is always the leader
+var Standalone model.Leadership = standaloneLeadership{}
+
+type standaloneLeadership struct{}
+
+func (standaloneLeadership) Token() (int64, bool) {
+	return 0, true
+}
+
+// JournalTopic returns the topic holding the command journal of a pair
+func JournalTopic(pairCode string) string {
+	return fmt.Sprintf("%v.journal", pairCode)
+}
+
+type kafkaJournal struct {
+	pairCode      string
+	brokers       string
+	kafkaProducer kafka.Producer
+}
+
+// NewKafkaJournal returns journal writing every entry of a pair into a single partition of its journal topic
+func NewKafkaJournal(pairCode, brokers string, kafkaProducer kafka.Producer) model.Journal {
+	return &kafkaJournal{
+		pairCode:      pairCode,
+		brokers:       brokers,
+		kafkaProducer: kafkaProducer,
+	}
+}
+
+func (j *kafkaJournal) Append(ctx context.Context, entry model.JournalEntry) error {
+	return j.kafkaProducer.Send(ctx, JournalTopic(j.pairCode), j.pairCode, entry)
+}
+
+// EndOffset is only called by the sequencer, every entry appended before it is already acknowledged
+func (j *kafkaJournal) EndOffset(ctx context.Context) (int64, error) {
+	return kafka.LastOffset(ctx, j.brokers, JournalTopic(j.pairCode), 0)
+}
@@ -0,0 +1,232 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gerins/log"
+
+	"matching-engine/internal/app/model"
+)
+
+// Sequencer runs every command of a single order book one at a time on its own goroutine.
+// Callers enqueue a command into a bounded buffer and wait for the result, when the
+// buffer is full the caller is blocked until there is room or its context is done.
+//
+// When a journal is given, the leader writes every command to the journal before applying it,
+// and the standby only accepts the commands replayed from that journal.
+type Sequencer struct {
+	book       *OrderBook
+	leadership model.Leadership
+	journal    model.Journal
+	commands   chan command
+	done       chan struct{}
+	stopped    chan struct{}
+}
+
+type command struct {
+	ctx    context.Context
+	run    func(ctx context.Context) (any, error)
+	result chan commandResult
+}
+
+type commandResult struct {
+	value any
+	err   error
+}
+
+// NewSequencer returns new sequencer and start processing the queue, journal is optional.
+func NewSequencer(book *OrderBook, queueSize int, leadership model.Leadership, journal model.Journal) *Sequencer {
+	sequencer := &Sequencer{
+		book:       book,
+		leadership: leadership,
+		journal:    journal,
+		commands:   make(chan command, queueSize),
+		done:       make(chan struct{}),
+		stopped:    make(chan struct{}),
+	}
+
+	go sequencer.run()
+	return sequencer
+}
+
+func (s *Sequencer) run() {
+	defer close(s.stopped)
+
+	for {
+		select {
+		case cmd := <-s.commands:
+			value, err := cmd.run(cmd.ctx)
+			cmd.result <- commandResult{value: value, err: err}
+
+		case <-s.done:
+			// Reject every command still waiting in the queue
+			for {
+				select {
+				case cmd := <-s.commands:
+					cmd.result <- commandResult{err: model.ErrEngineStopped}
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// Close stop accepting new command and wait until the running command finished
+func (s *Sequencer) Close() {
+	close(s.done)
+	<-s.stopped
+}
+
+// submit enqueue the command and wait for the result
+func (s *Sequencer) submit(ctx context.Context, run func(ctx context.Context) (any, error)) (any, error) {
+	cmd := command{
+		ctx:    context.WithoutCancel(ctx), // Once accepted the command must run until finish
+		run:    run,
+		result: make(chan commandResult, 1),
+	}
+
+	select {
+	case <-s.done:
+		return nil, model.ErrEngineStopped
+	default:
+	}
+
+	select {
+	case <-s.done:
+		return nil, model.ErrEngineStopped
+	case <-ctx.Done():
+		return nil, model.ErrEngineBusy
+	case s.commands <- cmd:
+	}
+
+	select {
+	case result := <-cmd.result:
+		return result.value, result.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (s *Sequencer) PairCode() string {
+	return s.book.PairCode()
+}
+
+func (s *Sequencer) Execute(ctx context.Context, order model.Order) ([]model.Trade, error) {
+	result, err := s.submit(ctx, func(ctx context.Context) (any, error) {
+		if err := s.appendJournal(ctx, model.JournalEntry{Command: model.JournalCommandExecute, Order: order}); err != nil {
+			return nil, err
+		}
+		return s.book.Execute(ctx, order)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.([]model.Trade), nil
+}
+
+func (s *Sequencer) Cancel(ctx context.Context, orderID int) (model.Order, error) {
+	result, err := s.submit(ctx, func(ctx context.Context) (any, error) {
+		if err := s.appendJournal(ctx, model.JournalEntry{Command: model.JournalCommandCancel, OrderID: orderID}); err != nil {
+			return nil, err
+		}
+		order, err := s.book.Cancel(ctx, orderID)
+		if err != nil || s.journal != nil {
+			return order, err
+		}
+
+		// Without journal the cancel is not in any log the book could be rebuilt from after a restart
+		if err := s.book.saveSnapshot(ctx, s.book.Snapshot()); err != nil {
+			log.Context(ctx).Errorf("failed saving snapshot after cancelling order %v, %v", orderID, err)
+		}
+		return order, nil
+	})
+	if err != nil {
+		return model.Order{}, err
+	}
+
+	return result.(model.Order), nil
+}
+
+func (s *Sequencer) Depth(ctx context.Context, limit int) (model.Depth, error) {
+	result, err := s.submit(ctx, func(ctx context.Context) (any, error) {
+		return s.book.Depth(ctx, limit)
+	})
+	if err != nil {
+		return model.Depth{}, err
+	}
+
+	return result.(model.Depth), nil
+}
+
+func (s *Sequencer) SubscribeBookUpdates() (<-chan model.BookUpdate, func()) {
+	return s.book.SubscribeBookUpdates()
+}
+
+func (s *Sequencer) SubscribeTrades() (<-chan model.Trade, func()) {
+	return s.book.SubscribeTrades()
+}
+
+// SaveSnapshot store the current book state, only the leader writes the snapshot
+func (s *Sequencer) SaveSnapshot(ctx context.Context) error {
+	if _, leader := s.leadership.Token(); !leader {
+		return nil
+	}
+
+	snapshot, err := s.snapshot(ctx)
+	if err != nil {
+		return err
+	}
+
+	return s.book.saveSnapshot(ctx, snapshot)
+}
+
+// snapshot copies the book together with the journal offset it has applied up to
+func (s *Sequencer) snapshot(ctx context.Context) (model.Snapshot, error) {
+	result, err := s.submit(ctx, func(ctx context.Context) (any, error) {
+		snapshot := s.book.Snapshot()
+		if s.journal != nil {
+			offset, err := s.journal.EndOffset(ctx)
+			if err != nil {
+				return nil, err
+			}
+			snapshot.JournalOffset = offset
+		}
+		return snapshot, nil
+	})
+	if err != nil {
+		return model.Snapshot{}, err
+	}
+
+	return result.(model.Snapshot), nil
+}
+
+// Replay apply a journal entry written by the leader, used by the standby to keep an identical book
+func (s *Sequencer) Replay(ctx context.Context, entry model.JournalEntry) error {
+	_, err := s.submit(ctx, func(ctx context.Context) (any, error) {
+		switch entry.Command {
+		case model.JournalCommandExecute:
+			return s.book.Execute(ctx, entry.Order)
+		case model.JournalCommandCancel:
+			return s.book.Cancel(ctx, entry.OrderID)
+		}
+		return nil, fmt.Errorf("unknown journal command %v", entry.Command)
+	})
+
+	return err
+}
+
+// Only the leader accepts new command, and it must be journaled before touching the book
+func (s *Sequencer) appendJournal(ctx context.Context, entry model.JournalEntry) error {
+	if _, leader := s.leadership.Token(); !leader {
+		return model.ErrNotLeader
+	}
+
+	if s.journal == nil {
+		return nil
+	}
+
+	return s.journal.Append(ctx, entry)
+}
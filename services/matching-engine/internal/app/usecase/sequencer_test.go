@@ -0,0 +1,102 @@
+package usecase
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"matching-engine/internal/app/model"
+)
+
+func TestSequencerConcurrentAccess(t *testing.T) {
+	var (
+		h         = newBookHarness(t)
+		sequencer = NewSequencer(h.book, 16, Standalone, nil)
+		wg        sync.WaitGroup
+	)
+
+	defer sequencer.Close()
+
+	for worker := 0; worker < 8; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				side := model.OrderSideBuy
+				if (worker+i)%2 == 1 {
+					side = model.OrderSideSell
+				}
+
+				order := model.Order{
+					ID:       worker*1000 + i + 1,
+					Price:    float64(10 + i%5),
+					Quantity: float64(1 + i%7),
+					Type:     model.OrderTypeLimit,
+					Side:     side,
+				}
+
+				if _, err := sequencer.Execute(context.Background(), order); err != nil {
+					t.Error(err)
+				}
+				if _, err := sequencer.Depth(context.Background(), 5); err != nil {
+					t.Error(err)
+				}
+			}
+		}(worker)
+	}
+
+	wg.Wait()
+	h.checkNotCrossed()
+	h.checkRestingQuantity()
+}
+
+func TestSequencerRejectAfterClose(t *testing.T) {
+	sequencer := NewSequencer(newBookHarness(t).book, 1, Standalone, nil)
+	sequencer.Close()
+
+	order := model.Order{ID: 1, Price: 10, Quantity: 1, Type: model.OrderTypeLimit, Side: model.OrderSideBuy}
+	if _, err := sequencer.Execute(context.Background(), order); err != model.ErrEngineStopped {
+		t.Fatalf("expected %v, got %v", model.ErrEngineStopped, err)
+	}
+}
+
+// fakeJournal keeps the entries in memory, the offset of an entry is its index
+type fakeJournal struct {
+	entries []model.JournalEntry
+}
+
+func (j *fakeJournal) Append(ctx context.Context, entry model.JournalEntry) error {
+	j.entries = append(j.entries, entry)
+	return nil
+}
+
+func (j *fakeJournal) EndOffset(ctx context.Context) (int64, error) {
+	return int64(len(j.entries)), nil
+}
+
+func TestSequencerSnapshotJournalOffset(t *testing.T) {
+	var (
+		journal   = &fakeJournal{}
+		sequencer = NewSequencer(newBookHarness(t).book, 4, Standalone, journal)
+	)
+	defer sequencer.Close()
+
+	for i := 1; i <= 3; i++ {
+		order := model.Order{ID: i, Price: 10, Quantity: 1, Type: model.OrderTypeLimit, Side: model.OrderSideBuy}
+		if _, err := sequencer.Execute(context.Background(), order); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	snapshot, err := sequencer.snapshot(context.Background())
+	if err != nil || snapshot.JournalOffset != 3 {
+		t.Fatalf("expected journal offset 3, got %v %v", snapshot.JournalOffset, err)
+	}
+
+	// Restored book tells the standby where to resume the journal
+	standby := newBookHarness(t).book
+	standby.Restore(snapshot)
+	if standby.JournalOffset() != 3 || len(standby.BuyOrders) != 3 {
+		t.Fatalf("unexpected restored book, offset %v buy %+v", standby.JournalOffset(), standby.BuyOrders)
+	}
+}
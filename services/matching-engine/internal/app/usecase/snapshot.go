@@ -0,0 +1,81 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"matching-engine/internal/app/model"
+)
+
+func snapshotKey(pairCode string) string {
+	return fmt.Sprintf("matching-engine#snapshot#%v", pairCode)
+}
+
+// Snapshot returns a copy of the current book state
+func (book *OrderBook) Snapshot() model.Snapshot {
+	return model.Snapshot{
+		PairCode:          book.pairCode,
+		BuyOrders:         append([]model.Order{}, book.BuyOrders...),
+		SellOrders:        append([]model.Order{}, book.SellOrders...),
+		ProcessedOrderIDs: book.processed.list(),
+		Unpublished:       copyUnpublished(book.unpublished),
+		TradeSequence:     book.tradeSequence,
+		CreatedAt:         time.Now().Unix(),
+	}
+}
+
+// Restore replace the book state with the snapshot
+func (book *OrderBook) Restore(snapshot model.Snapshot) {
+	book.BuyOrders = append([]model.Order{}, snapshot.BuyOrders...)
+	book.SellOrders = append([]model.Order{}, snapshot.SellOrders...)
+	book.tradeSequence = snapshot.TradeSequence
+	book.journalOffset = snapshot.JournalOffset
+
+	book.unpublished = copyUnpublished(snapshot.Unpublished)
+
+	book.processed = newDedupeIndex(cap(book.processed.window))
+	for _, id := range snapshot.ProcessedOrderIDs {
+		book.processed.add(id)
+	}
+}
+
+// JournalOffset returns the first journal entry not included in the restored snapshot
+func (book *OrderBook) JournalOffset() int64 {
+	return book.journalOffset
+}
+
+// LoadSnapshot restore the last saved snapshot of the pair, must be called before the book is used
+func (book *OrderBook) LoadSnapshot(ctx context.Context) error {
+	data, err := book.cache.Get(ctx, snapshotKey(book.pairCode)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil // First start, nothing to restore
+		}
+		return err
+	}
+
+	var snapshot model.Snapshot
+	if err := snapshot.FromJSON(data); err != nil {
+		return err
+	}
+
+	book.Restore(snapshot)
+	return nil
+}
+
+func (book *OrderBook) saveSnapshot(ctx context.Context, snapshot model.Snapshot) error {
+	return book.cache.Set(ctx, snapshotKey(book.pairCode), snapshot.ToJSON(), 0).Err()
+}
+
+func copyUnpublished(unpublished map[int]model.Unpublished) map[int]model.Unpublished {
+	result := make(map[int]model.Unpublished, len(unpublished))
+	for orderID, pending := range unpublished {
+		result[orderID] = pending
+	}
+
+	return result
+}
@@ -18,8 +18,15 @@ func init() {
 }
 
 func main() {
+	cfg := config.ParseConfigFile("config.yaml")
+
+	// Admin command, replay the dead-letter messages then exit
+	if len(os.Args) > 1 && os.Args[1] == "dlq-replay" {
+		cmd.ReplayDeadLetter(cfg, os.Args[2:])
+		return
+	}
+
 	var (
-		cfg  = config.ParseConfigFile("config.yaml")
 		http = cmd.NewHttpServer(cfg)
 		grpc = cmd.NewGRPCServer(cfg)
 	)
@@ -1,6 +1,7 @@
 package kafka
 
 import (
+	"context"
 	"strings"
 	"time"
 
@@ -9,11 +10,11 @@ import (
 	"matching-engine/config"
 )
 
-func NewConsumer(cfg config.MessageBroker) *kafka.Reader {
+func NewConsumer(cfg config.MessageBroker, topic string) *kafka.Reader {
 	consumerConfig := kafka.ReaderConfig{
 		Brokers:         strings.Split(cfg.Brokers, ","), // "localhost:9092,localhost:9092"
 		GroupID:         cfg.Group,
-		Topic:           cfg.Consumer.Topic,
+		Topic:           topic,
 		MinBytes:        10e3, // 10KB
 		MaxBytes:        10e6, // 10MB
 		MaxWait:         10 * time.Millisecond,
@@ -25,3 +26,78 @@ func NewConsumer(cfg config.MessageBroker) *kafka.Reader {
 	reader := kafka.NewReader(consumerConfig)
 	return reader
 }
+
+// NewJournalConsumer returns reader for the journal of a pair starting at the offset, the first entry not
+// included in the snapshot the book was restored from
+func NewJournalConsumer(brokers, topic string, offset int64) (*kafka.Reader, error) {
+	consumerConfig := kafka.ReaderConfig{
+		Brokers:     strings.Split(brokers, ","),
+		Topic:       topic,
+		Partition:   0, // Journal is written into single partition to keep the order
+		MinBytes:    10e3,
+		MaxBytes:    10e6,
+		MaxWait:     10 * time.Millisecond,
+		StartOffset: kafka.FirstOffset,
+	}
+
+	reader := kafka.NewReader(consumerConfig)
+	if offset > 0 {
+		if err := reader.SetOffset(offset); err != nil {
+			reader.Close()
+			return nil, err
+		}
+	}
+
+	return reader, nil
+}
+
+// LastOffset returns the offset of the next message written to the partition
+func LastOffset(ctx context.Context, brokers, topic string, partition int) (int64, error) {
+	var lastErr error
+	for _, broker := range strings.Split(brokers, ",") {
+		conn, err := kafka.DialLeader(ctx, "tcp", broker, topic, partition)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		offset, err := conn.ReadLastOffset()
+		conn.Close()
+		return offset, err
+	}
+
+	return 0, lastErr
+}
+
+// NewRegistryConsumer returns reader for the pair registry, every instance reads every registration from the start
+func NewRegistryConsumer(brokers, topic string) *kafka.Reader {
+	consumerConfig := kafka.ReaderConfig{
+		Brokers:     strings.Split(brokers, ","),
+		Topic:       topic,
+		Partition:   0, // Registry is a single partition compacted topic
+		MinBytes:    1,
+		MaxBytes:    10e6,
+		MaxWait:     time.Second,
+		StartOffset: kafka.FirstOffset,
+	}
+
+	reader := kafka.NewReader(consumerConfig)
+	return reader
+}
+
+// NewDeadLetterConsumer returns reader for replaying a dead-letter topic, the replay progress is kept by its own group
+func NewDeadLetterConsumer(cfg config.MessageBroker, topic string) *kafka.Reader {
+	consumerConfig := kafka.ReaderConfig{
+		Brokers:        strings.Split(cfg.Brokers, ","),
+		GroupID:        cfg.Group + "-dlq-replay",
+		Topic:          DeadLetterTopic(topic),
+		MinBytes:       10e3,
+		MaxBytes:       10e6,
+		MaxWait:        10 * time.Millisecond,
+		CommitInterval: 0, // Commit synchronously, a replayed message must not be replayed twice
+		StartOffset:    kafka.FirstOffset,
+	}
+
+	reader := kafka.NewReader(consumerConfig)
+	return reader
+}
@@ -0,0 +1,98 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+
+	"matching-engine/config"
+)
+
+type recordWriter struct {
+	messages []kafka.Message
+}
+
+func (w *recordWriter) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	w.messages = append(w.messages, msgs...)
+	return nil
+}
+
+func TestRetryProcessor(t *testing.T) {
+	message := kafka.Message{Topic: "DOGEIDRT", Partition: 2, Offset: 7, Key: []byte("1"), Value: []byte("{")}
+
+	tests := []struct {
+		name         string
+		failures     int
+		err          error
+		wantCalls    int
+		wantDeadLast bool
+	}{
+		{name: "success first attempt", failures: 0, err: errors.New("temporary"), wantCalls: 1},
+		{name: "success after retry", failures: 2, err: errors.New("temporary"), wantCalls: 3},
+		{name: "retries exhausted", failures: 10, err: errors.New("temporary"), wantCalls: 3, wantDeadLast: true},
+		{name: "permanent error", failures: 10, err: Permanent(errors.New("malformed")), wantCalls: 1, wantDeadLast: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writer := &recordWriter{}
+			processor := NewRetryProcessor(writer, config.Retry{MaxAttempts: 3})
+
+			calls := 0
+			err := processor.Process(context.Background(), message, func() error {
+				calls++
+				if calls <= tt.failures {
+					return tt.err
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			if calls != tt.wantCalls {
+				t.Errorf("handler called %v times, want %v", calls, tt.wantCalls)
+			}
+
+			if got := len(writer.messages) == 1; got != tt.wantDeadLast {
+				t.Fatalf("dead-lettered %v, want %v", got, tt.wantDeadLast)
+			}
+			if !tt.wantDeadLast {
+				return
+			}
+
+			dead := writer.messages[0]
+			headers := make(map[string]string)
+			for _, header := range dead.Headers {
+				headers[header.Key] = string(header.Value)
+			}
+
+			if dead.Topic != "DOGEIDRT.dlq" || string(dead.Value) != "{" {
+				t.Errorf("unexpected dead-letter message %v %s", dead.Topic, dead.Value)
+			}
+			if headers[HeaderOriginalTopic] != "DOGEIDRT" || headers[HeaderOriginalPartition] != "2" ||
+				headers[HeaderOriginalOffset] != "7" || headers[HeaderError] != tt.err.Error() {
+				t.Errorf("unexpected dead-letter headers %v", headers)
+			}
+		})
+	}
+}
+
+func TestRetryProcessorStopped(t *testing.T) {
+	writer := &recordWriter{}
+	processor := NewRetryProcessor(writer, config.Retry{MaxAttempts: 3, Backoff: time.Hour})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := processor.Process(ctx, kafka.Message{Topic: "DOGEIDRT"}, func() error { return errors.New("temporary") })
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected canceled, got %v", err)
+	}
+	if len(writer.messages) != 0 {
+		t.Fatalf("message must not be dead-lettered while stopping")
+	}
+}
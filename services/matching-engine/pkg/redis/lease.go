@@ -0,0 +1,100 @@
+package redis
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gerins/log"
+	"github.com/go-redis/redis/v8"
+)
+
+// Acquire the lease when nobody holds it and increase the fencing token,
+// or extend the lease when it is already held by the same owner.
+var leaseScript = redis.NewScript(`
+local owner = redis.call('GET', KEYS[1])
+if owner == false then
+	redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+	return redis.call('INCR', KEYS[2])
+end
+if owner == ARGV[1] then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+	return tonumber(redis.call('GET', KEYS[2]))
+end
+return 0
+`)
+
+// Lease is a leader lease held in redis. Every new acquisition returns a higher fencing token,
+// so a downstream service can reject a message published by an older leader.
+type Lease struct {
+	client *redis.Client
+	key    string
+	owner  string
+	ttl    time.Duration
+
+	lock       sync.RWMutex
+	token      int64
+	validUntil time.Time
+}
+
+// NewLease returns new leader lease for the given key.
+func NewLease(client *redis.Client, key, owner string, ttl time.Duration) *Lease {
+	return &Lease{
+		client: client,
+		key:    key,
+		owner:  owner,
+		ttl:    ttl,
+	}
+}
+
+// Token returns the fencing token and whether the lease is still held
+func (l *Lease) Token() (int64, bool) {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+
+	return l.token, l.token != 0 && time.Now().Before(l.validUntil)
+}
+
+// Run keep acquiring or renewing the lease until the context is done,
+// onChange is called every time the leadership of this instance changed.
+func (l *Lease) Run(ctx context.Context, onChange func(leader bool, token int64)) {
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+
+	wasLeader := false
+	for {
+		token := l.renew(ctx)
+		if isLeader := token != 0; isLeader != wasLeader {
+			wasLeader = isLeader
+			onChange(isLeader, token)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (l *Lease) renew(ctx context.Context) int64 {
+	// Local validity is shorter than the redis expiry to tolerate clock drift between instances
+	validUntil := time.Now().Add(l.ttl - l.ttl/10)
+
+	token, err := leaseScript.Run(ctx, l.client, []string{l.key, l.key + ":fencing"}, l.owner, l.ttl.Milliseconds()).Int64()
+	if err != nil {
+		log.Errorf("failed renewing lease %v, %v", l.key, err)
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	switch {
+	case err == nil && token != 0:
+		l.token, l.validUntil = token, validUntil
+	case err == nil || time.Now().After(l.validUntil):
+		l.token = 0 // Lease taken by other instance or already expired
+	}
+
+	return l.token
+}